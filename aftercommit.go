@@ -0,0 +1,132 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Operation is a modification of record passed to AfterCommit callbacks.
+type Operation int
+
+const (
+	// Record was inserted.
+	OpInsert Operation = iota
+
+	// Record was updated.
+	OpUpdate
+
+	// Record was inserted or updated by Upsert.
+	OpUpsert
+
+	// Record was deleted.
+	OpDelete
+)
+
+func (op Operation) String() string {
+	switch op {
+	case OpInsert:
+		return "insert"
+	case OpUpdate:
+		return "update"
+	case OpUpsert:
+		return "upsert"
+	case OpDelete:
+		return "delete"
+	}
+
+	return "unknown"
+}
+
+// CommitFunc is called after modification of record i was committed.
+// Record is a pointer to a copy of the structure passed to modification.
+type CommitFunc func(i interface{}, op Operation)
+
+// Modification of record waiting for commit.
+type commitEvent struct {
+	tbl    *dbTable
+	record interface{}
+	op     Operation
+}
+
+// Modifications within transaction, shared by DbHelpers bound to it.
+type commitEvents struct {
+	events []commitEvent
+	mutex  sync.Mutex
+}
+
+// AfterCommit registers callback called for records of table assigned to
+// type of i after they are inserted, updated or deleted by Insert, InsertWith,
+// InsertBatch, Update, Upsert and Delete. Within a transaction callbacks are
+// called after commit in order of modifications and are not called if
+// transaction is rolled back. Queries modifying rows by conditions, e.g.
+// DeleteWhere, do not call callbacks.
+func (dbh *DbHelper) AfterCommit(i interface{}, fn CommitFunc) error {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return err
+	}
+
+	tbl.afterCommit = append(tbl.afterCommit, fn)
+	return nil
+}
+
+// Calls callbacks of modification of structure v, or defers them until
+// commit of transaction.
+func (dbh *DbHelper) changed(tbl *dbTable, v reflect.Value, op Operation) {
+	if len(tbl.afterCommit) == 0 {
+		return
+	}
+
+	// record is not changed by caller after modification
+	record := reflect.New(tbl.structType)
+	record.Elem().Set(v)
+
+	e := commitEvent{tbl, record.Interface(), op}
+	if dbh.tx == nil {
+		e.fire()
+		return
+	}
+
+	dbh.txEvents.mutex.Lock()
+	dbh.txEvents.events = append(dbh.txEvents.events, e)
+	dbh.txEvents.mutex.Unlock()
+}
+
+// Returns modifications of transaction and forgets them.
+func (dbh *DbHelper) takeEvents() []commitEvent {
+	if dbh.txEvents == nil {
+		return nil
+	}
+
+	dbh.txEvents.mutex.Lock()
+	defer dbh.txEvents.mutex.Unlock()
+
+	events := dbh.txEvents.events
+	dbh.txEvents.events = nil
+	return events
+}
+
+// Calls callbacks of the table.
+func (e commitEvent) fire() {
+	for _, fn := range e.tbl.afterCommit {
+		fn(e.record, e.op)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAfterCommit(t *testing.T) {
+	dbh := newTestDriverDb("SELECT 1", &testResult{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var ops []Operation
+	var ids []int64
+	err = dbh.AfterCommit(testStruct{}, func(i interface{}, op Operation) {
+		ops = append(ops, op)
+		ids = append(ids, i.(*testStruct).Id)
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// callbacks are called immediately outside of transaction
+	record := &testStruct{Id: 1}
+	dbh.changed(tbl, reflect.ValueOf(record).Elem(), OpInsert)
+	if len(ops) != 1 || ops[0] != OpInsert {
+		t.Errorf("unexpected operations: %v", ops)
+		return
+	}
+
+	// callbacks are not called on rollback
+	txh, err := dbh.Begin()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	txh.changed(tbl, reflect.ValueOf(record).Elem(), OpDelete)
+	err = txh.Rollback()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(ops) != 1 {
+		t.Errorf("unexpected operations: %v", ops)
+		return
+	}
+
+	// callbacks are called after commit, including modifications of copies
+	txh, err = dbh.Begin()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	record.Id = 2
+	txh.changed(tbl, reflect.ValueOf(record).Elem(), OpUpdate)
+	txh.WithTenant(1).changed(tbl, reflect.ValueOf(record).Elem(), OpDelete)
+
+	// callbacks receive copies of records
+	record.Id = 3
+
+	if len(ops) != 1 {
+		t.Errorf("callbacks are called before commit: %v", ops)
+		return
+	}
+
+	err = txh.Commit()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !reflect.DeepEqual(ops, []Operation{OpInsert, OpUpdate, OpDelete}) || !reflect.DeepEqual(ids, []int64{1, 2, 2}) {
+		t.Errorf("unexpected operations: %v, %v", ops, ids)
+		return
+	}
+}
@@ -0,0 +1,482 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Savepoint of batch element executed with option ContinueOnError.
+const batchSavepoint = "dbhelper_item"
+
+// BatchOptions are options of ExecBatchWith and InsertBatchWith.
+type BatchOptions struct {
+	// Maximum number of elements in a chunk, 1000 if zero.
+	MaxChunk int
+
+	// Number of elements in the first chunk, which is used to estimate
+	// latency of an element, 10 if zero.
+	FirstChunk int
+
+	// If true, each element is executed within a savepoint, so failed
+	// elements are rolled back individually and reported in MultiError,
+	// while other elements are committed. Requires support of savepoints
+	// and costs two additional statements per element.
+	ContinueOnError bool
+}
+
+// BatchError is returned by ExecBatchWith and InsertBatchWith when batch
+// is executed partially, e.g. because deadline of context is reached.
+type BatchError struct {
+	// Number of executed elements, which are committed unless batch is
+	// executed within a transaction.
+	Done int
+
+	// Number of rows affected by each executed element.
+	Results []int64
+
+	// Error which stopped execution.
+	Err error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("dbhelper: batch stopped after %d elements: %v", e.Done, e.Err)
+}
+
+// Unwrap returns error which stopped execution.
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// ExecBatch executes prepared statement once for each element of params
+// within a single transaction. Elements of params are interpreted in the same
+// way as params argument of Exec. Returns number of rows affected by each
+// execution (-1 if this number cannot be obtained). If any execution fails,
+// transaction is rolled back and *MultiError with index of failed element is
+// returned. If statement was prepared by DbHelper bound to
+// a transaction, that transaction is used and it is not finished.
+func (pstmt *Pstmt) ExecBatch(params []interface{}) ([]int64, error) {
+	dbh := pstmt.dbHelper
+
+	// refuse to modify data
+	err := pstmt.checkReadOnly(true)
+	if err != nil {
+		return nil, err
+	}
+
+	// start transaction if needed
+	txh := dbh
+	if dbh.tx == nil {
+		txh, err = dbh.Begin()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results, err := pstmt.execBatch(txh, params, 0, nil)
+	if err != nil {
+		if txh != dbh {
+			txh.Rollback()
+		}
+
+		return nil, err
+	}
+
+	if txh != dbh {
+		err = txh.Commit()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// ExecBatchWith executes prepared statement once for each element of params
+// as ExecBatch does, splitting params to chunks which are executed in their
+// own transactions. If ctx has a deadline, size of each chunk is chosen to
+// fit within the remaining time, estimated from latency of executed
+// elements, and execution stops before the deadline. If not all elements
+// are executed, *BatchError with number of committed elements is returned.
+// With option ContinueOnError, failed elements are reported in *MultiError
+// and have zero affected rows. If statement was prepared by DbHelper bound
+// to a transaction, chunks are executed within that transaction.
+func (pstmt *Pstmt) ExecBatchWith(ctx context.Context, params []interface{}, opts BatchOptions) ([]int64, error) {
+	// refuse to modify data
+	err := pstmt.checkReadOnly(true)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MaxChunk <= 0 {
+		opts.MaxChunk = 1000
+	}
+
+	if opts.FirstChunk <= 0 {
+		opts.FirstChunk = 10
+	}
+
+	// errors of failed elements
+	var failed map[int]error
+	if opts.ContinueOnError {
+		if !pstmt.dbHelper.Capabilities().Savepoints {
+			return nil, errors.New("dbhelper: SQL dialect does not support savepoints")
+		}
+
+		failed = make(map[int]error)
+	}
+
+	dbh := pstmt.dbHelper.WithContext(ctx)
+	results := make([]int64, 0, len(params))
+	var elapsed time.Duration
+
+	for done := 0; done < len(params); {
+		// elements that fit within the remaining time
+		size := chunkSize(ctx, opts, done, elapsed)
+		if size < 1 {
+			return results, &BatchError{done, results, context.DeadlineExceeded}
+		}
+
+		if size > len(params)-done {
+			size = len(params) - done
+		}
+
+		start := time.Now()
+		chunk, err := pstmt.execChunk(dbh, params[done:done+size], done, failed)
+		if err != nil {
+			return results, &BatchError{done, results, err}
+		}
+
+		elapsed += time.Since(start)
+		results = append(results, chunk...)
+		done += size
+	}
+
+	if len(failed) > 0 {
+		return results, &MultiError{failed}
+	}
+
+	return results, nil
+}
+
+// Returns number of elements of the next chunk, which is 0 if remaining
+// time is not enough for a single element.
+func chunkSize(ctx context.Context, opts BatchOptions, done int, elapsed time.Duration) int {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return opts.MaxChunk
+	}
+
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return 0
+	}
+
+	if done == 0 || elapsed <= 0 {
+		return opts.FirstChunk
+	}
+
+	// leave 20% of remaining time as a reserve
+	size := int(int64(remaining) * 8 / 10 * int64(done) / int64(elapsed))
+	if size > opts.MaxChunk {
+		return opts.MaxChunk
+	}
+
+	return size
+}
+
+// Executes chunk of batch in its own transaction, unless dbh is bound to
+// a transaction. Offset is index of the first element of the chunk.
+func (pstmt *Pstmt) execChunk(dbh *DbHelper, params []interface{}, offset int, failed map[int]error) ([]int64, error) {
+	if dbh.tx != nil {
+		return pstmt.execBatch(dbh, params, offset, failed)
+	}
+
+	txh, err := dbh.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := pstmt.execBatch(txh, params, offset, failed)
+	if err != nil {
+		txh.Rollback()
+		return nil, err
+	}
+
+	err = txh.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Executes statement for each element of params within transaction of txh.
+// Offset is added to indexes of elements reported in errors. If failed is
+// not nil, each element is executed within a savepoint and errors of failed
+// elements are stored in failed instead of stopping execution.
+func (pstmt *Pstmt) execBatch(txh *DbHelper, params []interface{}, offset int, failed map[int]error) ([]int64, error) {
+	// bind statement to transaction once for all executions, statement
+	// prepared in another transaction is executed as text
+	stmt := pstmt.in(txh).getStmt()
+	if stmt != nil && stmt != pstmt.stmt {
+		defer stmt.Close()
+	}
+
+	results := make([]int64, len(params))
+	for i, p := range params {
+		// get parameter values for query
+		values, err := pstmt.getValues(p)
+		if err != nil {
+			if failed != nil {
+				failed[offset+i] = err
+				continue
+			}
+
+			return nil, &MultiError{map[int]error{offset + i: err}}
+		}
+
+		if failed != nil {
+			_, err = txh.execRaw("SAVEPOINT " + batchSavepoint)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// execute query
+		ctx, cancel := txh.withTimeout(txh.context())
+		var res sql.Result
+		if stmt != nil {
+			res, err = stmt.ExecContext(ctx, values...)
+		} else {
+			res, err = txh.tx.ExecContext(ctx, pstmt.prepared, values...)
+		}
+
+		cancel()
+		if err != nil && failed != nil && txh.context().Err() == nil {
+			// only failed element is rolled back
+			_, rbErr := txh.execRaw("ROLLBACK TO SAVEPOINT " + batchSavepoint)
+			if rbErr != nil {
+				return nil, rbErr
+			}
+
+			failed[offset+i] = pstmt.queryError(err)
+			continue
+		}
+
+		if err != nil {
+			return nil, &MultiError{map[int]error{offset + i: pstmt.queryError(err)}}
+		}
+
+		if failed != nil {
+			_, err = txh.execRaw("RELEASE SAVEPOINT " + batchSavepoint)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// get number of affected rows
+		num, err := res.RowsAffected()
+		if err != nil {
+			num = -1
+		}
+
+		results[i] = num
+	}
+
+	return results, nil
+}
+
+// InsertBatch inserts elements of slice i, which are structures or pointers
+// to structures, executing a single prepared statement within a transaction
+// as ExecBatch does. All columns are inserted, so options 'omitempty' are
+// ignored. Ids are generated by IdGenerator, if it is set for the table.
+// Ids generated by database are not assigned to structures, use Insert if
+// they are needed. Elements are prepared as copies, so that tenant, generated
+// ids, default values and hashed secrets are assigned only to elements that
+// were inserted. If some elements are invalid, e.g. nil or have values not
+// allowed by enum, nothing is inserted and *MultiError listing them is
+// returned.
+func (dbh *DbHelper) InsertBatch(i interface{}) error {
+	return dbh.insertBatch(i, nil)
+}
+
+// InsertBatchWith inserts elements of slice i as InsertBatch does, splitting
+// them to chunks as ExecBatchWith does, so that insertion stops before the
+// deadline of ctx. If not all elements are inserted, *BatchError with number
+// of inserted elements is returned, which are the first elements of i.
+// With option ContinueOnError, elements that failed to insert are reported
+// in *MultiError and other elements are inserted.
+func (dbh *DbHelper) InsertBatchWith(ctx context.Context, i interface{}, opts BatchOptions) error {
+	return dbh.WithContext(ctx).insertBatch(i, &opts)
+}
+
+// Inserts elements of slice i within a single transaction or in chunks
+// if opts is not nil.
+func (dbh *DbHelper) insertBatch(i interface{}, opts *BatchOptions) error {
+	// get current timestamp
+	time := time.Now().UTC().Unix()
+
+	v := reflect.ValueOf(i)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Slice {
+		return errors.New(fmt.Sprintf("dbhelper: slice is expected, got '%v'", v.Type()))
+	}
+
+	if v.Len() == 0 {
+		return nil
+	}
+
+	// get table
+	t, err := typeOf(i)
+	if err != nil {
+		return err
+	}
+
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return err
+	}
+
+	if tbl.view {
+		return errors.New(fmt.Sprintf("dbhelper: cannot modify read-only view '%s'", tbl.name))
+	}
+
+	// prepare insert query, generated ids are inserted
+	generated := tbl.idGenerator != nil
+	insertQuery, err := dbh.Prepare(tbl.insertSQL(nil, generated))
+	if err != nil {
+		return err
+	}
+
+	defer insertQuery.Close()
+
+	// get parameter values of copies of all elements, nothing is inserted
+	// if any element is invalid
+	params := make([]interface{}, v.Len())
+	copies := make([]reflect.Value, v.Len())
+	invalid := make(map[int]error)
+	for n := range params {
+		ev := reflect.Indirect(v.Index(n))
+		if !ev.IsValid() {
+			invalid[n] = errors.New("dbhelper: element of slice is nil")
+			continue
+		}
+
+		copies[n] = reflect.New(ev.Type()).Elem()
+		copies[n].Set(ev)
+
+		values, err := dbh.batchValues(tbl, insertQuery, copies[n], time, generated)
+		if err != nil {
+			invalid[n] = err
+			continue
+		}
+
+		defer putValueBuffer(values)
+
+		params[n] = orderedParams(*values)
+	}
+
+	if len(invalid) > 0 {
+		return &MultiError{invalid}
+	}
+
+	// inserted elements except failed ones
+	done := v.Len()
+	var failed map[int]error
+	if opts == nil {
+		_, err = insertQuery.ExecBatch(params)
+	} else {
+		_, err = insertQuery.ExecBatchWith(dbh.context(), params, *opts)
+		switch e := err.(type) {
+		case *BatchError:
+			done = e.Done
+		case *MultiError:
+			failed = e.Errors
+		}
+	}
+
+	if err != nil && done == v.Len() && failed == nil {
+		return err
+	}
+
+	// invalidate cached results
+	dbh.invalidate(tbl)
+
+	// update created and modified fields and assign inserted copies to
+	// elements
+	for n := 0; n < done; n++ {
+		if _, ok := failed[n]; ok {
+			continue
+		}
+
+		ev := copies[n]
+		if tbl.createdField != nil {
+			tbl.createdField.value(ev).SetInt(time)
+		}
+
+		if tbl.modifiedField != nil {
+			tbl.modifiedField.value(ev).SetInt(time)
+		}
+
+		reflect.Indirect(v.Index(n)).Set(ev)
+		dbh.changed(tbl, reflect.Indirect(v.Index(n)), OpInsert)
+	}
+
+	return err
+}
+
+// Prepares structure ev for insertion by q and returns values of its
+// parameters.
+func (dbh *DbHelper) batchValues(tbl *dbTable, q *Pstmt, ev reflect.Value, time int64, generated bool) (*[]interface{}, error) {
+	err := dbh.setTenant(tbl, ev)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tbl.applyDefaults(ev, time)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tbl.checkEnums(ev)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dbh.hashSecrets(tbl, ev)
+	if err != nil {
+		return nil, err
+	}
+
+	if generated {
+		id, err := tbl.idGenerator.NextId(dbh)
+		if err != nil {
+			return nil, err
+		}
+
+		err = setFieldValue(tbl.idField.value(ev), id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tbl.structValues(q, ev, time, true), nil
+}
@@ -0,0 +1,322 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecBatch(t *testing.T) {
+	dbh := newTestDriverDb("UPDATE test_batch_exec SET a = $1", &testResult{
+		rows: [][]driver.Value{{}, {}},
+		execErr: func(args []driver.Value) error {
+			if args[0] == int64(2) {
+				return errors.New("check constraint")
+			}
+
+			return nil
+		},
+	})
+
+	q, err := dbh.Prepare("UPDATE test_batch_exec SET a = :a")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	results, err := q.ExecBatch([]interface{}{map[string]interface{}{"a": 0}, map[string]interface{}{"a": 1}})
+	if err != nil || len(results) != 2 || results[1] != 2 {
+		t.Errorf("unexpected results: %v, %v", results, err)
+		return
+	}
+
+	// failed element is reported
+	results, err = q.ExecBatch([]interface{}{map[string]interface{}{"a": 1}, map[string]interface{}{"a": 2}})
+	var multiErr *MultiError
+	if !errors.As(err, &multiErr) || len(multiErr.Errors) != 1 || multiErr.Errors[1] == nil || results != nil {
+		t.Errorf("MultiError expected: %v", err)
+		return
+	}
+
+	// missing parameter
+	_, err = q.ExecBatch([]interface{}{map[string]interface{}{"b": 1}})
+	if !errors.As(err, &multiErr) || multiErr.Errors[0] == nil {
+		t.Errorf("MultiError expected: %v", err)
+		return
+	}
+
+	// transaction of statement is not finished
+	txh, err := dbh.Begin()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = q.in(txh).ExecBatch([]interface{}{map[string]interface{}{"a": 1}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = txh.Commit()
+	if err != nil {
+		t.Errorf("transaction was finished: %v", err)
+		return
+	}
+}
+
+func TestExecBatchWith(t *testing.T) {
+	dbh := newTestDriverDb("INSERT INTO test_batch (a) VALUES ($1)", &testResult{
+		rows: [][]driver.Value{{}},
+	})
+
+	q, err := dbh.Prepare("INSERT INTO test_batch (a) VALUES (:a)")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	params := make([]interface{}, 25)
+	for n := range params {
+		params[n] = map[string]interface{}{"a": n}
+	}
+
+	// without deadline chunks have maximum size
+	results, err := q.ExecBatchWith(context.Background(), params, BatchOptions{MaxChunk: 10})
+	if err != nil || len(results) != 25 || results[24] != 1 {
+		t.Errorf("unexpected results: %v, %v", results, err)
+		return
+	}
+
+	// nothing fits after deadline
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	_, err = q.ExecBatchWith(ctx, params, BatchOptions{})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Done != 0 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("BatchError expected: %v", err)
+		return
+	}
+}
+
+func TestChunkSize(t *testing.T) {
+	opts := BatchOptions{MaxChunk: 1000, FirstChunk: 10}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if size := chunkSize(ctx, opts, 0, 0); size != 10 {
+		t.Errorf("first chunk expected: %d", size)
+		return
+	}
+
+	// 80% of remaining second at 1ms per element
+	size := chunkSize(ctx, opts, 10, 10*time.Millisecond)
+	if size < 700 || size > 800 {
+		t.Errorf("unexpected chunk size: %d", size)
+		return
+	}
+
+	// slow elements
+	size = chunkSize(ctx, opts, 1, 2*time.Second)
+	if size != 0 {
+		t.Errorf("no elements expected: %d", size)
+		return
+	}
+
+	if size := chunkSize(context.Background(), opts, 10, time.Hour); size != 1000 {
+		t.Errorf("maximum chunk expected: %d", size)
+		return
+	}
+}
+
+func TestExecBatchContinueOnError(t *testing.T) {
+	dbh := newTestDriverDb("INSERT INTO test_batch_items (a) VALUES ($1)", &testResult{
+		rows: [][]driver.Value{{}},
+		execErr: func(args []driver.Value) error {
+			if args[0] == int64(3) || args[0] == int64(7) {
+				return errors.New("duplicate key")
+			}
+
+			return nil
+		},
+	})
+
+	rollbacks := 0
+	newTestDriverDb("SAVEPOINT dbhelper_item", &testResult{})
+	newTestDriverDb("RELEASE SAVEPOINT dbhelper_item", &testResult{})
+	newTestDriverDb("ROLLBACK TO SAVEPOINT dbhelper_item", &testResult{
+		execErr: func(args []driver.Value) error {
+			rollbacks++
+			return nil
+		},
+	})
+
+	q, err := dbh.Prepare("INSERT INTO test_batch_items (a) VALUES (:a)")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	params := make([]interface{}, 10)
+	for n := range params {
+		params[n] = map[string]interface{}{"a": n}
+	}
+
+	// failed elements are rolled back, others are executed
+	results, err := q.ExecBatchWith(context.Background(), params, BatchOptions{MaxChunk: 4, ContinueOnError: true})
+	var multiErr *MultiError
+	if !errors.As(err, &multiErr) || len(multiErr.Errors) != 2 || multiErr.Errors[3] == nil || multiErr.Errors[7] == nil {
+		t.Errorf("MultiError expected: %v", err)
+		return
+	}
+
+	if len(results) != 10 || results[3] != 0 || results[4] != 1 || rollbacks != 2 {
+		t.Errorf("unexpected results: %v, %d rollbacks", results, rollbacks)
+		return
+	}
+
+	// batch fails on first error without the option
+	_, err = q.ExecBatchWith(context.Background(), params, BatchOptions{})
+	if !errors.As(err, new(*BatchError)) {
+		t.Errorf("BatchError expected: %v", err)
+		return
+	}
+}
+
+func TestInsertBatchInvalid(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test_batch_invalid")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, _ := dbh.getTable(reflect.TypeOf(testStruct{}))
+	query, _, err := dbh.parseParams(tbl.insertSQL(nil, false), NamedParams)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	dbh.Db = newTestDriverDb(query, &testResult{}).Db
+
+	// all invalid elements are reported, nothing is inserted
+	err = dbh.InsertBatch([]*testStruct{{}, nil, {}, nil})
+	multiErr, ok := err.(*MultiError)
+	if !ok || !reflect.DeepEqual(multiErr.Indexes(), []int{1, 3}) {
+		t.Errorf("MultiError expected: %v", err)
+		return
+	}
+
+	if !strings.Contains(err.Error(), "2 elements failed: element 1:") {
+		t.Errorf("unexpected message: %v", err)
+		return
+	}
+}
+
+func TestInsertBatchCopies(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	dbh.SecretHasher = testHasher{}
+	err := dbh.AddTable(testUser{}, "test_batch_users")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, _ := dbh.getTable(reflect.TypeOf(testUser{}))
+	query, _, err := dbh.parseParams(tbl.insertSQL(nil, false), NamedParams)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	fail := true
+	dbh.Db = newTestDriverDb(query, &testResult{
+		execErr: func(args []driver.Value) error {
+			if fail {
+				return errors.New("insert failed")
+			}
+
+			return nil
+		},
+	}).Db
+
+	// elements are not changed if they are not inserted
+	users := []testUser{{Login: "a", Password: "secret"}, {Login: "b", Password: "secret"}}
+	err = dbh.InsertBatch(users)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	if users[0].Password != "secret" || users[1].Password != "secret" {
+		t.Errorf("elements are changed: %v", users)
+		return
+	}
+
+	fail = false
+	err = dbh.InsertBatch(users)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if users[0].Password != "hash:secret" || users[1].Password != "hash:secret" {
+		t.Errorf("unexpected elements: %v", users)
+		return
+	}
+
+	// views cannot be modified
+	err = dbh.AddView(testCSVStruct{}, "test_batch_view")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = dbh.InsertBatch([]testCSVStruct{{Id: 1}})
+	if err == nil || !strings.Contains(err.Error(), "read-only view") {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+}
+
+func TestMultiError(t *testing.T) {
+	err := &MultiError{map[int]error{
+		5: ErrNotFound,
+		1: errors.New("a"),
+		2: errors.New("b"),
+		9: errors.New("c"),
+	}}
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Error("errors of elements must be unwrapped")
+		return
+	}
+
+	if err.Error() != "dbhelper: 4 elements failed: element 1: a; element 2: b; element 5: dbhelper: record not found; ..." {
+		t.Errorf("unexpected message: %v", err)
+		return
+	}
+}
@@ -12,6 +12,8 @@
 package dbhelper
 
 import (
+	"database/sql/driver"
+	"fmt"
 	"github.com/coopernurse/gorp"
 	_ "github.com/lib/pq"
 	"testing"
@@ -173,3 +175,65 @@ func BenchmarkGorp(b *testing.B) {
 		}
 	}
 }
+
+type testBenchStruct struct {
+	Id    int64   `db:"id" dbopt:"id"`
+	Name  string  `db:"name"`
+	Count int64   `db:"count"`
+	Rate  float64 `db:"rate"`
+	Flag  bool    `db:"flag"`
+}
+
+// Returns DbHelper using test driver and prepared query returning n rows.
+func newBenchQuery(b *testing.B, n int) (*DbHelper, *Pstmt) {
+	rows := make([][]driver.Value, n)
+	for i := range rows {
+		rows[i] = []driver.Value{int64(i), "name", int64(i * 10), 1.5, true}
+	}
+
+	query := fmt.Sprintf("SELECT * FROM test_bench LIMIT %d", n)
+	dbh := newTestDriverDb(query, &testResult{
+		columns: []string{"id", "name", "count", "rate", "flag"},
+		rows:    rows,
+	})
+
+	err := dbh.AddTable(testBenchStruct{}, "test_bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	q, err := dbh.Prepare(query)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return dbh, q
+}
+
+func BenchmarkQuerySlice(b *testing.B) {
+	_, q := newBenchQuery(b, 100)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var list []*testBenchStruct
+		_, err := q.Query(&list, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkQueryRow(b *testing.B) {
+	_, q := newBenchQuery(b, 1)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var s testBenchStruct
+		_, err := q.Query(&s, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SelectBuilder builds select queries for registered tables.
+// Errors are reported when query is built or executed.
+type SelectBuilder struct {
+	dbHelper *DbHelper
+	tbl      *dbTable
+	with     []commonTableExpr
+	from     string
+	columns  []string
+	windows  []window
+	where    Cond
+	groupBy  []string
+	having   Cond
+	unions   []union
+	orderBy  []string
+	limit    int
+	offset   int
+	err      error
+}
+
+// Common table expression defined using With.
+type commonTableExpr struct {
+	name  string
+	query *SelectBuilder
+}
+
+// Query combined using Union or UnionAll.
+type union struct {
+	all   bool
+	query *SelectBuilder
+}
+
+// Window function defined using Over.
+type window struct {
+	function    string
+	alias       string
+	partitionBy []string
+	orderBy     []string
+}
+
+// Select returns builder of select query for table assigned to type of i.
+func (dbh *DbHelper) Select(i interface{}) *SelectBuilder {
+	b := &SelectBuilder{
+		dbHelper: dbh,
+	}
+
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		b.err = err
+		return b
+	}
+
+	// get table
+	b.tbl, b.err = dbh.getTable(t)
+	return b
+}
+
+// Returns quoted column or table name. Names which are not plain
+// identifiers, e.g. aggregate expressions in HAVING, are returned as is.
+func (b *SelectBuilder) quote(name string) string {
+	if !identifierRegexp.MatchString(name) {
+		return name
+	}
+
+	return b.dbHelper.quote(name)
+}
+
+// Returns error if table has no column. Columns of common table expressions
+// are not checked.
+func (b *SelectBuilder) checkColumn(column string) error {
+	if b.from != "" {
+		return nil
+	}
+
+	if _, ok := b.tbl.fields[column]; !ok {
+		return errors.New(fmt.Sprintf("dbhelper: table '%s' has no mapped column '%s'", b.tbl.name, column))
+	}
+
+	return nil
+}
+
+// Columns sets select list of the query (* by default). Elements of the list
+// are SQL expressions, e.g. "status" or "COUNT(*) AS count", and must not
+// contain user input. Results of such queries can be mapped to structures
+// which are not registered as tables, in which case fields are mapped by
+// column names only.
+func (b *SelectBuilder) Columns(columns ...string) *SelectBuilder {
+	b.columns = append(b.columns, columns...)
+	return b
+}
+
+// With defines common table expression name as result of query, which
+// can be used in From.
+func (b *SelectBuilder) With(name string, query *SelectBuilder) *SelectBuilder {
+	b.with = append(b.with, commonTableExpr{name, query})
+	return b
+}
+
+// From selects rows from common table expression defined using With instead
+// of the table. Columns of common table expressions are not checked.
+func (b *SelectBuilder) From(name string) *SelectBuilder {
+	b.from = name
+	return b
+}
+
+// Over adds window function to select list: function OVER (PARTITION BY
+// partitionBy ORDER BY orderBy) AS alias. Function is an SQL expression,
+// e.g. "ROW_NUMBER()" or "SUM(amount)", and must not contain user input.
+// Columns are checked and can be prefixed with '-' for descending order.
+func (b *SelectBuilder) Over(function string, alias string, partitionBy []string, orderBy ...string) *SelectBuilder {
+	b.windows = append(b.windows, window{function, alias, partitionBy, orderBy})
+	return b
+}
+
+// Returns ORDER BY list, checking column names.
+func (b *SelectBuilder) buildOrder(columns []string) (string, error) {
+	order := make([]string, len(columns))
+	for i, col := range columns {
+		dir := "ASC"
+		if strings.HasPrefix(col, "-") {
+			dir = "DESC"
+			col = col[1:]
+		}
+
+		err := b.checkColumn(col)
+		if err != nil {
+			return "", err
+		}
+
+		order[i] = fmt.Sprintf("%s %s", b.quote(col), dir)
+	}
+
+	return strings.Join(order, ", "), nil
+}
+
+// Returns window function expression, checking column names.
+func (b *SelectBuilder) buildWindow(w window) (string, error) {
+	var spec []string
+	if len(w.partitionBy) > 0 {
+		partitionBy := make([]string, len(w.partitionBy))
+		for i, col := range w.partitionBy {
+			err := b.checkColumn(col)
+			if err != nil {
+				return "", err
+			}
+
+			partitionBy[i] = b.quote(col)
+		}
+
+		spec = append(spec, "PARTITION BY "+strings.Join(partitionBy, ", "))
+	}
+
+	if len(w.orderBy) > 0 {
+		order, err := b.buildOrder(w.orderBy)
+		if err != nil {
+			return "", err
+		}
+
+		spec = append(spec, "ORDER BY "+order)
+	}
+
+	return fmt.Sprintf("%s OVER (%s) AS %s", w.function, strings.Join(spec, " "), w.alias), nil
+}
+
+// GroupBy sets columns used to group rows. Columns must be mapped.
+func (b *SelectBuilder) GroupBy(columns ...string) *SelectBuilder {
+	b.groupBy = append(b.groupBy, columns...)
+	return b
+}
+
+// Having sets condition on groups. Unlike Where, columns used in condition
+// are not checked, so that aggregate expressions can be used.
+func (b *SelectBuilder) Having(c Cond) *SelectBuilder {
+	b.having = c
+	return b
+}
+
+// Union combines results of the query with results of another query,
+// removing duplicate rows. Sort order and limits of combined queries are
+// not supported, sort order and limits of this query apply to the result.
+func (b *SelectBuilder) Union(query *SelectBuilder) *SelectBuilder {
+	b.unions = append(b.unions, union{false, query})
+	return b
+}
+
+// UnionAll combines results of the query with results of another query,
+// keeping duplicate rows. See Union for restrictions.
+func (b *SelectBuilder) UnionAll(query *SelectBuilder) *SelectBuilder {
+	b.unions = append(b.unions, union{true, query})
+	return b
+}
+
+// Where sets condition of the query. Columns used in condition must be mapped.
+func (b *SelectBuilder) Where(c Cond) *SelectBuilder {
+	b.where = c
+	return b
+}
+
+// OrderBy sets sort order. Column names prefixed with '-' are sorted in
+// descending order.
+func (b *SelectBuilder) OrderBy(columns ...string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, columns...)
+	return b
+}
+
+// Limit sets maximum number of returned rows.
+func (b *SelectBuilder) Limit(n int) *SelectBuilder {
+	b.limit = n
+	return b
+}
+
+// Offset sets number of rows to skip.
+func (b *SelectBuilder) Offset(n int) *SelectBuilder {
+	b.offset = n
+	return b
+}
+
+// SQL returns query with named parameters and values of parameters.
+func (b *SelectBuilder) SQL() (string, map[string]interface{}, error) {
+	cb := &condBuilder{
+		params: make(map[string]interface{}),
+	}
+
+	query, err := b.build(cb)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return query, cb.params, nil
+}
+
+// Returns query, storing parameter values in cb, which can be shared
+// with other queries.
+func (b *SelectBuilder) build(cb *condBuilder) (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+
+	// check and quote columns of this query
+	checkColumn, quote := cb.checkColumn, cb.quote
+	cb.checkColumn, cb.quote = b.checkColumn, b.quote
+	defer func() {
+		cb.checkColumn, cb.quote = checkColumn, quote
+	}()
+
+	query := ""
+
+	// common table expressions
+	if len(b.with) > 0 {
+		ctes := make([]string, len(b.with))
+		for i, cte := range b.with {
+			sub, err := cte.query.build(cb)
+			if err != nil {
+				return "", err
+			}
+
+			ctes[i] = fmt.Sprintf("%s AS (%s)", b.quote(cte.name), sub)
+		}
+
+		query = fmt.Sprintf("WITH %s ", strings.Join(ctes, ", "))
+	}
+
+	// select list
+	columns := b.columns
+	if len(columns) == 0 {
+		columns = []string{"*"}
+	}
+
+	for _, w := range b.windows {
+		col, err := b.buildWindow(w)
+		if err != nil {
+			return "", err
+		}
+
+		columns = append(columns[:len(columns):len(columns)], col)
+	}
+
+	// source of rows
+	from := b.tbl.name
+	if b.from != "" {
+		from = b.from
+	}
+
+	from = b.quote(from)
+
+	query += fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), from)
+
+	// records which are not deleted and belong to tenant
+	cond := b.where
+	if b.from == "" {
+		scope, err := b.dbHelper.scopeCond(b.tbl)
+		if err != nil {
+			return "", err
+		}
+
+		if len(scope) > 0 {
+			if cond != nil {
+				scope = append([]Cond{cond}, scope...)
+			}
+
+			cond = And(scope...)
+		}
+	}
+
+	// conditions
+	if cond != nil {
+		where, err := cond.build(cb)
+		if err != nil {
+			return "", err
+		}
+
+		query += " WHERE " + where
+	}
+
+	// groups
+	if len(b.groupBy) > 0 {
+		groupBy := make([]string, len(b.groupBy))
+		for i, col := range b.groupBy {
+			err := b.checkColumn(col)
+			if err != nil {
+				return "", err
+			}
+
+			groupBy[i] = b.quote(col)
+		}
+
+		query += " GROUP BY " + strings.Join(groupBy, ", ")
+	}
+
+	if b.having != nil {
+		// aggregate expressions are allowed
+		cb.checkColumn = nil
+		having, err := b.having.build(cb)
+		cb.checkColumn = b.checkColumn
+		if err != nil {
+			return "", err
+		}
+
+		query += " HAVING " + having
+	}
+
+	// combined queries
+	for _, u := range b.unions {
+		sub, err := u.query.build(cb)
+		if err != nil {
+			return "", err
+		}
+
+		if u.all {
+			query += " UNION ALL " + sub
+		} else {
+			query += " UNION " + sub
+		}
+	}
+
+	// sort order
+	if len(b.orderBy) > 0 {
+		order, err := b.buildOrder(b.orderBy)
+		if err != nil {
+			return "", err
+		}
+
+		query += " ORDER BY " + order
+	}
+
+	// limits
+	query += limitClause(b.dbHelper.sqlDialect, b.limit, b.offset)
+
+	return query, nil
+}
+
+// Query executes the query, mapping results to i as Pstmt.Query does.
+// Queries of the same shape, which differ only in parameter values, are
+// prepared once and reused.
+func (b *SelectBuilder) Query(i interface{}) (int64, error) {
+	query, params, err := b.SQL()
+	if err != nil {
+		return 0, err
+	}
+
+	q, err := b.tbl.getQuery(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.in(b.dbHelper).Query(i, params)
+}
@@ -0,0 +1,223 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectBuilder(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	query, params, err := dbh.Select(testStruct{}).
+		Where(And(Eq("b", true), Or(In("id", 1, 2), Not(Like("text", "a%"))))).
+		OrderBy("-c").
+		Limit(10).
+		SQL()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := "SELECT * FROM test WHERE (b = :c0 AND (id IN (:c1, :c2) OR NOT (text LIKE :c3))) ORDER BY c DESC LIMIT 10"
+	if query != expected {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+
+	if len(params) != 4 || params["c3"] != "a%" {
+		t.Errorf("unexpected parameters: %v", params)
+		return
+	}
+
+	// unknown column
+	_, _, err = dbh.Select(testStruct{}).Where(Eq("password", "")).SQL()
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestSelectBuilderGroupBy(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	query, _, err := dbh.Select(testStruct{}).
+		Columns("b", "COUNT(*) AS count").
+		GroupBy("b").
+		Having(Gt("COUNT(*)", 1)).
+		SQL()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if query != "SELECT b, COUNT(*) AS count FROM test GROUP BY b HAVING COUNT(*) > :c0" {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+
+	// structures that are not registered are mapped by column names
+	type count struct {
+		Bool  bool  `db:"b"`
+		Count int64 `db:"count"`
+	}
+
+	tbl, err := dbh.getMapping(reflect.TypeOf(count{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = tbl.scanPlan([]string{"b", "count"}, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestSelectBuilderWith(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the latest record for each text
+	ranked := dbh.Select(testStruct{}).
+		Over("ROW_NUMBER()", "rn", []string{"text"}, "-c").
+		Where(Eq("b", true))
+
+	query, params, err := dbh.Select(testStruct{}).
+		With("ranked", ranked).
+		From("ranked").
+		Columns("id", "text").
+		Where(Eq("rn", 1)).
+		SQL()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := "WITH ranked AS (SELECT *, ROW_NUMBER() OVER (PARTITION BY text ORDER BY c DESC) AS rn FROM test WHERE b = :c0) " +
+		"SELECT id, text FROM ranked WHERE rn = :c1"
+	if query != expected {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+
+	if len(params) != 2 {
+		t.Errorf("unexpected parameters: %v", params)
+		return
+	}
+}
+
+func TestSelectBuilderUnion(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	query, _, err := dbh.Select(testStruct{}).
+		Where(Eq("b", true)).
+		UnionAll(dbh.Select(testStruct{}).Where(Gt("c", 100))).
+		OrderBy("id").
+		SQL()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if query != "SELECT * FROM test WHERE b = :c0 UNION ALL SELECT * FROM test WHERE c > :c1 ORDER BY id ASC" {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+}
+
+func TestSelectBuilderSubquery(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	sub := dbh.Select(testStruct{}).Columns("id").Where(Eq("text", "abc"))
+	query, params, err := dbh.Select(testStruct{}).
+		Where(And(Eq("b", true), InSubquery("id", sub), Not(Exists(sub)))).
+		SQL()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := "SELECT * FROM test WHERE (b = :c0 AND id IN (SELECT id FROM test WHERE text = :c1) AND " +
+		"NOT (EXISTS (SELECT id FROM test WHERE text = :c2)))"
+	if query != expected {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+
+	// placeholders are numbered across outer and inner queries
+	q, _, err := dbh.parseParams(query, NamedParams)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !strings.Contains(q, "text = $2") || !strings.Contains(q, "text = $3") || len(params) != 3 {
+		t.Errorf("unexpected query: %s", q)
+		return
+	}
+}
+
+func TestSelectBuilderQuoting(t *testing.T) {
+	dbh := New(nil, MySql{})
+	err := dbh.AddTable(testStruct{}, "order")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	query, _, err := dbh.Select(testStruct{}).
+		Columns("c", "COUNT(*) AS n").
+		Where(Eq("b", true)).
+		GroupBy("c").
+		Having(Gt("COUNT(*)", 1)).
+		OrderBy("-c").
+		SQL()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// expressions are not quoted
+	expected := "SELECT c, COUNT(*) AS n FROM `order` WHERE `b` = :c0 GROUP BY `c` HAVING COUNT(*) > :c1 ORDER BY `c` DESC"
+	if query != expected {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"container/list"
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// Cache stores results of SelectById and SelectBy queries. Entries of a table
+// are invalidated when records of the table are inserted, updated or deleted.
+// Cache must be safe for concurrent use.
+type Cache interface {
+	// Get returns value stored for key of table and true, or false if there is no value.
+	Get(table string, key string) ([]byte, bool)
+
+	// Set stores value for key of table.
+	Set(table string, key string, value []byte)
+
+	// Invalidate removes all values stored for table.
+	Invalidate(table string)
+}
+
+// Generations of cached results of tables. Generation of a table is changed
+// on each invalidation, so that results selected before the invalidation are
+// not stored in cache.
+type cacheGenerations struct {
+	mutex  sync.Mutex
+	tables map[string]uint64
+}
+
+// Returns current generation of table.
+func (g *cacheGenerations) get(table string) uint64 {
+	if g == nil {
+		return 0
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.tables[table]
+}
+
+// Starts new generation of table.
+func (g *cacheGenerations) next(table string) {
+	if g == nil {
+		return
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.tables == nil {
+		g.tables = make(map[string]uint64)
+	}
+
+	g.tables[table]++
+}
+
+// Returns generation of cached results of table, which must be passed to
+// setCached for results of query performed afterwards.
+func (dbh *DbHelper) cacheGeneration(tbl *dbTable) uint64 {
+	return dbh.cacheGens.get(tbl.name)
+}
+
+// Returns cache key for the query result. Results mapped to slices and to
+// structures are cached separately.
+func cacheKey(i interface{}, key string) string {
+	t := reflect.TypeOf(i)
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Slice {
+		return "slice:" + key
+	}
+
+	return key
+}
+
+// Cached record. Values of fields are stored by column name, so that JSON
+// tags of structure do not change which fields are cached.
+type cachedRecord map[string]json.RawMessage
+
+// Returns cached record containing values of all fields of structure v.
+func (tbl *dbTable) encodeRecord(v reflect.Value) (cachedRecord, error) {
+	r := make(cachedRecord, len(tbl.orderedFields))
+	for _, f := range tbl.orderedFields {
+		data, err := json.Marshal(f.value(v).Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		r[f.column] = data
+	}
+
+	return r, nil
+}
+
+// Sets fields of structure v to values of cached record.
+func (tbl *dbTable) decodeRecord(r cachedRecord, v reflect.Value) error {
+	for _, f := range tbl.orderedFields {
+		data, ok := r[f.column]
+		if !ok {
+			continue
+		}
+
+		err := json.Unmarshal(data, f.value(v).Addr().Interface())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Reads cached query result to i. Returns number of processed rows and true
+// if result was found in cache. Cache is not used within transactions.
+func (dbh *DbHelper) getCached(tbl *dbTable, key string, i interface{}) (int64, bool) {
+	if dbh.Cache == nil || dbh.tx != nil {
+		return 0, false
+	}
+
+	data, ok := dbh.Cache.Get(tbl.name, cacheKey(i, key))
+	if !ok {
+		return 0, false
+	}
+
+	var records []cachedRecord
+	err := json.Unmarshal(data, &records)
+	if err != nil {
+		return 0, false
+	}
+
+	v := reflect.ValueOf(i).Elem()
+	if v.Kind() != reflect.Slice {
+		if len(records) != 1 {
+			return 0, false
+		}
+
+		// fields missing in cached record must not keep previous values
+		record := reflect.New(v.Type()).Elem()
+		err = tbl.decodeRecord(records[0], record)
+		if err != nil {
+			return 0, false
+		}
+
+		v.Set(record)
+		return 1, true
+	}
+
+	// elements of slice are structures or pointers to structures
+	elemType := v.Type().Elem()
+	slice := reflect.MakeSlice(v.Type(), len(records), len(records))
+	for n, r := range records {
+		elem := slice.Index(n)
+		if elemType.Kind() == reflect.Ptr {
+			elem.Set(reflect.New(elemType.Elem()))
+			elem = elem.Elem()
+		}
+
+		err = tbl.decodeRecord(r, elem)
+		if err != nil {
+			return 0, false
+		}
+	}
+
+	v.Set(slice)
+	return int64(len(records)), true
+}
+
+// Stores query result in cache. Empty single row results are not cached.
+// Result is not stored if table was invalidated since generation gen was
+// returned by cacheGeneration, because it may be stale.
+func (dbh *DbHelper) setCached(tbl *dbTable, key string, i interface{}, num int64, gen uint64) {
+	if dbh.Cache == nil || dbh.tx != nil {
+		return
+	}
+
+	v := reflect.ValueOf(i).Elem()
+	if num == 0 && v.Kind() != reflect.Slice {
+		return
+	}
+
+	// single structure is cached as slice with one record
+	var records []cachedRecord
+	if v.Kind() == reflect.Slice {
+		records = make([]cachedRecord, 0, v.Len())
+		for n := 0; n < v.Len(); n++ {
+			elem := reflect.Indirect(v.Index(n))
+			if !elem.IsValid() {
+				return
+			}
+
+			r, err := tbl.encodeRecord(elem)
+			if err != nil {
+				return
+			}
+
+			records = append(records, r)
+		}
+	} else {
+		r, err := tbl.encodeRecord(v)
+		if err != nil {
+			return
+		}
+
+		records = []cachedRecord{r}
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return
+	}
+
+	if dbh.cacheGeneration(tbl) != gen {
+		return
+	}
+
+	dbh.Cache.Set(tbl.name, cacheKey(i, key), data)
+
+	// table was invalidated while result was stored
+	if dbh.cacheGeneration(tbl) != gen {
+		dbh.Cache.Invalidate(tbl.name)
+	}
+}
+
+// Invalidates cached results for table. Within a transaction cached results
+// are invalidated after commit.
+func (dbh *DbHelper) invalidate(tbl *dbTable) {
+	if dbh.Cache == nil {
+		return
+	}
+
+	if dbh.tx != nil {
+		if dbh.txTables == nil {
+			dbh.txTables = make(map[string]bool)
+		}
+
+		dbh.txTables[tbl.name] = true
+		return
+	}
+
+	dbh.invalidateTable(tbl.name)
+}
+
+// Removes cached results of table and starts its new generation.
+func (dbh *DbHelper) invalidateTable(name string) {
+	dbh.cacheGens.next(name)
+	dbh.Cache.Invalidate(name)
+}
+
+// Entry of LRU cache.
+type lruEntry struct {
+	table string
+	key   string
+	value []byte
+}
+
+// LRUCache is an in-memory Cache which holds limited number of values.
+// When the limit is reached, least recently used values are removed.
+type LRUCache struct {
+	size    int
+	list    *list.List
+	entries map[string]map[string]*list.Element
+	mutex   sync.Mutex
+}
+
+// NewLRUCache returns new LRUCache holding up to size values.
+func NewLRUCache(size int) *LRUCache {
+	return &LRUCache{
+		size:    size,
+		list:    list.New(),
+		entries: make(map[string]map[string]*list.Element),
+	}
+}
+
+// Get implements Cache.
+func (c *LRUCache) Get(table string, key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e, ok := c.entries[table][key]
+	if !ok {
+		return nil, false
+	}
+
+	c.list.MoveToFront(e)
+	return e.Value.(*lruEntry).value, true
+}
+
+// Set implements Cache.
+func (c *LRUCache) Set(table string, key string, value []byte) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// update existing value
+	if e, ok := c.entries[table][key]; ok {
+		e.Value.(*lruEntry).value = value
+		c.list.MoveToFront(e)
+		return
+	}
+
+	// cache of zero size holds no values
+	if c.size <= 0 {
+		return
+	}
+
+	// remove least recently used value
+	if c.list.Len() >= c.size {
+		e := c.list.Back()
+		if e != nil {
+			c.remove(e)
+		}
+	}
+
+	// add new value
+	tableEntries, ok := c.entries[table]
+	if !ok {
+		tableEntries = make(map[string]*list.Element)
+		c.entries[table] = tableEntries
+	}
+
+	tableEntries[key] = c.list.PushFront(&lruEntry{
+		table: table,
+		key:   key,
+		value: value,
+	})
+}
+
+// Invalidate implements Cache.
+func (c *LRUCache) Invalidate(table string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, e := range c.entries[table] {
+		c.list.Remove(e)
+	}
+
+	delete(c.entries, table)
+}
+
+func (c *LRUCache) remove(e *list.Element) {
+	entry := c.list.Remove(e).(*lruEntry)
+	delete(c.entries[entry.table], entry.key)
+}
@@ -0,0 +1,137 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLRUCache(t *testing.T) {
+	c := NewLRUCache(2)
+	c.Set("test", "id:1", []byte("1"))
+	c.Set("test", "id:2", []byte("2"))
+
+	// use first value, so that second one is removed
+	if _, ok := c.Get("test", "id:1"); !ok {
+		t.Error("value expected")
+		return
+	}
+
+	c.Set("other", "id:3", []byte("3"))
+
+	if _, ok := c.Get("test", "id:2"); ok {
+		t.Error("least recently used value was not removed")
+		return
+	}
+
+	// invalidate table
+	c.Invalidate("test")
+
+	if _, ok := c.Get("test", "id:1"); ok {
+		t.Error("value was not invalidated")
+		return
+	}
+
+	if v, ok := c.Get("other", "id:3"); !ok || string(v) != "3" {
+		t.Error("value of another table was invalidated")
+		return
+	}
+}
+
+func TestLRUCacheZeroSize(t *testing.T) {
+	c := NewLRUCache(0)
+	c.Set("test", "id:1", []byte("1"))
+
+	if _, ok := c.Get("test", "id:1"); ok {
+		t.Error("cache of zero size holds value")
+		return
+	}
+}
+
+type testCachedStruct struct {
+	Id     int64  `db:"id" dbopt:"id"`
+	Name   string `db:"name" json:"title"`
+	Secret string `db:"secret" json:"-"`
+}
+
+func TestCachedRecord(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	dbh.Cache = NewLRUCache(10)
+
+	err := dbh.AddTable(testCachedStruct{}, "test_cached")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testCachedStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// fields hidden from JSON are cached
+	dbh.setCached(tbl, "id:1", &testCachedStruct{Id: 1, Name: "name", Secret: "secret"}, 1, 0)
+
+	s := &testCachedStruct{Id: 2, Name: "other", Secret: "other"}
+	num, ok := dbh.getCached(tbl, "id:1", s)
+	if !ok || num != 1 || *s != (testCachedStruct{Id: 1, Name: "name", Secret: "secret"}) {
+		t.Errorf("unexpected cached record: %v, %d, %v", ok, num, s)
+		return
+	}
+
+	// slice is replaced with cached one
+	dbh.setCached(tbl, "all", &[]*testCachedStruct{{Id: 1, Secret: "a"}, {Id: 2, Secret: "b"}}, 2, 0)
+
+	list := []*testCachedStruct{{Id: 3}}
+	num, ok = dbh.getCached(tbl, "all", &list)
+	if !ok || num != 2 || len(list) != 2 || list[1].Secret != "b" {
+		t.Errorf("unexpected cached records: %v, %d, %v", ok, num, list)
+		return
+	}
+}
+
+func TestCacheGeneration(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	dbh.Cache = NewLRUCache(10)
+
+	err := dbh.AddTable(testCachedStruct{}, "test_cached")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testCachedStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// result selected before invalidation is not cached
+	gen := dbh.cacheGeneration(tbl)
+	dbh.invalidate(tbl)
+	dbh.setCached(tbl, "id:1", &testCachedStruct{Id: 1, Name: "stale"}, 1, gen)
+
+	var s testCachedStruct
+	if _, ok := dbh.getCached(tbl, "id:1", &s); ok {
+		t.Errorf("stale record is cached: %v", s)
+		return
+	}
+
+	gen = dbh.cacheGeneration(tbl)
+	dbh.setCached(tbl, "id:1", &testCachedStruct{Id: 1, Name: "name"}, 1, gen)
+	if _, ok := dbh.getCached(tbl, "id:1", &s); !ok || s.Name != "name" {
+		t.Errorf("unexpected cached record: %v", s)
+		return
+	}
+}
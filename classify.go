@@ -0,0 +1,118 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// ErrorClassifier decides whether error of database is transient, so that
+// operation can be retried, e.g. after deadlock or lost connection. SQL
+// dialects implementing ErrorClassifier classify errors of their drivers.
+type ErrorClassifier interface {
+	IsTransient(err error) bool
+}
+
+// ErrorClassifierFunc is a function implementing ErrorClassifier.
+type ErrorClassifierFunc func(err error) bool
+
+// IsTransient calls f.
+func (f ErrorClassifierFunc) IsTransient(err error) bool {
+	return f(err)
+}
+
+// IsTransient returns true if err is transient according to ErrorClassifier
+// of DbHelper or of SQL dialect. Lost connections are always transient.
+func (dbh *DbHelper) IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+
+	if dbh.ErrorClassifier != nil && dbh.ErrorClassifier.IsTransient(err) {
+		return true
+	}
+
+	if sqld, ok := dbh.sqlDialect.(ErrorClassifier); ok {
+		return sqld.IsTransient(err)
+	}
+
+	return false
+}
+
+// Returns SQLSTATE code of error of lib/pq or pgx drivers.
+func sqlState(err error) string {
+	var e interface {
+		SQLState() string
+	}
+
+	if errors.As(err, &e) {
+		return e.SQLState()
+	}
+
+	return ""
+}
+
+// Returns value of integer field of error, found in chain of wrapped
+// errors, used to read codes of drivers without importing them.
+func errorCode(err error, field string) (int64, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		v := reflect.Indirect(reflect.ValueOf(err))
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+
+		f := v.FieldByName(field)
+		switch f.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return f.Int(), true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return int64(f.Uint()), true
+		}
+	}
+
+	return 0, false
+}
+
+// IsTransient returns true for serialization failures, deadlocks, lock
+// timeouts, connection errors and server shutdown.
+func (sqld Postgresql) IsTransient(err error) bool {
+	state := sqlState(err)
+	switch state {
+	case "40001", "40P01", "55P03", "53300", "57P01", "57P02", "57P03":
+		return true
+	}
+
+	// connection exceptions
+	return strings.HasPrefix(state, "08")
+}
+
+// IsTransient returns true for deadlocks and lock wait timeouts reported
+// by go-sql-driver/mysql.
+func (sqld MySql) IsTransient(err error) bool {
+	number, ok := errorCode(err, "Number")
+	return ok && (number == 1213 || number == 1205)
+}
+
+// IsTransient returns true for busy and locked database reported by
+// mattn/go-sqlite3.
+func (sqld Sqlite) IsTransient(err error) bool {
+	code, ok := errorCode(err, "Code")
+	return ok && (code == 5 || code == 6)
+}
@@ -0,0 +1,84 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+type testPgError struct {
+	code string
+}
+
+func (e *testPgError) Error() string {
+	return "pq: " + e.code
+}
+
+func (e *testPgError) SQLState() string {
+	return e.code
+}
+
+type testMySqlError struct {
+	Number uint16
+}
+
+func (e *testMySqlError) Error() string {
+	return "mysql error"
+}
+
+type testSqliteError struct {
+	Code int
+}
+
+func (e testSqliteError) Error() string {
+	return "sqlite error"
+}
+
+func TestIsTransient(t *testing.T) {
+	pg := New(nil, Postgresql{})
+	deadlock := &QueryError{Query: "UPDATE test SET b = :b", Err: &testPgError{"40P01"}}
+	if !pg.IsTransient(deadlock) || !pg.IsTransient(&testPgError{"08006"}) || pg.IsTransient(&testPgError{"23505"}) {
+		t.Error("unexpected classification of Postgresql errors")
+		return
+	}
+
+	my := New(nil, MySql{})
+	if !my.IsTransient(&testMySqlError{1213}) || my.IsTransient(&testMySqlError{1062}) {
+		t.Error("unexpected classification of MySql errors")
+		return
+	}
+
+	lite := New(nil, Sqlite{})
+	if !lite.IsTransient(testSqliteError{5}) || lite.IsTransient(testSqliteError{19}) {
+		t.Error("unexpected classification of Sqlite errors")
+		return
+	}
+
+	// lost connections are transient for all dialects
+	if !New(nil, ClickHouse{}).IsTransient(driver.ErrBadConn) || pg.IsTransient(nil) {
+		t.Error("unexpected classification of connection errors")
+		return
+	}
+
+	// errors declared by user
+	errThrottled := errors.New("throttled")
+	pg.ErrorClassifier = ErrorClassifierFunc(func(err error) bool {
+		return errors.Is(err, errThrottled)
+	})
+
+	if !pg.IsTransient(errThrottled) || !pg.IsTransient(deadlock) {
+		t.Error("unexpected classification of user errors")
+		return
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Command dbhelpercols generates column references of structures mapped by
+// dbhelper. For each structure type it declares variable <Type>Cols with a
+// field per mapped column holding the name of the column, so queries refer
+// to columns as UserCols.Email instead of "email". Removing or renaming a
+// field breaks compilation of queries using it, and changing its db tag
+// changes queries when the file is generated again.
+//
+// Usage with go generate:
+//
+//	//go:generate dbhelpercols -type User,Order
+//
+// Generated code is written to <file>_cols.go next to the source file.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	typeNames = flag.String("type", "", "comma-separated list of structure types; all structures with db tags if empty")
+	output    = flag.String("output", "", "output file name; default <file>_cols.go")
+)
+
+// Column of structure type.
+type column struct {
+	field string
+	name  string
+}
+
+func main() {
+	flag.Parse()
+
+	// file is passed by go generate if not specified
+	file := flag.Arg(0)
+	if file == "" {
+		file = os.Getenv("GOFILE")
+	}
+
+	if file == "" {
+		fmt.Fprintln(os.Stderr, "dbhelpercols: source file is not specified")
+		os.Exit(2)
+	}
+
+	out := *output
+	if out == "" {
+		out = strings.TrimSuffix(file, ".go") + "_cols.go"
+	}
+
+	var types []string
+	if *typeNames != "" {
+		types = strings.Split(*typeNames, ",")
+	}
+
+	src, err := generate(file, types)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	err = os.WriteFile(out, src, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Returns source code with columns of types declared in file. If types are
+// not specified, all structures having db tags are used.
+func generate(file string, types []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	// structures declared in file
+	structs := make(map[string]*ast.StructType)
+	var names []string
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+
+			structs[ts.Name.Name] = st
+			names = append(names, ts.Name.Name)
+		}
+	}
+
+	// use structures having db tags by default
+	if len(types) == 0 {
+		for _, name := range names {
+			if hasDbTags(structs[name]) {
+				types = append(types, name)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by dbhelpercols; DO NOT EDIT.\n\npackage %s\n", f.Name.Name)
+
+	for _, name := range types {
+		st, ok := structs[name]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("dbhelpercols: structure type '%s' is not declared in '%s'", name, file))
+		}
+
+		columns := structColumns(st, structs, make(map[string]bool))
+
+		// names of variables follow export of types
+		varName := name + "Cols"
+		fmt.Fprintf(&buf, "\n// %s lists columns of table assigned to %s.\nvar %s = struct {\n", varName, name, varName)
+		for _, c := range columns {
+			fmt.Fprintf(&buf, "%s string\n", c.field)
+		}
+
+		buf.WriteString("}{\n")
+		for _, c := range columns {
+			fmt.Fprintf(&buf, "%s: %s,\n", c.field, strconv.Quote(c.name))
+		}
+
+		buf.WriteString("}\n")
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+// Returns true if structure has fields with db tags.
+func hasDbTags(st *ast.StructType) bool {
+	for _, field := range st.Fields.List {
+		if _, ok := fieldTag(field).Lookup("db"); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns tag of field.
+func fieldTag(field *ast.Field) reflect.StructTag {
+	if field.Tag == nil {
+		return ""
+	}
+
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+
+	return reflect.StructTag(tag)
+}
+
+// Returns columns of structure mapped in the same way as by dbhelper.
+// Embedded structures declared in the same file are expanded, columns of
+// outer structure take precedence.
+func structColumns(st *ast.StructType, structs map[string]*ast.StructType, visited map[string]bool) []column {
+	var columns []column
+	var embedded []*ast.StructType
+	for _, field := range st.Fields.List {
+		tag := fieldTag(field)
+		if tag.Get("db") == "-" {
+			continue
+		}
+
+		// embedded structure
+		if len(field.Names) == 0 {
+			typ := field.Type
+			if star, ok := typ.(*ast.StarExpr); ok {
+				typ = star.X
+			}
+
+			ident, ok := typ.(*ast.Ident)
+			if !ok || visited[ident.Name] || structs[ident.Name] == nil {
+				continue
+			}
+
+			visited[ident.Name] = true
+			embedded = append(embedded, structs[ident.Name])
+			continue
+		}
+
+		for _, name := range field.Names {
+			// unexported fields are not mapped
+			if !name.IsExported() {
+				continue
+			}
+
+			// if db tag is empty, use field name as column name
+			col := tag.Get("db")
+			if col == "" {
+				col = name.Name
+			}
+
+			columns = append(columns, column{field: name.Name, name: col})
+		}
+	}
+
+	for _, sub := range embedded {
+		for _, c := range structColumns(sub, structs, visited) {
+			if !hasField(columns, c.field) {
+				columns = append(columns, c)
+			}
+		}
+	}
+
+	return columns
+}
+
+// Returns true if columns contain field with name.
+func hasField(columns []column, name string) bool {
+	for _, c := range columns {
+		if c.field == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,98 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testSource = `package models
+
+type Base struct {
+	Id      int64 ` + "`db:\"id\" dbopt:\"id,auto\"`" + `
+	Created int64 ` + "`db:\"created\"`" + `
+}
+
+type User struct {
+	Base
+	Email   string ` + "`db:\"email\"`" + `
+	Name    string
+	Created int64  ` + "`db:\"created_at\"`" + `
+	Skip    string ` + "`db:\"-\"`" + `
+	secret  string
+}
+
+type Options struct {
+	Debug bool
+}
+`
+
+// Writes source to temporary file and returns its name.
+func writeSource(t *testing.T, src string) string {
+	file := filepath.Join(t.TempDir(), "models.go")
+	err := os.WriteFile(file, []byte(src), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+func TestGenerate(t *testing.T) {
+	file := writeSource(t, testSource)
+
+	// structures with db tags are used by default
+	src, err := generate(file, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	code := string(src)
+	for _, s := range []string{
+		"package models",
+		"var BaseCols = struct {",
+		"var UserCols = struct {",
+		`Email:   "email",`,
+		`Name:    "Name",`,
+		`Created: "created_at",`,
+		`Id:      "id",`,
+	} {
+		if !strings.Contains(code, s) {
+			t.Errorf("generated code does not contain %q:\n%s", s, code)
+			return
+		}
+	}
+
+	for _, s := range []string{"OptionsCols", "Skip", "secret"} {
+		if strings.Contains(code, s) {
+			t.Errorf("generated code contains %q:\n%s", s, code)
+			return
+		}
+	}
+
+	// column of outer structure takes precedence
+	if strings.Count(code, `"created"`) != 1 {
+		t.Errorf("column of embedded structure is used:\n%s", code)
+		return
+	}
+
+	// selected types
+	src, err = generate(file, []string{"Options"})
+	if err != nil || !strings.Contains(string(src), `Debug: "Debug",`) || strings.Contains(string(src), "UserCols") {
+		t.Errorf("unexpected code: %s, %v", src, err)
+		return
+	}
+
+	// unknown type
+	_, err = generate(file, []string{"Order"})
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cond is a condition of WHERE clause. Conditions are compiled to SQL with
+// named parameters, which are replaced with placeholders of SQL dialect by
+// Prepare, so values never become a part of query text.
+type Cond interface {
+	// Returns SQL of the condition, storing parameter values in b.
+	build(b *condBuilder) (string, error)
+}
+
+// Compiles conditions, collecting parameter values.
+type condBuilder struct {
+	params map[string]interface{}
+
+	// Optional check of column names.
+	checkColumn func(column string) error
+
+	// Optional quoting of column names.
+	quote func(name string) string
+}
+
+// Stores parameter value and returns named placeholder for it.
+func (b *condBuilder) param(value interface{}) string {
+	name := fmt.Sprintf("c%d", len(b.params))
+	b.params[name] = value
+	return getNamedPlaceholder(name)
+}
+
+// Checks column name if check is defined.
+func (b *condBuilder) column(column string) (string, error) {
+	if b.checkColumn != nil {
+		err := b.checkColumn(column)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if b.quote != nil {
+		return b.quote(column), nil
+	}
+
+	return column, nil
+}
+
+// BuildCond compiles condition to SQL with named parameters and returns it
+// together with parameter values. Result can be used with Prepare and Query.
+func BuildCond(c Cond) (string, map[string]interface{}, error) {
+	b := &condBuilder{
+		params: make(map[string]interface{}),
+	}
+
+	sql, err := c.build(b)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return sql, b.params, nil
+}
+
+// Comparison of column with value.
+type compareCond struct {
+	column string
+	op     string
+	value  interface{}
+}
+
+func (c *compareCond) build(b *condBuilder) (string, error) {
+	column, err := b.column(c.column)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s %s %s", column, c.op, b.param(c.value)), nil
+}
+
+// Eq is a condition: column = value.
+func Eq(column string, value interface{}) Cond {
+	return &compareCond{column, "=", value}
+}
+
+// Neq is a condition: column <> value.
+func Neq(column string, value interface{}) Cond {
+	return &compareCond{column, "<>", value}
+}
+
+// Gt is a condition: column > value.
+func Gt(column string, value interface{}) Cond {
+	return &compareCond{column, ">", value}
+}
+
+// Gte is a condition: column >= value.
+func Gte(column string, value interface{}) Cond {
+	return &compareCond{column, ">=", value}
+}
+
+// Lt is a condition: column < value.
+func Lt(column string, value interface{}) Cond {
+	return &compareCond{column, "<", value}
+}
+
+// Lte is a condition: column <= value.
+func Lte(column string, value interface{}) Cond {
+	return &compareCond{column, "<=", value}
+}
+
+// Like is a condition: column LIKE pattern.
+func Like(column string, pattern string) Cond {
+	return &compareCond{column, "LIKE", pattern}
+}
+
+// Condition: column IN (values).
+type inCond struct {
+	column string
+	values []interface{}
+}
+
+func (c *inCond) build(b *condBuilder) (string, error) {
+	column, err := b.column(c.column)
+	if err != nil {
+		return "", err
+	}
+
+	// empty list does not match anything
+	if len(c.values) == 0 {
+		return "1 = 0", nil
+	}
+
+	holders := make([]string, len(c.values))
+	for i, v := range c.values {
+		holders[i] = b.param(v)
+	}
+
+	return fmt.Sprintf("%s IN (%s)", column, strings.Join(holders, ", ")), nil
+}
+
+// In is a condition: column IN (values). Condition with empty list of values
+// does not match any rows.
+func In(column string, values ...interface{}) Cond {
+	return &inCond{column, values}
+}
+
+// Conditions joined with AND or OR.
+type joinCond struct {
+	op    string
+	conds []Cond
+}
+
+func (c *joinCond) build(b *condBuilder) (string, error) {
+	// empty AND matches all rows, empty OR does not match anything
+	if len(c.conds) == 0 {
+		if c.op == "AND" {
+			return "1 = 1", nil
+		}
+
+		return "1 = 0", nil
+	}
+
+	parts := make([]string, len(c.conds))
+	for i, cond := range c.conds {
+		sql, err := cond.build(b)
+		if err != nil {
+			return "", err
+		}
+
+		parts[i] = sql
+	}
+
+	return fmt.Sprintf("(%s)", strings.Join(parts, fmt.Sprintf(" %s ", c.op))), nil
+}
+
+// And is a condition which is true if all conds are true.
+func And(conds ...Cond) Cond {
+	return &joinCond{"AND", conds}
+}
+
+// Or is a condition which is true if any of conds is true.
+func Or(conds ...Cond) Cond {
+	return &joinCond{"OR", conds}
+}
+
+// Negation of condition.
+type notCond struct {
+	cond Cond
+}
+
+func (c *notCond) build(b *condBuilder) (string, error) {
+	sql, err := c.cond.build(b)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("NOT (%s)", sql), nil
+}
+
+// Not is a negation of condition.
+func Not(cond Cond) Cond {
+	return &notCond{cond}
+}
+
+// Condition: column IN (subquery).
+type inSubqueryCond struct {
+	column string
+	query  *SelectBuilder
+}
+
+func (c *inSubqueryCond) build(b *condBuilder) (string, error) {
+	column, err := b.column(c.column)
+	if err != nil {
+		return "", err
+	}
+
+	sub, err := c.query.build(b)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s IN (%s)", column, sub), nil
+}
+
+// InSubquery is a condition: column IN (query). Parameters of the subquery
+// are merged with parameters of the outer query.
+func InSubquery(column string, query *SelectBuilder) Cond {
+	return &inSubqueryCond{column, query}
+}
+
+// Condition: EXISTS (subquery).
+type existsCond struct {
+	query *SelectBuilder
+}
+
+func (c *existsCond) build(b *condBuilder) (string, error) {
+	sub, err := c.query.build(b)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("EXISTS (%s)", sub), nil
+}
+
+// Exists is a condition which is true if query returns any rows.
+// Parameters of the subquery are merged with parameters of the outer query.
+func Exists(query *SelectBuilder) Cond {
+	return &existsCond{query}
+}
@@ -0,0 +1,292 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// CSVNull is written by ExportCSV in place of NULL values and is read back
+// as NULL by ImportCSV.
+const CSVNull = `\N`
+
+// Returns string written to CSV for value arg of a field.
+func csvValue(arg interface{}) (string, error) {
+	if valuer, ok := arg.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return "", wrapError(err)
+		}
+
+		arg = value
+	}
+
+	switch x := arg.(type) {
+	case nil:
+		return CSVNull, nil
+	case []byte:
+		return string(x), nil
+	}
+
+	return fmt.Sprint(arg), nil
+}
+
+// Assigns value parsed from string s to structure field v.
+func setFieldString(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return wrapError(err)
+		}
+
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return wrapError(err)
+		}
+
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return wrapError(err)
+		}
+
+		v.SetBool(b)
+	default:
+		return errors.New(fmt.Sprintf("dbhelper: unsupported field type '%v'", v.Type()))
+	}
+
+	return nil
+}
+
+// ExportCSV writes records of the table assigned to type of i to w in CSV format.
+// The first line contains column names. All records are exported, unless query
+// is provided, in which case records returned by the query are exported.
+// Query must not have parameters. NULL values are written as CSVNull.
+// Records are written as they are read, so they are not loaded to memory at once.
+func (dbh *DbHelper) ExportCSV(i interface{}, w io.Writer, query ...string) error {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return err
+	}
+
+	// get table
+	tbl, err := dbh.getPreparedTable(t)
+	if err != nil {
+		return err
+	}
+
+	// parameters of query limited to tenant
+	var params interface{}
+	if tbl.tenantField != nil {
+		scoped := make(map[string]interface{}, 1)
+		err = dbh.scopeParams(tbl, scoped)
+		if err != nil {
+			return err
+		}
+
+		params = scoped
+	}
+
+	q := tbl.selectAllQuery.in(dbh)
+	if len(query) > 0 {
+		params = nil
+
+		q, err = dbh.Prepare(query[0])
+		if err != nil {
+			return err
+		}
+
+		defer q.Close()
+	}
+
+	cw := csv.NewWriter(w)
+
+	// write header
+	line := make([]string, len(tbl.orderedFields))
+	for n, f := range tbl.orderedFields {
+		line[n] = f.column
+	}
+
+	err = cw.Write(line)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	// write records as they are scanned
+	shape, err := dbh.resultShapeOf(reflect.PtrTo(t))
+	if err != nil {
+		return err
+	}
+
+	stream := *shape
+	stream.each = func(v reflect.Value) error {
+		for n, f := range tbl.orderedFields {
+			s, err := csvValue(f.arg(v))
+			if err != nil {
+				return err
+			}
+
+			line[n] = s
+		}
+
+		err := cw.Write(line)
+		if err != nil {
+			return wrapError(err)
+		}
+
+		return nil
+	}
+
+	_, err = q.queryShape(dbh.context(), &stream, reflect.New(t), params, nil)
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	err = cw.Error()
+	if err != nil {
+		return wrapError(err)
+	}
+
+	return nil
+}
+
+// ImportCSV reads records in CSV format from r and inserts them to the table
+// assigned to type of i. The first line must contain column names. CSVNull
+// values are assigned as NULL to fields that can store it. Records
+// are inserted using Insert within one transaction, so values of auto
+// incremented and timestamp columns are set automatically.
+// Returns number of inserted records.
+func (dbh *DbHelper) ImportCSV(i interface{}, r io.Reader) (int64, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return 0, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return 0, err
+	}
+
+	cr := csv.NewReader(r)
+
+	// read header
+	header, err := cr.Read()
+	if err != nil {
+		return 0, wrapError(err)
+	}
+
+	fields := make([]*dbField, len(header))
+	for n, col := range header {
+		f, ok := tbl.fields[col]
+		if !ok {
+			return 0, errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field assigned to column '%s' of table '%s'",
+				t, col, tbl.name))
+		}
+
+		fields[n] = f
+	}
+
+	// start transaction if needed
+	txh := dbh
+	if dbh.tx == nil {
+		txh, err = dbh.Begin()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	num, err := txh.importCSV(t, cr, fields)
+	if err != nil {
+		if txh != dbh {
+			txh.Rollback()
+		}
+
+		return 0, err
+	}
+
+	if txh != dbh {
+		err = txh.Commit()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return num, nil
+}
+
+// Assigns NULL to field f of structure v.
+func setFieldNull(f *dbField, v reflect.Value) error {
+	if !f.nullable() {
+		return errors.New(fmt.Sprintf("dbhelper: column '%s' cannot store NULL value", f.column))
+	}
+
+	return f.dest(v).(sql.Scanner).Scan(nil)
+}
+
+func (dbh *DbHelper) importCSV(t reflect.Type, cr *csv.Reader, fields []*dbField) (int64, error) {
+	num := int64(0)
+	for {
+		line, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return 0, wrapError(err)
+		}
+
+		// create new structure
+		ptr := reflect.New(t)
+		v := ptr.Elem()
+
+		// fill fields
+		for n, s := range line {
+			if s == CSVNull {
+				err = setFieldNull(fields[n], v)
+			} else if scanner, ok := fields[n].dest(v).(sql.Scanner); ok {
+				err = scanner.Scan(s)
+			} else {
+				err = setFieldString(fields[n].value(v), s)
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		// insert record
+		err = dbh.Insert(ptr.Interface())
+		if err != nil {
+			return 0, err
+		}
+
+		num++
+	}
+
+	return num, nil
+}
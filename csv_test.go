@@ -0,0 +1,177 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+type testCSVStruct struct {
+	Id   int64  `db:"id" dbopt:"id"`
+	Name string `db:"name"`
+}
+
+type testCSVTenantStruct struct {
+	Id     int64          `db:"id" dbopt:"id"`
+	Name   sql.NullString `db:"name"`
+	Tenant string         `db:"tenant"`
+}
+
+// Returns DbHelper with standard queries of test_csv table, select of all
+// records returns rows.
+func newTestCSVDb(rows [][]driver.Value) *DbHelper {
+	newTestDriverDb("INSERT INTO test_csv(id, name) VALUES($1, $2) RETURNING id", &testResult{
+		columns: []string{"id"},
+		rows:    [][]driver.Value{{int64(1)}},
+	})
+	newTestDriverDb("UPDATE test_csv SET name = $1 WHERE id = $2", &testResult{})
+	newTestDriverDb("DELETE FROM test_csv WHERE id = $1", &testResult{})
+	newTestDriverDb("SELECT * FROM test_csv WHERE id = $1", &testResult{})
+
+	return newTestDriverDb("SELECT * FROM test_csv", &testResult{
+		columns: []string{"id", "name"},
+		rows:    rows,
+	})
+}
+
+func TestExportCSV(t *testing.T) {
+	dbh := newTestCSVDb([][]driver.Value{{int64(1), "a"}, {int64(2), "b,c"}})
+
+	err := dbh.AddTable(testCSVStruct{}, "test_csv")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	err = dbh.ExportCSV(testCSVStruct{}, &buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if buf.String() != "id,name\n1,a\n2,\"b,c\"\n" {
+		t.Errorf("unexpected CSV: %q", buf.String())
+		return
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	dbh := newTestCSVDb(nil)
+
+	err := dbh.AddTable(testCSVStruct{}, "test_csv")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	num, err := dbh.ImportCSV(testCSVStruct{}, strings.NewReader("name,id\na,1\n\"b,c\",2\n"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if num != 2 {
+		t.Errorf("unexpected number of records: %d", num)
+		return
+	}
+
+	// unknown column
+	_, err = dbh.ImportCSV(testCSVStruct{}, strings.NewReader("id,unknown\n1,a\n"))
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	rows := [][]driver.Value{
+		{int64(1), "a", "acme"},
+		{int64(2), nil, "acme"},
+		{int64(3), "c", "other"},
+	}
+
+	// inserted records
+	var inserted [][]driver.Value
+
+	newTestDriverDb("INSERT INTO test_csv_tenant(id, name, tenant) VALUES($1, $2, $3) RETURNING id", &testResult{
+		execErr: func(args []driver.Value) error {
+			inserted = append(inserted, args)
+			return nil
+		},
+	})
+	newTestDriverDb("UPDATE test_csv_tenant SET name = $1 WHERE id = $2 AND tenant = $3", &testResult{})
+	newTestDriverDb("DELETE FROM test_csv_tenant WHERE id = $1 AND tenant = $2", &testResult{})
+	newTestDriverDb("SELECT * FROM test_csv_tenant WHERE id = $1 AND tenant = $2", &testResult{})
+	dbh := newTestDriverDb("SELECT * FROM test_csv_tenant WHERE tenant = $1", &testResult{
+		columns: []string{"id", "name", "tenant"},
+		queryRows: func(args []driver.Value) [][]driver.Value {
+			var result [][]driver.Value
+			for _, row := range rows {
+				if row[2] == args[0] {
+					result = append(result, row)
+				}
+			}
+
+			return result
+		},
+	})
+
+	err := dbh.AddTableWith(testCSVTenantStruct{}, TableOptions{
+		Name:   "test_csv_tenant",
+		Tenant: "tenant",
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// tenant is required
+	var buf bytes.Buffer
+	err = dbh.ExportCSV(testCSVTenantStruct{}, &buf)
+	if err != ErrNoTenant {
+		t.Errorf("ErrNoTenant expected: %v", err)
+		return
+	}
+
+	acme := dbh.WithTenant("acme")
+	err = acme.ExportCSV(testCSVTenantStruct{}, &buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if buf.String() != "id,name,tenant\n1,a,acme\n2,\\N,acme\n" {
+		t.Errorf("unexpected CSV: %q", buf.String())
+		return
+	}
+
+	num, err := acme.ImportCSV(testCSVTenantStruct{}, &buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if num != 2 || len(inserted) != 2 {
+		t.Errorf("unexpected number of records: %d", num)
+		return
+	}
+
+	if inserted[0][1] != "a" || inserted[1][1] != nil {
+		t.Errorf("unexpected records: %v", inserted)
+		return
+	}
+}
@@ -12,18 +12,35 @@
 package dbhelper
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
-	"strings"
+	"sync"
 	"time"
 )
 
 var (
 	paramRegexp *regexp.Regexp
 	errorNil    = errors.New("dbhelper: cannot use nil to define type")
+
+	// ErrNotFound is returned by single row selectors when no record was found.
+	ErrNotFound = errors.New("dbhelper: record not found")
+
+	// ErrNoRowsAffected is returned by UpdateStrict and DeleteStrict when no
+	// record matched the id.
+	ErrNoRowsAffected = errors.New("dbhelper: no rows affected")
+
+	// ErrMaxRows is returned by Query when result has more rows than MaxRows.
+	// Slice contains the first MaxRows rows, which is the returned number of rows.
+	ErrMaxRows = errors.New("dbhelper: query returned more rows than allowed by MaxRows")
+
+	// ErrMultipleRows is returned by Query mapping result to a single
+	// structure or value when query returned several rows and StrictSingleRow
+	// is set. It is the same error as ErrTooManyRows.
+	ErrMultipleRows = ErrTooManyRows
 )
 
 func init() {
@@ -70,7 +87,9 @@ func checkFieldType(t reflect.Type) bool {
 		kind == reflect.Int64 ||
 		kind == reflect.Float32 ||
 		kind == reflect.Float64 ||
-		kind == reflect.Bool
+		kind == reflect.Bool ||
+		getCodec(t) != nil ||
+		isScannerValuer(t)
 }
 
 // DbHelper contains all data about database and tables.
@@ -78,8 +97,137 @@ type DbHelper struct {
 	// Pointer to underlying sql.DB.
 	Db *sql.DB
 
+	// Optional cache for results of SelectById and SelectBy.
+	Cache Cache
+
+	// If not zero, every query is executed with this timeout, unless
+	// context used for execution already has a deadline.
+	DefaultTimeout time.Duration
+
+	// By default, passing a map with keys that are not parameters of the
+	// query is an error. If true, such keys are ignored.
+	AllowUnknownParams bool
+
+	// If true, columns of query results are matched to mapped columns
+	// case-insensitively, since drivers report names of columns in
+	// different case.
+	IgnoreColumnCase bool
+
+	// Defines how NULL values are scanned to fields which cannot store NULL.
+	NullPolicy NullPolicy
+
+	// Hashes values of fields with option 'bcrypt'.
+	SecretHasher SecretHasher
+
+	// If not zero, queries mapping results to slices fail with ErrMaxRows
+	// instead of reading more rows, which protects from unbounded results.
+	// The first MaxRows rows are mapped and their number is returned.
+	MaxRows int64
+
+	// By default, queries mapping results to a single structure or value map
+	// the first row and ignore others. If true, such queries fail with
+	// ErrMultipleRows returning number of rows of the result.
+	StrictSingleRow bool
+
+	// Declares transient errors in addition to ErrorClassifier of SQL
+	// dialect, see IsTransient.
+	ErrorClassifier ErrorClassifier
+
+	// If true, queries executed with context carrying tags set by WithTags
+	// are prefixed with SQL comment listing the tags, which attributes load
+	// in database statistics and logs. Such queries are sent as text instead
+	// of using prepared statements.
+	TagQueries bool
+
+	// Maximum number of queries generated for each table, e.g. by
+	// SelectBuilder or DeleteWhere, which are prepared and reused.
+	// Further queries are executed as text like ExecDirect does.
+	// DefaultMaxPreparedQueries is used if zero, negative is not limited.
+	MaxPreparedQueries int
+
+	// Debug mode: if not zero, transactions that are not committed or
+	// rolled back within this duration are reported to OnLeak together
+	// with stack of Begin. Recording stacks is expensive.
+	LeakTimeout time.Duration
+
+	// Handles leaked transactions, which are logged if nil.
+	OnLeak func(r LeakReport)
+
+	// Number of connections opened by Warmup, set by NewWith and Open.
+	// MaxIdleConns of the pool must allow to keep them idle.
+	MinIdleConns int
+
 	sqlDialect SqlDialect
 	tables     map[reflect.Type]*dbTable
+
+	// Transaction DbHelper is bound to.
+	tx *sql.Tx
+
+	// Tables modified within transaction.
+	txTables map[string]bool
+
+	// Modifications within transaction calling AfterCommit callbacks.
+	txEvents *commitEvents
+
+	// Reports transaction which is not finished, see LeakTimeout.
+	txLeak *leakTimer
+
+	// Identical concurrent select queries.
+	flights *flightGroup
+
+	// Generations of cached results of tables.
+	cacheGens *cacheGenerations
+
+	// Mappings of structures that are not registered as tables.
+	mappings *mappings
+
+	// Tenant set by WithTenant.
+	tenant interface{}
+
+	// Context set by WithContext.
+	ctx context.Context
+
+	// Statements modifying data are refused, set by ReadOnly.
+	readOnly bool
+
+	// Statistics of connection pool, set by StartSampler.
+	sampler *sampler
+}
+
+// WithContext returns DbHelper sharing registered tables and transaction with
+// dbh, which executes queries using context ctx, unless context is passed
+// explicitly, e.g. to QueryContext. Context can carry roles set by WithRoles.
+func (dbh *DbHelper) WithContext(ctx context.Context) *DbHelper {
+	// modified tables must be shared to invalidate cache on commit
+	if dbh.tx != nil && dbh.txTables == nil {
+		dbh.txTables = make(map[string]bool)
+	}
+
+	ch := *dbh
+	ch.ctx = ctx
+	return &ch
+}
+
+// Returns context set by WithContext or background context.
+func (dbh *DbHelper) context() context.Context {
+	if dbh.ctx == nil {
+		return context.Background()
+	}
+
+	return dbh.ctx
+}
+
+// Returns context with default timeout applied.
+func (dbh *DbHelper) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if dbh.DefaultTimeout <= 0 {
+		return ctx, func() {}
+	}
+
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, dbh.DefaultTimeout)
 }
 
 // New returns new DbHelper.
@@ -88,12 +236,26 @@ func New(db *sql.DB, sqlDialect SqlDialect) *DbHelper {
 		Db:         db,
 		sqlDialect: sqlDialect,
 		tables:     make(map[reflect.Type]*dbTable),
+		flights:    &flightGroup{},
+		cacheGens:  &cacheGenerations{},
+		mappings:   &mappings{types: make(map[reflect.Type]*dbTable)},
 	}
 }
 
 // AddTable adds a connection between type of i and table name.
 // There is no difference what to use, type or pointer to type.
 func (dbh *DbHelper) AddTable(i interface{}, name string) error {
+	return dbh.addTable(i, name, false)
+}
+
+// AddView adds a connection between type of i and read-only view name.
+// Only select queries can be performed on views, so structure is not required
+// to have a field with option 'id', unless SelectById is used.
+func (dbh *DbHelper) AddView(i interface{}, name string) error {
+	return dbh.addTable(i, name, true)
+}
+
+func (dbh *DbHelper) addTable(i interface{}, name string, view bool) error {
 	t, err := typeOf(i)
 	if err != nil {
 		return err
@@ -108,11 +270,17 @@ func (dbh *DbHelper) AddTable(i interface{}, name string) error {
 		return errors.New("dbhelper: table name cannot be an empty string")
 	}
 
-	tbl, err = dbh.newDbTable(t, name)
+	if view {
+		tbl, err = dbh.newMapping(t, name)
+	} else {
+		tbl, err = dbh.newDbTable(t, name)
+	}
+
 	if err != nil {
 		return err
 	}
 
+	tbl.view = view
 	dbh.tables[t] = tbl
 
 	return nil
@@ -148,48 +316,206 @@ func (dbh *DbHelper) getTable(t reflect.Type) (*dbTable, error) {
 	return tbl, nil
 }
 
+// Returns mapping of structure type used to read query results. Structures
+// that are not registered as tables are mapped by column names only.
+func (dbh *DbHelper) getMapping(t reflect.Type) (*dbTable, error) {
+	if tbl, ok := dbh.tables[t]; ok {
+		return tbl, nil
+	}
+
+	dbh.mappings.mutex.Lock()
+	defer dbh.mappings.mutex.Unlock()
+
+	if tbl, ok := dbh.mappings.types[t]; ok {
+		return tbl, nil
+	}
+
+	tbl, err := dbh.newMapping(t, "")
+	if err != nil {
+		return nil, err
+	}
+
+	dbh.mappings.types[t] = tbl
+	return tbl, nil
+}
+
+// Returns table with prepared standard queries.
+func (dbh *DbHelper) getPreparedTable(t reflect.Type) (*dbTable, error) {
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tbl.prepare()
+	if err != nil {
+		return nil, err
+	}
+
+	return tbl, nil
+}
+
 func (dbh *DbHelper) getPlaceholders(n int) []string {
 	a := make([]string, n, n)
-	ph := dbh.sqlDialect.placeholder()
+	ph := dbh.sqlDialect.Placeholder()
 	for i := 1; i < n; i++ {
-		a[i] = ph.next()
+		a[i] = ph.Next()
 	}
 
 	return a
 }
 
 // Prepares SQL query. Prepared query can be executed with different parameter values.
-func (dbh *DbHelper) Prepare(query string) (*Pstmt, error) {
-	ph := dbh.sqlDialect.placeholder()
-	params := paramRegexp.FindAllString(query, -1)
-	for i, p := range params {
-		if len(p) < 2 {
-			return nil, errors.New(fmt.Sprintf("dbhelper: wrong parameter placeholder: '%s'", p))
-		}
+// By default query parameters are named (:name). Other parameter style can be
+// chosen using optional style argument. If dbh is bound to a transaction,
+// query is prepared in the transaction, so it may use tables created by it,
+// and is executed as text after the transaction is finished.
+func (dbh *DbHelper) Prepare(query string, style ...ParamStyle) (*Pstmt, error) {
+	paramStyle := NamedParams
+	if len(style) > 0 {
+		paramStyle = style[0]
+	}
+
+	// replace parameters with placeholders
+	sql, params, err := dbh.parseParams(query, paramStyle)
+	if err != nil {
+		return nil, err
+	}
 
-		// replaced named parameter with placeholder
-		query = strings.Replace(query, p, ph.next(), 1)
+	err = dbh.checkParams(params)
+	if err != nil {
+		return nil, err
+	}
 
-		// store named parameter
-		params[i] = p[1:]
+	// prepare query, within transaction it may use tables created by it
+	prepare := dbh.Db.PrepareContext
+	if dbh.tx != nil {
+		prepare = dbh.tx.PrepareContext
 	}
 
-	// prepare query
-	stmt, err := dbh.Db.Prepare(query)
+	stmt, err := prepare(dbh.context(), sql)
 	if err != nil {
 		return nil, wrapError(err)
 	}
 
 	pstmp := &Pstmt{
+		dbHelper:   dbh,
+		params:     params,
+		positional: paramStyle != NamedParams,
+		stmt:       stmt,
+		tx:         dbh.tx,
+		query:      query,
+		prepared:   sql,
+	}
+
+	return pstmp, nil
+}
+
+// Exec prepares query, executes it with parameter values as Pstmt.Exec does
+// and closes it. Used for queries which are executed rarely, other queries
+// should be prepared once.
+func (dbh *DbHelper) Exec(query string, params interface{}) (int64, error) {
+	q, err := dbh.Prepare(query)
+	if err != nil {
+		return 0, err
+	}
+
+	defer q.Close()
+
+	return q.Exec(params)
+}
+
+// Query prepares query, executes it mapping results to i as Pstmt.Query does
+// and closes it. Used for queries which are executed rarely, other queries
+// should be prepared once.
+func (dbh *DbHelper) Query(i interface{}, query string, params interface{}) (int64, error) {
+	q, err := dbh.Prepare(query)
+	if err != nil {
+		return 0, err
+	}
+
+	defer q.Close()
+
+	return q.Query(i, params)
+}
+
+// ExecDirect is like Exec, but query is not prepared: query text with
+// placeholders and parameter values are passed to driver, which binds them
+// on the client or in one round trip, e.g. using simple protocol of pgx.
+// Used with drivers and connection poolers for which preparation has no
+// benefit.
+func (dbh *DbHelper) ExecDirect(query string, params interface{}) (int64, error) {
+	q, err := dbh.direct(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.Exec(params)
+}
+
+// QueryDirect is like Query, but query is not prepared, see ExecDirect.
+func (dbh *DbHelper) QueryDirect(i interface{}, query string, params interface{}) (int64, error) {
+	q, err := dbh.direct(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.Query(i, params)
+}
+
+// Returns statement which is executed as text instead of being prepared.
+func (dbh *DbHelper) direct(query string) (*Pstmt, error) {
+	// replace parameters with placeholders
+	sql, params, err := dbh.parseParams(query, NamedParams)
+	if err != nil {
+		return nil, err
+	}
+
+	err = dbh.checkParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Pstmt{
 		dbHelper: dbh,
 		params:   params,
-		stmt:     stmt,
+		query:    query,
+		prepared: sql,
+	}, nil
+}
+
+// Mappings of structures that are not registered as tables.
+type mappings struct {
+	types map[reflect.Type]*dbTable
+	mutex sync.Mutex
+}
+
+// Returns ErrNotFound if no rows were mapped to i, unless i is a pointer to slice.
+func checkFound(i interface{}, num int64) error {
+	if num > 0 {
+		return nil
 	}
 
-	return pstmp, nil
+	t := reflect.TypeOf(i)
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Slice {
+		return nil
+	}
+
+	return ErrNotFound
+}
+
+// Returns slice of pointers to structures of the table pointed by i.
+func listValue(tbl *dbTable, i interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice || v.Elem().Type().Elem().Kind() != reflect.Ptr {
+		return reflect.Value{}, errors.New(fmt.Sprintf("dbhelper: pointer to slice of pointers to structure type '%v' expected",
+			tbl.structType))
+	}
+
+	return v.Elem(), nil
 }
 
 // Performs a select by id query.
+// If i is a pointer to structure and record was not found, ErrNotFound is returned.
 func (dbh *DbHelper) SelectById(i interface{}, id int64) (int64, error) {
 	// get type
 	t, err := typeOf(i)
@@ -198,16 +524,188 @@ func (dbh *DbHelper) SelectById(i interface{}, id int64) (int64, error) {
 	}
 
 	// get table
-	tbl, err := dbh.getTable(t)
+	tbl, err := dbh.getPreparedTable(t)
 	if err != nil {
 		return 0, err
 	}
 
+	if tbl.idField == nil {
+		return 0, errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field with option 'id'", t))
+	}
+
+	// parameters of query limited to tenant
+	var params interface{} = id
+	if tbl.tenantField != nil {
+		scoped := map[string]interface{}{tbl.idField.column: id}
+		err = dbh.scopeParams(tbl, scoped)
+		if err != nil {
+			return 0, err
+		}
+
+		params = scoped
+	}
+
+	// get cached result
+	key := dbh.scopeKey(tbl, fmt.Sprintf("id:%d", id))
+	if num, ok := dbh.getCached(tbl, key, i); ok {
+		return num, nil
+	}
+
+	gen := dbh.cacheGeneration(tbl)
+
 	// perform query
-	return tbl.selectByIdQuery.Query(i, id)
+	num, err := dbh.selectShared(tbl, key, tbl.selectByIdQuery, i, params)
+	if err != nil {
+		return 0, err
+	}
+
+	dbh.setCached(tbl, key, i, num, gen)
+	return num, checkFound(i, num)
+}
+
+// SelectByIds selects records with ids, which are passed in IN clause split
+// into several queries if number of ids exceeds maximum number of parameters
+// of the database. I must be a pointer to slice of pointers. Records are not
+// sorted; use SelectByIdsOrdered to get them in order of ids.
+func (dbh *DbHelper) SelectByIds(i interface{}, ids []int64) (int64, error) {
+	_, list, err := dbh.selectByIds(i, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(list.Len()), nil
+}
+
+// SelectByIdsOrdered selects records with ids like SelectByIds. Resulting
+// slice has the same length as ids and contains record with n-th id at
+// index n, or nil if record was not found. Returns number of found records.
+func (dbh *DbHelper) SelectByIdsOrdered(i interface{}, ids []int64) (int64, error) {
+	tbl, list, err := dbh.selectByIds(i, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	// records by ids
+	records := make(map[string]reflect.Value, list.Len())
+	for n := 0; n < list.Len(); n++ {
+		ptr := list.Index(n)
+		records[relationKey(tbl.idField.value(ptr.Elem()).Interface())] = ptr
+	}
+
+	// arrange records in order of ids
+	ordered := reflect.MakeSlice(list.Type(), len(ids), len(ids))
+	for n, id := range ids {
+		if ptr, ok := records[relationKey(id)]; ok {
+			ordered.Index(n).Set(ptr)
+		}
+	}
+
+	list.Set(ordered)
+	return int64(len(records)), nil
+}
+
+// Selects records with ids to slice pointed by i and returns the slice.
+func (dbh *DbHelper) selectByIds(i interface{}, ids []int64) (*dbTable, reflect.Value, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return nil, reflect.Value{}, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return nil, reflect.Value{}, err
+	}
+
+	if tbl.idField == nil {
+		return nil, reflect.Value{}, errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field with option 'id'", t))
+	}
+
+	list, err := listValue(tbl, i)
+	if err != nil {
+		return nil, reflect.Value{}, err
+	}
+
+	// distinct ids
+	values := make([]interface{}, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			values = append(values, id)
+		}
+	}
+
+	result := reflect.MakeSlice(list.Type(), 0, len(values))
+	err = dbh.inChunks(tbl, values, func(chunk []interface{}) error {
+		rows := reflect.New(list.Type())
+		_, err := dbh.Select(i).Where(In(tbl.idField.column, chunk...)).Query(rows.Interface())
+		if err != nil {
+			return err
+		}
+
+		result = reflect.AppendSlice(result, rows.Elem())
+		return nil
+	})
+	if err != nil {
+		return nil, reflect.Value{}, err
+	}
+
+	list.Set(result)
+	return tbl, list, nil
+}
+
+// Returns error if query has more parameters than SQL dialect allows, which
+// is clearer than error of driver.
+func (dbh *DbHelper) checkParams(params []string) error {
+	if max := dbh.Capabilities().MaxParams; max > 0 && len(params) > max {
+		return errors.New(fmt.Sprintf("dbhelper: query has %d parameters, SQL dialect allows at most %d", len(params), max))
+	}
+
+	return nil
+}
+
+// Calls fn for chunks of values, which are passed in IN clause of queries
+// of table tbl, so that number of parameters of each query including
+// parameters of scope does not exceed MaxParams of SQL dialect.
+func (dbh *DbHelper) inChunks(tbl *dbTable, values []interface{}, fn func(chunk []interface{}) error) error {
+	size := len(values)
+	if max := dbh.Capabilities().MaxParams; max > 0 {
+		// parameters of scope are added to every query
+		reserved := 0
+		if tbl != nil {
+			scope, err := dbh.scopeCond(tbl)
+			if err != nil {
+				return err
+			}
+
+			reserved = len(scope)
+		}
+
+		if size > max-reserved {
+			size = max - reserved
+		}
+	}
+
+	for len(values) > 0 {
+		if size > len(values) {
+			size = len(values)
+		}
+
+		err := fn(values[:size])
+		if err != nil {
+			return err
+		}
+
+		values = values[size:]
+	}
+
+	return nil
 }
 
 // Performs a select by column query.
+// If i is a pointer to structure and record was not found, ErrNotFound is returned.
 func (dbh *DbHelper) SelectBy(i interface{}, column string, value interface{}) (int64, error) {
 	// get type
 	t, err := typeOf(i)
@@ -221,6 +719,26 @@ func (dbh *DbHelper) SelectBy(i interface{}, column string, value interface{}) (
 		return 0, err
 	}
 
+	// parameters of query limited to tenant
+	params := value
+	if tbl.tenantField != nil {
+		scoped := map[string]interface{}{column: value}
+		err = dbh.scopeParams(tbl, scoped)
+		if err != nil {
+			return 0, err
+		}
+
+		params = scoped
+	}
+
+	// get cached result
+	key := dbh.scopeKey(tbl, fmt.Sprintf("%s:%v", column, value))
+	if num, ok := dbh.getCached(tbl, key, i); ok {
+		return num, nil
+	}
+
+	gen := dbh.cacheGeneration(tbl)
+
 	// check if query was already prepared
 	q, ok := tbl.selectQueries[column]
 	if !ok {
@@ -233,10 +751,11 @@ func (dbh *DbHelper) SelectBy(i interface{}, column string, value interface{}) (
 		}
 
 		// select query
-		query := fmt.Sprintf("SELECT * FROM %s WHERE %s = :%s", tbl.name, column, column)
+		query := fmt.Sprintf("SELECT * FROM %s WHERE %s = :%s%s", dbh.quote(tbl.name), dbh.quote(column), column,
+			tbl.scopeSQL(tenantParam))
 
-		// prepare query
-		q, err = dbh.Prepare(query)
+		// prepare query, it is cached for all transactions
+		q, err = tbl.dbHelper.Prepare(query)
 		if err != nil {
 			return 0, err
 		}
@@ -246,7 +765,13 @@ func (dbh *DbHelper) SelectBy(i interface{}, column string, value interface{}) (
 	}
 
 	// perform query
-	return q.Query(i, value)
+	num, err := dbh.selectShared(tbl, key, q, i, params)
+	if err != nil {
+		return 0, err
+	}
+
+	dbh.setCached(tbl, key, i, num, gen)
+	return num, checkFound(i, num)
 }
 
 // Performs a select all query.
@@ -258,17 +783,29 @@ func (dbh *DbHelper) SelectAll(i interface{}) (int64, error) {
 	}
 
 	// get table
-	tbl, err := dbh.getTable(t)
+	tbl, err := dbh.getPreparedTable(t)
 	if err != nil {
 		return 0, err
 	}
 
+	// parameters of query limited to tenant
+	var params interface{}
+	if tbl.tenantField != nil {
+		scoped := make(map[string]interface{}, 1)
+		err = dbh.scopeParams(tbl, scoped)
+		if err != nil {
+			return 0, err
+		}
+
+		params = scoped
+	}
+
 	// perform query
-	return tbl.selectAllQuery.Query(i, nil)
+	return tbl.selectAllQuery.in(dbh).Query(i, params)
 }
 
-// Prepares parameters for standard query.
-func (dbh *DbHelper) prepareParams(i interface{}) (tbl *dbTable, params map[string]interface{}, v reflect.Value, err error) {
+// Returns table and value of structure i for standard query.
+func (dbh *DbHelper) prepareParams(i interface{}) (tbl *dbTable, v reflect.Value, err error) {
 	// get structure type
 	t, err := typeOf(i)
 	if err != nil {
@@ -276,58 +813,170 @@ func (dbh *DbHelper) prepareParams(i interface{}) (tbl *dbTable, params map[stri
 	}
 
 	// get table
-	tbl, err = dbh.getTable(t)
+	tbl, err = dbh.getPreparedTable(t)
 	if err != nil {
 		return
 	}
 
+	if tbl.view {
+		err = errors.New(fmt.Sprintf("dbhelper: cannot modify read-only view '%s'", tbl.name))
+		return
+	}
+
 	// get value of structure to insert
-	v = reflect.ValueOf(i)
-	if v.Type().Kind() == reflect.Ptr {
+	v, err = tbl.structValue(i)
+	if err != nil {
+		return
+	}
+
+	// records belong to tenant
+	err = dbh.setTenant(tbl, v)
+	return
+}
+
+// Returns value of structure i of the table. I must be a structure or a
+// pointer to structure.
+func (tbl *dbTable) structValue(i interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(i)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}, errors.New(fmt.Sprintf("dbhelper: cannot use nil pointer to structure type '%v'",
+				tbl.structType))
+		}
+
 		v = v.Elem()
 	}
 
-	// get parameter values
-	l := len(tbl.insertQuery.params)
-	params = make(map[string]interface{}, l)
-	for _, f := range tbl.fields {
-		params[f.column] = v.FieldByIndex(f.index).Interface()
+	if v.Type() != tbl.structType {
+		return reflect.Value{}, errors.New(fmt.Sprintf("dbhelper: structure or pointer to structure type '%v' expected, got '%T'",
+			tbl.structType, i))
 	}
 
-	return
+	return v, nil
+}
+
+// Returns error if any of fields will be set, but structure v was not
+// passed by pointer.
+func (tbl *dbTable) checkSettable(v reflect.Value, fields ...*dbField) error {
+	if v.CanSet() {
+		return nil
+	}
+
+	for _, f := range fields {
+		if f != nil {
+			return errors.New(fmt.Sprintf("dbhelper: pointer to structure type '%v' is required to set field '%s'",
+				tbl.structType, f.name))
+		}
+	}
+
+	return nil
 }
 
 // Inserts new record to databse. Field with option 'id' is automatically updated.
+// String field with options 'id' and 'auto' is omitted and receives the id
+// generated by database, if SQL dialect can return it (Postgresql).
 func (dbh *DbHelper) Insert(i interface{}) error {
+	return dbh.InsertWith(i, InsertOptions{})
+}
+
+// InsertOptions changes behaviour of InsertWith.
+type InsertOptions struct {
+	// Insert value of the field with option 'id' even if it has option 'auto'.
+	ForceId bool
+
+	// After insertion with ForceId, set sequence generating ids of
+	// auto-incremented column to the maximum id of the table (Postgresql).
+	ResetSequence bool
+}
+
+// InsertWith inserts new record to database like Insert, using options opts.
+func (dbh *DbHelper) InsertWith(i interface{}, opts InsertOptions) error {
 	// get current timestamp
 	time := time.Now().UTC().Unix()
 
 	// prepare parameters
-	tbl, params, v, err := dbh.prepareParams(i)
+	tbl, v, err := dbh.prepareParams(i)
 	if err != nil {
 		return err
 	}
 
-	// set created time
-	if tbl.createdField != nil {
-		params[tbl.createdField.column] = time
+	// id is set by application or generator
+	idSet := tbl.idGenerator != nil || opts.ForceId || !tbl.idField.auto
+
+	// fields set by insertion
+	setFields := append([]*dbField{tbl.createdField, tbl.modifiedField}, tbl.defaultFields...)
+	setFields = append(setFields, tbl.secretFields...)
+	if !idSet || (tbl.idGenerator != nil && !opts.ForceId) {
+		setFields = append(setFields, tbl.idField)
 	}
 
-	// set modified time
-	if tbl.modifiedField != nil {
-		params[tbl.modifiedField.column] = time
+	err = tbl.checkSettable(v, setFields...)
+	if err != nil {
+		return err
 	}
 
-	var id int64
-	if sqld, ok := dbh.sqlDialect.(hasCustomInsert); ok {
+	// assign default values to fields with zero values
+	err = tbl.applyDefaults(v, time)
+	if err != nil {
+		return err
+	}
+
+	// check values of enum fields
+	err = tbl.checkEnums(v)
+	if err != nil {
+		return err
+	}
+
+	// store hashes of secrets
+	err = dbh.hashSecrets(tbl, v)
+	if err != nil {
+		return err
+	}
+
+	// generate id
+	if tbl.idGenerator != nil && !opts.ForceId {
+		id, err := tbl.idGenerator.NextId(dbh)
+		if err != nil {
+			return err
+		}
+
+		err = setFieldValue(tbl.idField.value(v), id)
+		if err != nil {
+			return err
+		}
+	}
+
+	// get insert query
+	insertQuery, err := tbl.getInsertQuery(v, opts.ForceId)
+	if err != nil {
+		return err
+	}
+
+	// get parameter values, created and modified time is set
+	params := tbl.structValues(insertQuery, v, time, true)
+	defer putValueBuffer(params)
+
+	var id interface{}
+	if idSet {
+		_, err = insertQuery.in(dbh).exec(dbh.context(), orderedParams(*params))
+		if err != nil {
+			return err
+		}
+	} else if sqld, ok := dbh.sqlDialect.(HasCustomInsert); ok {
 		// custom insert
-		id, err = sqld.insert(tbl, params)
+		id, err = sqld.Insert(tbl.info(), insertQuery.in(dbh), orderedParams(*params))
 		if err != nil {
 			return err
 		}
 	} else {
+		// only integer ids generated by database can be obtained
+		if tbl.idField.auto && tbl.idField.typ.Kind() == reflect.String {
+			return errors.New(fmt.Sprintf("dbhelper: SQL dialect cannot return string id of structure type '%v'",
+				tbl.structType))
+		}
+
 		// standart insert
-		res, err := tbl.insertQuery.exec(params)
+		res, err := insertQuery.in(dbh).exec(dbh.context(), orderedParams(*params))
 		if err != nil {
 			return err
 		}
@@ -339,19 +988,38 @@ func (dbh *DbHelper) Insert(i interface{}) error {
 		}
 	}
 
+	// invalidate cached results
+	dbh.invalidate(tbl)
+
 	// udpate id field in structure
-	v.FieldByIndex(tbl.idField.index).SetInt(id)
+	if !idSet {
+		err = setFieldValue(tbl.idField.value(v), id)
+		if err != nil {
+			return err
+		}
+	}
+
+	// ids inserted explicitly are not known to sequence
+	if opts.ForceId && opts.ResetSequence && tbl.idField.auto {
+		if sqld, ok := dbh.sqlDialect.(HasSequences); ok {
+			_, err = dbh.execRaw(sqld.ResetSequence(tbl.name, tbl.idField.column))
+			if err != nil {
+				return err
+			}
+		}
+	}
 
 	// update created field in structure
 	if tbl.createdField != nil {
-		v.FieldByIndex(tbl.createdField.index).SetInt(time)
+		tbl.createdField.value(v).SetInt(time)
 	}
 
 	// update modified field in structure
 	if tbl.modifiedField != nil {
-		v.FieldByIndex(tbl.modifiedField.index).SetInt(time)
+		tbl.modifiedField.value(v).SetInt(time)
 	}
 
+	dbh.changed(tbl, v, OpInsert)
 	return nil
 }
 
@@ -363,25 +1031,56 @@ func (dbh *DbHelper) Update(i interface{}) (int64, error) {
 	time := time.Now().UTC().Unix()
 
 	// prepare parameters
-	tbl, params, v, err := dbh.prepareParams(i)
+	tbl, v, err := dbh.prepareParams(i)
 	if err != nil {
 		return 0, err
 	}
 
-	// set modified time
-	if tbl.modifiedField != nil {
-		params[tbl.modifiedField.column] = time
+	err = tbl.checkSettable(v, append([]*dbField{tbl.modifiedField, tbl.versionField}, tbl.secretFields...)...)
+	if err != nil {
+		return 0, err
+	}
+
+	// check values of enum fields
+	err = tbl.checkEnums(v)
+	if err != nil {
+		return 0, err
 	}
 
+	// store hashes of secrets
+	err = dbh.hashSecrets(tbl, v)
+	if err != nil {
+		return 0, err
+	}
+
+	// get parameter values, modified time is set
+	params := tbl.structValues(tbl.updateQuery, v, time, false)
+	defer putValueBuffer(params)
+
 	// standart update
-	num, err := tbl.updateQuery.Exec(params)
+	num, err := dbh.withHistory(tbl, v, time, func(dbh *DbHelper) (int64, error) {
+		return tbl.updateQuery.in(dbh).Exec(orderedParams(*params))
+	})
 	if err != nil {
 		return 0, err
 	}
 
+	// invalidate cached results
+	dbh.invalidate(tbl)
+
 	// update modified field in structure
 	if tbl.modifiedField != nil {
-		v.FieldByIndex(tbl.modifiedField.index).SetInt(time)
+		tbl.modifiedField.value(v).SetInt(time)
+	}
+
+	// record has new version
+	if tbl.versionField != nil && num > 0 {
+		f := tbl.versionField.value(v)
+		f.SetInt(f.Int() + 1)
+	}
+
+	if num > 0 {
+		dbh.changed(tbl, v, OpUpdate)
 	}
 
 	return num, nil
@@ -390,17 +1089,106 @@ func (dbh *DbHelper) Update(i interface{}) (int64, error) {
 // Deletes record(s) in database and returns number of affected rows.
 // Field with option 'id' is used to define the record in database.
 func (dbh *DbHelper) Delete(i interface{}) (int64, error) {
+	// get current timestamp
+	time := time.Now().UTC().Unix()
+
 	// prepare parameters
-	tbl, params, _, err := dbh.prepareParams(i)
+	tbl, v, err := dbh.prepareParams(i)
 	if err != nil {
 		return 0, err
 	}
 
-	// standart update
-	num, err := tbl.deleteQuery.Exec(params)
+	err = tbl.checkSettable(v, tbl.softDeleteField)
+	if err != nil {
+		return 0, err
+	}
+
+	// deletion time is passed in structure
+	if tbl.softDeleteField != nil {
+		tbl.softDeleteField.value(v).SetInt(time)
+	}
+
+	// get parameter values
+	params := tbl.structValues(tbl.deleteQuery, v, 0, false)
+	defer putValueBuffer(params)
+
+	// standart delete
+	num, err := dbh.withHistory(tbl, v, time, func(dbh *DbHelper) (int64, error) {
+		return tbl.deleteQuery.in(dbh).Exec(orderedParams(*params))
+	})
+
+	// record was not deleted
+	if tbl.softDeleteField != nil && (err != nil || num == 0) {
+		tbl.softDeleteField.value(v).SetInt(0)
+	}
+
 	if err != nil {
 		return 0, err
 	}
 
+	// invalidate cached results
+	dbh.invalidate(tbl)
+
+	if num > 0 {
+		dbh.changed(tbl, v, OpDelete)
+	}
+
 	return num, nil
 }
+
+// UpdateStrict is like Update, but returns ErrNoRowsAffected if record was
+// not found, e.g. because it was already deleted.
+func (dbh *DbHelper) UpdateStrict(i interface{}) error {
+	num, err := dbh.Update(i)
+	if err != nil {
+		return err
+	}
+
+	if num == 0 {
+		return ErrNoRowsAffected
+	}
+
+	return nil
+}
+
+// DeleteStrict is like Delete, but returns ErrNoRowsAffected if record was not found.
+func (dbh *DbHelper) DeleteStrict(i interface{}) error {
+	num, err := dbh.Delete(i)
+	if err != nil {
+		return err
+	}
+
+	if num == 0 {
+		return ErrNoRowsAffected
+	}
+
+	return nil
+}
+
+// GetId returns value of the field with option 'id' of structure i, which
+// has type of the field, e.g. int64 or string.
+func (dbh *DbHelper) GetId(i interface{}) (interface{}, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return nil, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if tbl.idField == nil {
+		return nil, errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field with option 'id'", t))
+	}
+
+	// get value of structure
+	v, err := tbl.structValue(i)
+	if err != nil {
+		return nil, err
+	}
+
+	return tbl.idField.value(v).Interface(), nil
+}
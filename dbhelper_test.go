@@ -14,6 +14,8 @@ package dbhelper
 import (
 	"database/sql"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
 	_ "github.com/lib/pq"
@@ -247,3 +249,88 @@ func TestQuery(t *testing.T) {
 		return
 	}
 }
+
+func TestSelectByIds(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// pointer to slice of pointers is required
+	_, err = dbh.SelectByIds(&testStruct{}, []int64{1})
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	// no queries are executed without ids
+	var list []*testStruct
+	num, err := dbh.SelectByIdsOrdered(&list, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if num != 0 || list == nil || len(list) != 0 {
+		t.Errorf("empty list expected: %d, %v", num, list)
+		return
+	}
+}
+
+func TestStructValidation(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// values that are not structures of the table
+	values := []interface{}{(*testStruct)(nil), []*testStruct{}, &[]testStruct{}}
+	for _, i := range values {
+		_, err = tbl.structValue(i)
+		if err == nil {
+			t.Errorf("error expected for %T", i)
+			return
+		}
+
+		_, err = dbh.GetId(i)
+		if err == nil {
+			t.Errorf("error expected for %T", i)
+			return
+		}
+	}
+
+	// fields set by dbhelper cannot be set in structure passed by value
+	v, err := tbl.structValue(testStruct{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = tbl.checkSettable(v, tbl.modifiedField)
+	if err == nil || !strings.Contains(err.Error(), "'Modified'") {
+		t.Errorf("error naming the field expected: %v", err)
+		return
+	}
+
+	v, err = tbl.structValue(&testStruct{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = tbl.checkSettable(v, tbl.modifiedField)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
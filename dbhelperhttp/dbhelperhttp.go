@@ -0,0 +1,450 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelperhttp provides HTTP handler exposing CRUD operations on tables
+// registered in dbhelper. Records are encoded to and decoded from JSON.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelperhttp
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bogomolovs/dbhelper"
+)
+
+// AuthFunc checks if request is allowed to perform operation on resource.
+// Operation is HTTP method of the request. If returned error is not nil,
+// request is rejected with status 403.
+type AuthFunc func(r *http.Request, resource string, method string) error
+
+// FilterFunc is applied to each record before it is encoded to response.
+// It can be used to hide fields or to return a different representation.
+type FilterFunc func(r *http.Request, i interface{}) interface{}
+
+// Default maximum size of request body.
+const defaultMaxBodySize = 1 << 20
+
+// Stores information about resource.
+type resource struct {
+	structType reflect.Type
+	filter     FilterFunc
+
+	// column and type of id, nil type if table has no id
+	idColumn string
+	idType   reflect.Type
+
+	// names of fields which can be set by request body, all fields if nil
+	input []string
+}
+
+// Handler serves CRUD requests for registered resources:
+//
+//	GET    /name      - all records, filtered according to query string
+//	GET    /name/{id} - record with id
+//	POST   /name      - insert record
+//	PUT    /name/{id} - update record with id
+//	DELETE /name/{id} - delete record with id
+//
+// See dbhelper.ParseFilter for the query string format.
+// Handler expects resource name to be the first element of the request path,
+// so it should be mounted using http.StripPrefix.
+type Handler struct {
+	// Optional authorization function.
+	Auth AuthFunc
+
+	// Maximum size of request body, 1 MB if zero.
+	MaxBodySize int64
+
+	// Optional function returning tenant of request. If set, operations
+	// are performed by DbHelper bound to the tenant using WithTenant, so
+	// records of other tenants are not accessible.
+	Tenant func(r *http.Request) interface{}
+
+	dbHelper  *dbhelper.DbHelper
+	resources map[string]*resource
+}
+
+// New returns new Handler.
+func New(dbh *dbhelper.DbHelper) *Handler {
+	return &Handler{
+		dbHelper:  dbh,
+		resources: make(map[string]*resource),
+	}
+}
+
+// Returns information about table assigned to type t.
+func (h *Handler) tableInfo(t reflect.Type) (*dbhelper.TableInfo, error) {
+	for _, info := range h.dbHelper.Tables() {
+		if info.Type == t {
+			return &info, nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("dbhelperhttp: type '%v' has no assigned table", t))
+}
+
+// Add exposes table assigned to type of i as resource with name.
+// Filter is optional and can be nil. Returns error if no table is assigned
+// to type of i. Request bodies can set all fields, unless they are limited
+// by AllowInput.
+func (h *Handler) Add(name string, i interface{}, filter FilterFunc) error {
+	t := reflect.TypeOf(i)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// check that table is registered
+	info, err := h.tableInfo(t)
+	if err != nil {
+		return err
+	}
+
+	h.resources[name] = &resource{
+		structType: t,
+		filter:     filter,
+		idColumn:   info.Id.Name,
+		idType:     info.Id.Type,
+	}
+
+	return nil
+}
+
+// AllowInput limits fields of resource with name, which can be set by
+// bodies of POST and PUT requests, to fields assigned to columns. Values
+// of other fields in request bodies are ignored, so clients cannot change
+// e.g. owner or role of a record.
+func (h *Handler) AllowInput(name string, columns ...string) error {
+	res, ok := h.resources[name]
+	if !ok {
+		return errors.New(fmt.Sprintf("dbhelperhttp: resource '%s' is not added", name))
+	}
+
+	info, err := h.tableInfo(res.structType)
+	if err != nil {
+		return err
+	}
+
+	input := make([]string, 0, len(columns))
+	for _, column := range columns {
+		field := ""
+		for _, c := range info.Columns {
+			if c.Name == column {
+				field = c.Field
+				break
+			}
+		}
+
+		if field == "" {
+			return errors.New(fmt.Sprintf("dbhelperhttp: table '%s' has no column '%s'", info.Name, column))
+		}
+
+		input = append(input, field)
+	}
+
+	res.input = input
+	return nil
+}
+
+// Returns DbHelper performing operations of request.
+func (h *Handler) db(r *http.Request) *dbhelper.DbHelper {
+	if h.Tenant == nil {
+		return h.dbHelper
+	}
+
+	return h.dbHelper.WithTenant(h.Tenant(r))
+}
+
+// Writes error caused by request, its message is returned to client.
+func writeError(w http.ResponseWriter, err error, status int) {
+	http.Error(w, err.Error(), status)
+}
+
+// Writes error of database. Its message may contain queries and values, so
+// client receives a generic message.
+func writeServerError(w http.ResponseWriter) {
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, r *http.Request, res *resource, v reflect.Value, status int) {
+	var out interface{}
+	if v.Kind() == reflect.Slice {
+		// filter all records
+		records := make([]interface{}, v.Len())
+		for n := range records {
+			records[n] = h.filter(r, res, v.Index(n).Interface())
+		}
+
+		out = records
+	} else {
+		out = h.filter(r, res, v.Interface())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(out)
+}
+
+func (h *Handler) filter(r *http.Request, res *resource, i interface{}) interface{} {
+	if res.filter == nil {
+		return i
+	}
+
+	return res.filter(r, i)
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// parse path
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(path) > 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	res, ok := h.resources[path[0]]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	// check authorization
+	if h.Auth != nil {
+		err := h.Auth(r, path[0], r.Method)
+		if err != nil {
+			writeError(w, err, http.StatusForbidden)
+			return
+		}
+	}
+
+	if len(path) == 1 {
+		switch r.Method {
+		case "GET":
+			h.selectAll(w, r, res)
+		case "POST":
+			h.insert(w, r, res)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+
+		return
+	}
+
+	// parse id
+	if res.idType == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	id, err := parseId(path[1], res.idType)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		h.selectById(w, r, res, id)
+	case "PUT":
+		h.update(w, r, res, id)
+	case "DELETE":
+		h.delete(w, r, res, id)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) selectAll(w http.ResponseWriter, r *http.Request, res *resource) {
+	records := reflect.New(reflect.SliceOf(reflect.PtrTo(res.structType)))
+
+	// wrong filters are errors of request
+	dbh := h.db(r)
+	filter, err := dbh.ParseFilter(records.Interface(), r.URL.Query())
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	_, err = dbh.SelectWithFilter(records.Interface(), filter)
+	if err != nil {
+		writeServerError(w)
+		return
+	}
+
+	h.writeJSON(w, r, res, records.Elem(), http.StatusOK)
+}
+
+// Selects record by id. Writes response and returns nil if record was not found.
+func (h *Handler) load(w http.ResponseWriter, r *http.Request, res *resource, id interface{}) interface{} {
+	record := reflect.New(res.structType).Interface()
+	_, err := h.db(r).SelectBy(record, res.idColumn, id)
+	if errors.Is(err, dbhelper.ErrNotFound) {
+		http.NotFound(w, r)
+		return nil
+	}
+
+	if err != nil {
+		writeServerError(w)
+		return nil
+	}
+
+	return record
+}
+
+// Decodes JSON request body to record, changing only fields allowed by
+// AllowInput. Writes response and returns false if body is invalid.
+func (h *Handler) decode(w http.ResponseWriter, r *http.Request, res *resource, record interface{}) bool {
+	limit := h.MaxBodySize
+	if limit <= 0 {
+		limit = defaultMaxBodySize
+	}
+
+	body := http.MaxBytesReader(w, r.Body, limit)
+	v := reflect.ValueOf(record).Elem()
+
+	// body is decoded to a new structure containing only allowed fields
+	in := v
+	if res.input != nil {
+		in = reflect.New(res.structType).Elem()
+		for _, field := range res.input {
+			in.FieldByName(field).Set(v.FieldByName(field))
+		}
+	}
+
+	err := json.NewDecoder(body).Decode(in.Addr().Interface())
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeError(w, err, http.StatusRequestEntityTooLarge)
+		} else {
+			writeError(w, err, http.StatusBadRequest)
+		}
+
+		return false
+	}
+
+	for _, field := range res.input {
+		v.FieldByName(field).Set(in.FieldByName(field))
+	}
+
+	return true
+}
+
+func (h *Handler) selectById(w http.ResponseWriter, r *http.Request, res *resource, id interface{}) {
+	record := h.load(w, r, res, id)
+	if record == nil {
+		return
+	}
+
+	h.writeJSON(w, r, res, reflect.ValueOf(record), http.StatusOK)
+}
+
+func (h *Handler) insert(w http.ResponseWriter, r *http.Request, res *resource) {
+	record := reflect.New(res.structType).Interface()
+	if !h.decode(w, r, res, record) {
+		return
+	}
+
+	err := h.db(r).Insert(record)
+	if err != nil {
+		writeServerError(w)
+		return
+	}
+
+	h.writeJSON(w, r, res, reflect.ValueOf(record), http.StatusCreated)
+}
+
+func (h *Handler) update(w http.ResponseWriter, r *http.Request, res *resource, id interface{}) {
+	record := h.load(w, r, res, id)
+	if record == nil {
+		return
+	}
+
+	// decode new values over the stored record
+	if !h.decode(w, r, res, record) {
+		return
+	}
+
+	// id cannot be changed
+	newId, err := h.dbHelper.GetId(record)
+	if err != nil {
+		writeServerError(w)
+		return
+	}
+
+	if !reflect.DeepEqual(newId, id) {
+		http.Error(w, "id cannot be changed", http.StatusBadRequest)
+		return
+	}
+
+	_, err = h.db(r).Update(record)
+	if err != nil {
+		writeServerError(w)
+		return
+	}
+
+	h.writeJSON(w, r, res, reflect.ValueOf(record), http.StatusOK)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, res *resource, id interface{}) {
+	record := h.load(w, r, res, id)
+	if record == nil {
+		return
+	}
+
+	_, err := h.db(r).Delete(record)
+	if err != nil {
+		writeServerError(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Converts id from request path to type t of id field. Types implementing
+// encoding.TextUnmarshaler, e.g. UUIDs, are parsed by UnmarshalText.
+func parseId(s string, t reflect.Type) (interface{}, error) {
+	ptr := reflect.New(t)
+	if u, ok := ptr.Interface().(encoding.TextUnmarshaler); ok {
+		err := u.UnmarshalText([]byte(s))
+		if err != nil {
+			return nil, err
+		}
+
+		return ptr.Elem().Interface(), nil
+	}
+
+	v := ptr.Elem()
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return nil, err
+		}
+
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return nil, err
+		}
+
+		v.SetUint(n)
+	default:
+		return nil, errors.New(fmt.Sprintf("dbhelperhttp: unsupported id type '%v'", t))
+	}
+
+	return v.Interface(), nil
+}
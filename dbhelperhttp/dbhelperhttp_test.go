@@ -0,0 +1,358 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbhelperhttp
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bogomolovs/dbhelper"
+)
+
+// Driver returning predefined results of queries. Other queries fail with
+// error containing query text.
+type testDriver struct{}
+
+type testResult struct {
+	columns []string
+	rows    [][]driver.Value
+
+	// Optional function returning rows of query executed with args.
+	queryRows func(args []driver.Value) [][]driver.Value
+}
+
+var testResults = map[string]*testResult{
+	"SELECT * FROM items": {
+		columns: []string{"id", "name"},
+		rows:    [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}},
+	},
+	"SELECT * FROM items WHERE id = $1": {
+		columns: []string{"id", "name"},
+		rows:    [][]driver.Value{{int64(1), "a"}},
+	},
+
+	"SELECT * FROM codes WHERE code = $1": {
+		columns: []string{"code", "name", "role"},
+		rows:    [][]driver.Value{{"a-1", "a", "user"}},
+	},
+
+	// deleted notes and notes of other tenants are skipped
+	"SELECT * FROM notes WHERE deleted = 0 AND tenant = $1": {
+		columns: []string{"id", "deleted", "tenant"},
+		queryRows: func(args []driver.Value) [][]driver.Value {
+			var rows [][]driver.Value
+			for _, row := range [][]driver.Value{
+				{int64(1), int64(0), "acme"},
+				{int64(2), int64(123), "acme"},
+				{int64(3), int64(0), "other"},
+			} {
+				if row[1] == int64(0) && row[2] == args[0] {
+					rows = append(rows, row)
+				}
+			}
+
+			return rows
+		},
+	},
+}
+
+func init() {
+	sql.Register("dbhelperhttp_test", testDriver{})
+}
+
+func (d testDriver) Open(name string) (driver.Conn, error) {
+	return testConn{}, nil
+}
+
+type testConn struct{}
+
+func (c testConn) Prepare(query string) (driver.Stmt, error) {
+	return testStmt{query}, nil
+}
+
+func (c testConn) Close() error {
+	return nil
+}
+
+func (c testConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type testStmt struct {
+	query string
+}
+
+func (s testStmt) Close() error {
+	return nil
+}
+
+func (s testStmt) NumInput() int {
+	return -1
+}
+
+func (s testStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (s testStmt) Query(args []driver.Value) (driver.Rows, error) {
+	result, ok := testResults[s.query]
+	if !ok {
+		return nil, errors.New("syntax error in query: " + s.query)
+	}
+
+	if result.queryRows != nil {
+		return &testRows{result: &testResult{columns: result.columns, rows: result.queryRows(args)}}, nil
+	}
+
+	return &testRows{result: result}, nil
+}
+
+type testRows struct {
+	result *testResult
+	n      int
+}
+
+func (r *testRows) Columns() []string {
+	return r.result.columns
+}
+
+func (r *testRows) Close() error {
+	return nil
+}
+
+func (r *testRows) Next(dest []driver.Value) error {
+	if r.n >= len(r.result.rows) {
+		return io.EOF
+	}
+
+	copy(dest, r.result.rows[r.n])
+	r.n++
+	return nil
+}
+
+type item struct {
+	Id   int64  `db:"id" dbopt:"id"`
+	Name string `db:"name"`
+}
+
+type code struct {
+	Code string `db:"code" dbopt:"id"`
+	Name string `db:"name"`
+	Role string `db:"role"`
+}
+
+type note struct {
+	Id      int64  `db:"id" dbopt:"id"`
+	Deleted int64  `db:"deleted"`
+	Tenant  string `db:"tenant"`
+}
+
+type broken struct {
+	Id int64 `db:"id" dbopt:"id"`
+}
+
+func newTestHandler(t *testing.T) *Handler {
+	db, _ := sql.Open("dbhelperhttp_test", "")
+	dbh := dbhelper.New(db, dbhelper.Postgresql{})
+
+	err := dbh.AddTable(item{}, "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dbh.AddTable(broken{}, "broken")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := New(dbh)
+	err = h.Add("items", item{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = h.Add("broken", &broken{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+// Performs request and returns recorded response.
+func serve(h *Handler, method string, path string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestAdd(t *testing.T) {
+	h := newTestHandler(t)
+
+	// type without table
+	err := h.Add("other", struct{ Id int64 }{}, nil)
+	if err == nil {
+		t.Error("error expected for unregistered type")
+		return
+	}
+}
+
+func TestSelect(t *testing.T) {
+	h := newTestHandler(t)
+
+	w := serve(h, "GET", "/items", "")
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `[{"Id":1,"Name":"a"},{"Id":2,"Name":"b"}]` {
+		t.Errorf("unexpected response: %d %s", w.Code, w.Body)
+		return
+	}
+
+	w = serve(h, "GET", "/items/1", "")
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"Id":1,"Name":"a"}` {
+		t.Errorf("unexpected response: %d %s", w.Code, w.Body)
+		return
+	}
+
+	w = serve(h, "GET", "/unknown", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("404 expected: %d", w.Code)
+		return
+	}
+}
+
+func TestBadFilter(t *testing.T) {
+	h := newTestHandler(t)
+
+	w := serve(h, "GET", "/items?unknown=1", "")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("400 expected: %d %s", w.Code, w.Body)
+		return
+	}
+}
+
+func TestServerError(t *testing.T) {
+	h := newTestHandler(t)
+
+	// query is not returned to client
+	w := serve(h, "GET", "/broken", "")
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "SELECT") {
+		t.Errorf("generic 500 expected: %d %s", w.Code, w.Body)
+		return
+	}
+}
+
+func TestUpdateId(t *testing.T) {
+	h := newTestHandler(t)
+
+	w := serve(h, "PUT", "/items/1", `{"Id":2}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("400 expected: %d %s", w.Code, w.Body)
+		return
+	}
+
+	w = serve(h, "PUT", "/items/1", `{"Name":"c"}`)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"Id":1,"Name":"c"}` {
+		t.Errorf("unexpected response: %d %s", w.Code, w.Body)
+		return
+	}
+}
+
+func TestSelectTenant(t *testing.T) {
+	h := newTestHandler(t)
+	err := h.dbHelper.AddTableWith(note{}, dbhelper.TableOptions{Name: "notes", SoftDelete: "deleted", Tenant: "tenant"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = h.Add("notes", note{}, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// tenant is required
+	w := serve(h, "GET", "/notes", "")
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("500 expected: %d %s", w.Code, w.Body)
+		return
+	}
+
+	h.Tenant = func(r *http.Request) interface{} {
+		return r.Header.Get("X-Tenant")
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/notes", nil)
+	r.Header.Set("X-Tenant", "acme")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `[{"Id":1,"Deleted":0,"Tenant":"acme"}]` {
+		t.Errorf("unexpected response: %d %s", w.Code, w.Body)
+		return
+	}
+}
+
+func TestStringId(t *testing.T) {
+	h := newTestHandler(t)
+	err := h.dbHelper.AddTable(code{}, "codes")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = h.Add("codes", code{}, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	w := serve(h, "GET", "/codes/a-1", "")
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"Code":"a-1","Name":"a","Role":"user"}` {
+		t.Errorf("unexpected response: %d %s", w.Code, w.Body)
+		return
+	}
+
+	// only allowed fields are changed
+	err = h.AllowInput("codes", "name")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	w = serve(h, "PUT", "/codes/a-1", `{"Name":"b","Role":"admin"}`)
+	if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"Code":"a-1","Name":"b","Role":"user"}` {
+		t.Errorf("unexpected response: %d %s", w.Code, w.Body)
+		return
+	}
+
+	err = h.AllowInput("codes", "unknown")
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestBodySize(t *testing.T) {
+	h := newTestHandler(t)
+	h.MaxBodySize = 16
+
+	w := serve(h, "PUT", "/items/1", `{"Name":"`+strings.Repeat("a", 32)+`"}`)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("413 expected: %d %s", w.Code, w.Body)
+		return
+	}
+
+	// wrong id
+	w = serve(h, "GET", "/items/a", "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("404 expected: %d", w.Code)
+		return
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelperredis provides dbhelper.Cache storing query results in Redis.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelperredis
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Client is a subset of Redis commands used by Cache. It can be implemented
+// as a thin wrapper around any Redis client library.
+type Client interface {
+	// Get returns value of key or nil if key does not exist.
+	Get(key string) ([]byte, error)
+
+	// Set sets value of key, which expires after ttl. Zero ttl means that
+	// key does not expire.
+	Set(key string, value []byte, ttl time.Duration) error
+
+	// Expire sets time after which key expires.
+	Expire(key string, ttl time.Duration) error
+
+	// SAdd adds member to set stored at key.
+	SAdd(key string, member string) error
+
+	// SMembers returns all members of set stored at key.
+	SMembers(key string) ([]string, error)
+
+	// Del removes keys.
+	Del(keys ...string) error
+}
+
+// Cache implements dbhelper.Cache using Redis. Keys of cached values of each
+// table are stored in a set, which is used to invalidate them. Errors returned
+// by client are treated as cache misses. Errors of invalidation, which leave
+// stale values in cache, are passed to InvalidateError.
+type Cache struct {
+	// Time after which cached values expire. Zero means that values are
+	// stored until they are invalidated.
+	TTL time.Duration
+
+	// Function called when values of table cannot be invalidated. Errors
+	// are logged if it is nil.
+	InvalidateError func(table string, err error)
+
+	client Client
+	prefix string
+}
+
+// New returns new Cache. Prefix is prepended to all keys.
+func New(client Client, prefix string) *Cache {
+	return &Cache{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+// Returns Redis key of set containing keys of table values.
+func (c *Cache) tableKey(table string) string {
+	return fmt.Sprintf("%s%s", c.prefix, table)
+}
+
+// Returns Redis key of value.
+func (c *Cache) valueKey(table string, key string) string {
+	return fmt.Sprintf("%s%s:%s", c.prefix, table, key)
+}
+
+// Get implements dbhelper.Cache.
+func (c *Cache) Get(table string, key string) ([]byte, bool) {
+	value, err := c.client.Get(c.valueKey(table, key))
+	if err != nil || value == nil {
+		return nil, false
+	}
+
+	return value, true
+}
+
+// Set implements dbhelper.Cache.
+func (c *Cache) Set(table string, key string, value []byte) {
+	valueKey := c.valueKey(table, key)
+
+	tableKey := c.tableKey(table)
+	err := c.client.SAdd(tableKey, valueKey)
+	if err != nil {
+		return
+	}
+
+	// set of keys expires after the last value
+	if c.TTL > 0 {
+		err = c.client.Expire(tableKey, c.TTL)
+		if err != nil {
+			return
+		}
+	}
+
+	c.client.Set(valueKey, value, c.TTL)
+}
+
+// Invalidate implements dbhelper.Cache.
+func (c *Cache) Invalidate(table string) {
+	tableKey := c.tableKey(table)
+
+	keys, err := c.client.SMembers(tableKey)
+	if err != nil {
+		c.invalidateError(table, err)
+		return
+	}
+
+	err = c.client.Del(append(keys, tableKey)...)
+	if err != nil {
+		c.invalidateError(table, err)
+	}
+}
+
+// Reports error of invalidation of table.
+func (c *Cache) invalidateError(table string, err error) {
+	if c.InvalidateError != nil {
+		c.InvalidateError(table, err)
+		return
+	}
+
+	log.Printf("dbhelperredis: cannot invalidate cached values of table '%s': %v", table, err)
+}
@@ -0,0 +1,121 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbhelperredis
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// Client storing values in memory.
+type testClient struct {
+	values map[string][]byte
+	sets   map[string][]string
+	ttl    map[string]time.Duration
+	err    error
+}
+
+func newTestClient() *testClient {
+	return &testClient{
+		values: make(map[string][]byte),
+		sets:   make(map[string][]string),
+		ttl:    make(map[string]time.Duration),
+	}
+}
+
+func (c *testClient) Get(key string) ([]byte, error) {
+	return c.values[key], c.err
+}
+
+func (c *testClient) Set(key string, value []byte, ttl time.Duration) error {
+	c.values[key] = value
+	c.ttl[key] = ttl
+	return c.err
+}
+
+func (c *testClient) Expire(key string, ttl time.Duration) error {
+	c.ttl[key] = ttl
+	return c.err
+}
+
+func (c *testClient) SAdd(key string, member string) error {
+	c.sets[key] = append(c.sets[key], member)
+	return c.err
+}
+
+func (c *testClient) SMembers(key string) ([]string, error) {
+	return c.sets[key], c.err
+}
+
+func (c *testClient) Del(keys ...string) error {
+	for _, key := range keys {
+		delete(c.values, key)
+		delete(c.sets, key)
+	}
+
+	return c.err
+}
+
+func TestCache(t *testing.T) {
+	client := newTestClient()
+	c := New(client, "dbh:")
+	c.Set("test", "id:1", []byte("1"))
+	c.Set("other", "id:1", []byte("2"))
+
+	if v, ok := c.Get("test", "id:1"); !ok || string(v) != "1" {
+		t.Errorf("unexpected value: %s, %v", v, ok)
+		return
+	}
+
+	if client.ttl["dbh:test:id:1"] != 0 {
+		t.Error("value must not expire")
+		return
+	}
+
+	// invalidate table
+	c.Invalidate("test")
+
+	if _, ok := c.Get("test", "id:1"); ok {
+		t.Error("value was not invalidated")
+		return
+	}
+
+	if v, ok := c.Get("other", "id:1"); !ok || string(v) != "2" {
+		t.Error("value of another table was invalidated")
+		return
+	}
+
+	// client errors are cache misses
+	client.err = errors.New("connection refused")
+	if _, ok := c.Get("other", "id:1"); ok {
+		t.Error("cache miss expected")
+		return
+	}
+
+	// invalidation errors are reported
+	var reported string
+	c.InvalidateError = func(table string, err error) {
+		reported = table
+	}
+
+	c.Invalidate("other")
+	if reported != "other" {
+		t.Error("invalidation error was not reported")
+		return
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	client := newTestClient()
+	c := New(client, "dbh:")
+	c.TTL = time.Minute
+	c.Set("test", "id:1", []byte("1"))
+
+	if client.ttl["dbh:test:id:1"] != time.Minute || client.ttl["dbh:test"] != time.Minute {
+		t.Errorf("unexpected expiration: %v", client.ttl)
+		return
+	}
+}
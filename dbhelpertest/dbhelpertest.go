@@ -0,0 +1,89 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelpertest provides helpers for integration tests of code using
+// dbhelper. Each test is wrapped in a transaction which is rolled back when
+// the test finishes.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelpertest
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/bogomolovs/dbhelper"
+)
+
+// Returns database/sql driver name for SQL dialect.
+func driverName(dialect dbhelper.SqlDialect) string {
+	switch dialect.(type) {
+	case dbhelper.Postgresql:
+		return "postgres"
+	case dbhelper.MySql:
+		return "mysql"
+	case dbhelper.Sqlite:
+		return "sqlite3"
+	case dbhelper.ClickHouse:
+		return "clickhouse"
+	}
+
+	return ""
+}
+
+// New opens database using dsn and returns DbHelper bound to a transaction.
+// The transaction is rolled back and database is closed when the test finishes,
+// so changes made by the test are not persisted. Corresponding database driver
+// must be imported by the test.
+func New(t testing.TB, dsn string, dialect dbhelper.SqlDialect) *dbhelper.DbHelper {
+	t.Helper()
+
+	db, err := sql.Open(driverName(dialect), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dbh, err := dbhelper.New(db, dialect).Begin()
+	if err != nil {
+		db.Close()
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		err := dbh.Rollback()
+		if err != nil {
+			t.Error(err)
+		}
+
+		db.Close()
+	})
+
+	return dbh
+}
+
+// CreateTables creates tables for registered types of i.
+func CreateTables(t testing.TB, dbh *dbhelper.DbHelper, i ...interface{}) {
+	t.Helper()
+
+	for _, v := range i {
+		err := dbh.CreateTable(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// DropTables drops tables for registered types of i.
+func DropTables(t testing.TB, dbh *dbhelper.DbHelper, i ...interface{}) {
+	t.Helper()
+
+	for _, v := range i {
+		err := dbh.DropTable(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
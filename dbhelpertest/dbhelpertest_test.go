@@ -0,0 +1,172 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbhelpertest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bogomolovs/dbhelper"
+)
+
+// Driver of database where tables created in a transaction are visible only
+// in this transaction until it is committed, as in Postgresql.
+type testDriver struct{}
+
+var testDb = struct {
+	tables map[string]int
+	mutex  sync.Mutex
+}{
+	tables: make(map[string]int),
+}
+
+func init() {
+	sql.Register("sqlite3", testDriver{})
+}
+
+func (d testDriver) Open(name string) (driver.Conn, error) {
+	return &testConn{}, nil
+}
+
+type testConn struct {
+	// Tables created in transaction and numbers of their rows.
+	tables map[string]int
+}
+
+// Returns number of rows of table visible to connection.
+func (c *testConn) rows(table string) (int, bool) {
+	if num, ok := c.tables[table]; ok {
+		return num, true
+	}
+
+	testDb.mutex.Lock()
+	defer testDb.mutex.Unlock()
+
+	num, ok := testDb.tables[table]
+	return num, ok
+}
+
+func (c *testConn) Prepare(query string) (driver.Stmt, error) {
+	fields := strings.FieldsFunc(query, func(r rune) bool {
+		return r == ' ' || r == '('
+	})
+
+	if strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS ") {
+		return &testStmt{conn: c, table: fields[5]}, nil
+	}
+
+	// other queries use table following one of keywords
+	for i, field := range fields[:len(fields)-1] {
+		if field != "INTO" && field != "UPDATE" && field != "FROM" {
+			continue
+		}
+
+		table := fields[i+1]
+		if _, ok := c.rows(table); !ok {
+			return nil, errors.New("relation \"" + table + "\" does not exist")
+		}
+
+		return &testStmt{conn: c, table: table, insert: fields[0] == "INSERT"}, nil
+	}
+
+	return nil, errors.New("unknown query: " + query)
+}
+
+func (c *testConn) Close() error {
+	return nil
+}
+
+func (c *testConn) Begin() (driver.Tx, error) {
+	c.tables = make(map[string]int)
+	return c, nil
+}
+
+func (c *testConn) Commit() error {
+	testDb.mutex.Lock()
+	for table, num := range c.tables {
+		testDb.tables[table] = num
+	}
+
+	testDb.mutex.Unlock()
+
+	c.tables = nil
+	return nil
+}
+
+func (c *testConn) Rollback() error {
+	c.tables = nil
+	return nil
+}
+
+type testStmt struct {
+	conn   *testConn
+	table  string
+	insert bool
+}
+
+func (s *testStmt) Close() error {
+	return nil
+}
+
+func (s *testStmt) NumInput() int {
+	return -1
+}
+
+func (s *testStmt) Exec(args []driver.Value) (driver.Result, error) {
+	num, _ := s.conn.rows(s.table)
+	if s.insert {
+		num++
+	}
+
+	if s.conn.tables != nil {
+		s.conn.tables[s.table] = num
+		return driver.RowsAffected(1), nil
+	}
+
+	testDb.mutex.Lock()
+	testDb.tables[s.table] = num
+	testDb.mutex.Unlock()
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s *testStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("queries are not supported")
+}
+
+type testRecord struct {
+	Id   int64  `db:"id" dbopt:"id"`
+	Name string `db:"name"`
+}
+
+func TestInsertCreatedTable(t *testing.T) {
+	t.Run("insert", func(t *testing.T) {
+		dbh := New(t, "", dbhelper.Sqlite{})
+		err := dbh.AddTable(testRecord{}, "records")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		CreateTables(t, dbh, testRecord{})
+
+		// table is visible only within transaction of the test
+		err = dbh.Insert(&testRecord{Id: 1, Name: "a"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// changes are rolled back
+	testDb.mutex.Lock()
+	_, ok := testDb.tables["records"]
+	testDb.mutex.Unlock()
+	if ok {
+		t.Error("table is not rolled back")
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 )
 
 // Stores field data.
@@ -26,6 +27,15 @@ type dbField struct {
 	// Name of the column in database.
 	column string
 
+	// Name of the structure field.
+	name string
+
+	// Type of the field.
+	typ reflect.Type
+
+	// Offset of the field from the beginning of the structure.
+	offset uintptr
+
 	// Autoincremented field.
 	auto bool
 
@@ -37,6 +47,45 @@ type dbField struct {
 
 	// This field stores a timestamp of time when the record was modified.
 	modified bool
+
+	// This field is a full-text search document maintained by database.
+	tsvector bool
+
+	// This field is computed by database, it is never inserted or updated.
+	readonly bool
+
+	// This field is set on insertion and never updated.
+	insertonly bool
+
+	// This field is not inserted if it has zero value.
+	omitempty bool
+
+	// Kind of the column, if it is not defined by field type.
+	kind string
+
+	// Converts values of types not supported by database/sql.
+	codec fieldCodec
+
+	// Valid values of enum field.
+	enum []interface{}
+
+	// Value inserted instead of zero value, current timestamp if defaultNow is true.
+	defaultValue interface{}
+	defaultNow   bool
+
+	// Replaces value of the field in logs and error messages.
+	redactor Redactor
+
+	// Value is hashed by SecretHasher before it is stored.
+	secret bool
+
+	// Replaces value of the field in query results, unless reader has one
+	// of maskRoles.
+	mask      Redactor
+	maskRoles []string
+
+	// Value of the field routes record to a shard of Sharded.
+	shardKey bool
 }
 
 // Stores information about database table.
@@ -45,24 +94,84 @@ type dbTable struct {
 	structType reflect.Type
 	name       string
 
-	fields        map[string]*dbField
-	idField       *dbField
-	createdField  *dbField
-	modifiedField *dbField
+	fields          map[string]*dbField
+	orderedFields   []*dbField
+	idField         *dbField
+	createdField    *dbField
+	modifiedField   *dbField
+	tsvectorField   *dbField
+	shardKeyField   *dbField
+	enumFields      []*dbField
+	defaultFields   []*dbField
+	secretFields    []*dbField
+	maskFields      []*dbField
+	omitemptyFields []*dbField
 
 	numField     int
 	numFieldAuto int
 
 	insertQuery     *Pstmt
+	insertQueries   map[string]*Pstmt
 	updateQuery     *Pstmt
 	deleteQuery     *Pstmt
 	selectByIdQuery *Pstmt
 	selectAllQuery  *Pstmt
 	selectQueries   map[string]*Pstmt
+	searchQueries   map[string]*Pstmt
+
+	// Other queries generated for the table, by query text.
+	queries map[string]*Pstmt
+
+	// Generated queries by shape of request, see getPlan.
+	plans map[planKey]*Pstmt
+
+	// Read-only view, only select queries are prepared.
+	view bool
+
+	// Generates ids of inserted records.
+	idGenerator IdGenerator
+
+	// Callbacks called after commit of modifications.
+	afterCommit []CommitFunc
+
+	// Relations to other tables by name.
+	relations     map[string]*dbRelation
+	relationNames []string
+
+	// Fields defined by TableOptions.
+	softDeleteField *dbField
+	versionField    *dbField
+	tenantField     *dbField
+	parentField     *dbField
+
+	// Previous states of records are copied to history table.
+	history      bool
+	historyQuery *Pstmt
+	asOfQueries  [2]*Pstmt
+
+	// Standard queries are prepared on first use.
+	prepared bool
+	mutex    sync.Mutex
 }
 
 // Returns pointer to new database table structure.
 func (dbh *DbHelper) newDbTable(t reflect.Type, name string) (*dbTable, error) {
+	tbl, err := dbh.newMapping(t, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// table must have an id field
+	if tbl.idField == nil {
+		return nil, errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field with option 'id'", t))
+	}
+
+	return tbl, nil
+}
+
+// Returns mapping of structure fields to columns. Unlike tables, mappings
+// are not required to have an id field.
+func (dbh *DbHelper) newMapping(t reflect.Type, name string) (*dbTable, error) {
 	if t.Kind() != reflect.Struct {
 		return nil, errors.New(fmt.Sprintf("dbhelper: type '%v' is not a structure", t))
 	}
@@ -76,7 +185,11 @@ func (dbh *DbHelper) newDbTable(t reflect.Type, name string) (*dbTable, error) {
 		structType:    t,
 		name:          name,
 		fields:        make(map[string]*dbField),
+		insertQueries: make(map[string]*Pstmt),
+		queries:       make(map[string]*Pstmt),
+		plans:         make(map[planKey]*Pstmt),
 		selectQueries: make(map[string]*Pstmt),
+		searchQueries: make(map[string]*Pstmt),
 	}
 
 	// check all fields in the structure
@@ -99,6 +212,7 @@ func (dbh *DbHelper) newDbTable(t reflect.Type, name string) (*dbTable, error) {
 			// add field to table
 			tbl.numField++
 			tbl.fields[f.column] = f
+			tbl.orderedFields = append(tbl.orderedFields, f)
 
 			// increase number of auto incremented fields
 			if f.auto {
@@ -134,6 +248,46 @@ func (dbh *DbHelper) newDbTable(t reflect.Type, name string) (*dbTable, error) {
 
 				tbl.modifiedField = f
 			}
+
+			// store full-text search field
+			if f.tsvector {
+				if tbl.tsvectorField != nil {
+					return nil, errors.New(
+						fmt.Sprintf("dbhelper: attempt to define several fields with 'tsvector' option in structure type '%v'", t))
+				}
+
+				tbl.tsvectorField = f
+			}
+
+			// store shard key field
+			if f.shardKey {
+				if tbl.shardKeyField != nil {
+					return nil, errors.New(
+						fmt.Sprintf("dbhelper: attempt to define several fields with 'shardkey' option in structure type '%v'", t))
+				}
+
+				tbl.shardKeyField = f
+			}
+
+			// store enum field
+			if f.enum != nil {
+				tbl.enumFields = append(tbl.enumFields, f)
+			}
+
+			// store field omitted if empty
+			if f.omitempty {
+				tbl.omitemptyFields = append(tbl.omitemptyFields, f)
+			}
+
+			// store field with default value
+			if f.defaultValue != nil || f.defaultNow {
+				tbl.defaultFields = append(tbl.defaultFields, f)
+			}
+
+			// store field storing hash of secret
+			if f.secret {
+				tbl.secretFields = append(tbl.secretFields, f)
+			}
 		}
 	}
 
@@ -142,18 +296,80 @@ func (dbh *DbHelper) newDbTable(t reflect.Type, name string) (*dbTable, error) {
 		return nil, errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no exported fields", t))
 	}
 
-	// table must have an id field
-	if tbl.idField == nil {
-		return nil, errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field with option 'id'", t))
+	return tbl, nil
+}
+
+// Returns description of the column.
+func (f *dbField) info() ColumnInfo {
+	// options set by flags
+	var opts []string
+	flags := []struct {
+		set  bool
+		name string
+	}{
+		{f.id, "id"}, {f.auto, "auto"}, {f.created, "created"}, {f.modified, "modified"},
+		{f.tsvector, "tsvector"}, {f.readonly, "readonly"}, {f.insertonly, "insertonly"},
+		{f.omitempty, "omitempty"},
 	}
 
-	// prepare standart queries
-	err := tbl.prepareStandardQueries()
-	if err != nil {
-		return nil, err
+	for _, flag := range flags {
+		if flag.set {
+			opts = append(opts, flag.name)
+		}
 	}
 
-	return tbl, nil
+	return ColumnInfo{
+		Name:     f.column,
+		Field:    f.name,
+		Type:     f.typ,
+		Kind:     f.kind,
+		Id:       f.id,
+		Auto:     f.auto,
+		TsVector: f.tsvector,
+		Options:  opts,
+		Enum:     f.enum,
+	}
+}
+
+// Returns description of the table.
+func (tbl *dbTable) info() TableInfo {
+	columns := make([]ColumnInfo, len(tbl.orderedFields))
+	for i, f := range tbl.orderedFields {
+		columns[i] = f.info()
+	}
+
+	info := TableInfo{
+		Name:    tbl.name,
+		Type:    tbl.structType,
+		Columns: columns,
+		View:    tbl.view,
+		History: tbl.history,
+		Options: TableOptions{
+			Name:       tbl.name,
+			SoftDelete: columnParam(tbl.softDeleteField),
+			Version:    columnParam(tbl.versionField),
+			Tenant:     columnParam(tbl.tenantField),
+			Parent:     columnParam(tbl.parentField),
+		},
+	}
+
+	if tbl.idField != nil {
+		info.Id = tbl.idField.info()
+	}
+
+	return info
+}
+
+// Tables returns descriptions of registered tables and views sorted by
+// name and structure type.
+func (dbh *DbHelper) Tables() []TableInfo {
+	tables := dbh.sortedTables()
+	infos := make([]TableInfo, len(tables))
+	for i, tbl := range tables {
+		infos[i] = tbl.info()
+	}
+
+	return infos
 }
 
 // Returns a slice of fields including embedded structures fields.
@@ -187,6 +403,7 @@ func (tbl *dbTable) parseField(field reflect.StructField) ([]*dbField, error) {
 				newIndex := make([]int, 1, l)
 				newIndex[0] = field.Index[0]
 				f.index = append(newIndex, f.index...)
+				f.offset += field.Offset
 			}
 
 			// append fields from embedded structure
@@ -198,6 +415,11 @@ func (tbl *dbTable) parseField(field reflect.StructField) ([]*dbField, error) {
 			return fields, nil
 		}
 
+		// field is not mapped, e.g. it stores related records
+		if field.Tag.Get("db") == "-" {
+			return fields, nil
+		}
+
 		// check that field has supported type
 		if !checkFieldType(field.Type) {
 			return nil, errors.New(fmt.Sprintf("dbhelper: field '%s' of structure type'%v' has unsupported type '%v'",
@@ -214,9 +436,26 @@ func (tbl *dbTable) parseField(field reflect.StructField) ([]*dbField, error) {
 		// create new dbField structure
 		f := &dbField{
 			index:  field.Index,
+			name:   field.Name,
 			column: column,
+			typ:    field.Type,
+			offset: field.Offset,
+			codec:  getCodec(field.Type),
 		}
 
+		// kind of column is defined by codec
+		if f.codec != nil {
+			f.kind = f.codec.kind()
+		}
+
+		// valid values of enum type
+		enum, err := enumTypeValues(field.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		f.enum = enum
+
 		// parse field options
 		dbopt := field.Tag.Get("dbopt")
 		if dbopt != "" {
@@ -235,15 +474,65 @@ func (tbl *dbTable) parseField(field reflect.StructField) ([]*dbField, error) {
 					f.created = true
 				case "modified":
 					f.modified = true
+				case "tsvector":
+					f.tsvector = true
+				case "readonly":
+					f.readonly = true
+				case "insertonly":
+					f.insertonly = true
+				case "omitempty":
+					f.omitempty = true
+				case "decimal":
+					f.kind = kindDecimal
+				case "shardkey":
+					f.shardKey = true
+				case "bcrypt":
+					if field.Type.Kind() != reflect.String {
+						return nil, errors.New(fmt.Sprintf("dbhelper: field '%s' of structure type '%v' with option 'bcrypt' must be a string",
+							field.Name, tbl.structType))
+					}
+
+					// hashes are not logged
+					f.secret = true
+					f.redactor = RedactAll
+				case "ms", "interval":
+					err = tbl.setDurationCodec(f, opt)
+					if err != nil {
+						return nil, err
+					}
 				case "skip":
 					continue
 				default:
+					if strings.HasPrefix(opt, "enum=") {
+						f.enum, err = enumOptionValues(field.Type, strings.TrimPrefix(opt, "enum="))
+						if err != nil {
+							return nil, err
+						}
+
+						continue
+					}
+
+					if strings.HasPrefix(opt, "default=") {
+						f.defaultValue, f.defaultNow, err = parseDefault(field.Type, strings.TrimPrefix(opt, "default="))
+						if err != nil {
+							return nil, err
+						}
+
+						continue
+					}
+
 					return nil, errors.New(fmt.Sprintf("dbhelper: unknown option '%s' for field '%s' in structure type '%v'",
 						opt, field.Name, tbl.structType))
 				}
 			}
 		}
 
+		// default value cannot be converted by codec
+		if (f.defaultValue != nil || f.defaultNow) && f.codec != nil {
+			return nil, errors.New(fmt.Sprintf("dbhelper: field '%s' of structure type '%v' cannot have default value",
+				field.Name, tbl.structType))
+		}
+
 		// append new field to slice
 		fields = append(fields, f)
 	}
@@ -251,82 +540,280 @@ func (tbl *dbTable) parseField(field reflect.StructField) ([]*dbField, error) {
 	return fields, nil
 }
 
-// Returns fields that can be inserted and named placeholders
+// Returns destination for rows.Scan assigning value to field of structure v.
+func (f *dbField) dest(v reflect.Value) interface{} {
+	if f.codec != nil {
+		return f.codec.dest(f.value(v))
+	}
+
+	return f.value(v).Addr().Interface()
+}
+
+// Returns true if field can store NULL value.
+func (f *dbField) nullable() bool {
+	return f.codec != nil || isScannerValuer(f.typ)
+}
+
+// Returns value of field of structure v passed to database.
+func (f *dbField) arg(v reflect.Value) interface{} {
+	if f.codec != nil {
+		return f.codec.arg(f.value(v))
+	}
+
+	return f.value(v).Interface()
+}
+
+// Returns fields corresponding to columns of query result. Columns found in
+// aliases are mapped to fields with column or Go field name from aliases.
+func (tbl *dbTable) scanPlan(columns []string, aliases map[string]string) ([]*dbField, error) {
+	plan := make([]*dbField, len(columns))
+	for i, col := range columns {
+		f, ok := tbl.resultField(col)
+		if alias, aliased := aliases[col]; aliased {
+			f, ok = tbl.aliasField(alias)
+		}
+
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field assigned to column '%s' of table '%s'",
+				tbl.structType, col, tbl.name))
+		}
+
+		plan[i] = f
+	}
+
+	return plan, nil
+}
+
+// Returns field mapped to column of query result.
+func (tbl *dbTable) resultField(column string) (*dbField, bool) {
+	if f, ok := tbl.fields[column]; ok {
+		return f, true
+	}
+
+	if tbl.dbHelper != nil && tbl.dbHelper.IgnoreColumnCase {
+		for _, f := range tbl.orderedFields {
+			if strings.EqualFold(f.column, column) {
+				return f, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// Returns field with column or Go field name.
+func (tbl *dbTable) aliasField(name string) (*dbField, bool) {
+	if f, ok := tbl.resultField(name); ok {
+		return f, true
+	}
+
+	for _, f := range tbl.orderedFields {
+		if f.name == name {
+			return f, true
+		}
+	}
+
+	return nil, false
+}
+
+// Returns values of parameters of standard query taken from fields of
+// structure v. Parameters for fields with option 'modified' and, if insert
+// is true, with option 'created' get timestamp value. Returned slice must be
+// returned to the pool using putValueBuffer.
+func (tbl *dbTable) structValues(pstmt *Pstmt, v reflect.Value, timestamp int64, insert bool) *[]interface{} {
+	buf := getValueBuffer(len(pstmt.params))
+	for i, p := range pstmt.params {
+		f := tbl.fields[p]
+		if f.modified || (f.created && insert) {
+			(*buf)[i] = timestamp
+		} else {
+			(*buf)[i] = f.arg(v)
+		}
+	}
+
+	return buf
+}
+
+// Returns fields that can be inserted and named placeholders in the order of structure fields.
 func (tbl *dbTable) getInsertFields() ([]string, []string) {
 	fields := make([]string, 0, tbl.numField)
 	holders := make([]string, 0, tbl.numField)
 
-	for col, f := range tbl.fields {
-		if f.auto {
+	for _, f := range tbl.orderedFields {
+		col := f.column
+		if f.auto || f.tsvector || f.readonly {
 			continue
 		}
 
 		fields = append(fields, col)
-		holders = append(holders, getNamedPlaceholder(col))
+		holders = append(holders, tbl.fieldPlaceholder(f))
 	}
 
 	return fields, holders
 }
 
-// Returns fields that can be updated and named placeholders
+// Returns fields that can be updated and named placeholders in the order of structure fields.
 func (tbl *dbTable) getUpdateFields() ([]string, []string) {
 	fields := make([]string, 0, tbl.numField)
 	holders := make([]string, 0, tbl.numField)
 
-	for col, f := range tbl.fields {
-		if f.id || f.auto || f.created {
+	for _, f := range tbl.orderedFields {
+		col := f.column
+		if f.id || f.auto || f.created || f.tsvector || f.readonly || f.insertonly {
 			continue
 		}
 
 		fields = append(fields, col)
-		holders = append(holders, getNamedPlaceholder(col))
+		holders = append(holders, tbl.fieldPlaceholder(f))
 	}
 
 	return fields, holders
 }
 
+// Returns SQL query inserting record. Columns in omitted are not inserted.
+// If forceId is true, auto-incremented id column is inserted.
+func (tbl *dbTable) insertSQL(omitted map[string]bool, forceId bool) string {
+	// insert fields and placeholders
+	fields, ph := tbl.getInsertFields()
+	if forceId && tbl.idField.auto {
+		fields = append(fields, tbl.idField.column)
+		ph = append(ph, tbl.fieldPlaceholder(tbl.idField))
+	}
+
+	columns := make([]string, 0, len(fields))
+	holders := make([]string, 0, len(fields))
+	for i, col := range fields {
+		if omitted[col] {
+			continue
+		}
+
+		columns = append(columns, tbl.dbHelper.quote(col))
+		holders = append(holders, ph[i])
+	}
+
+	// insert query postfix
+	insertPostfix := ""
+	if sqld, ok := tbl.dbHelper.sqlDialect.(HasInsertPostfix); ok {
+		insertPostfix = sqld.InsertPostfix(tbl.info())
+	}
+
+	return fmt.Sprintf("INSERT INTO %s(%s) VALUES(%s) %s",
+		tbl.dbHelper.quote(tbl.name), strings.Join(columns, ", "), strings.Join(holders, ", "), insertPostfix)
+}
+
+// Returns prepared insert query for structure v. Fields with option
+// 'omitempty' that have zero values are not inserted, so database defaults
+// are applied. If forceId is true, auto-incremented id is inserted. Queries
+// for different sets of inserted fields are prepared on first use.
+func (tbl *dbTable) getInsertQuery(v reflect.Value, forceId bool) (*Pstmt, error) {
+	// omitted columns
+	var omitted map[string]bool
+	key := ""
+	if forceId && tbl.idField.auto {
+		key = "+" + tbl.idField.column + ","
+	}
+
+	for _, f := range tbl.omitemptyFields {
+		if f.value(v).IsZero() {
+			if omitted == nil {
+				omitted = make(map[string]bool)
+			}
+
+			omitted[f.column] = true
+			key += f.column + ","
+		}
+	}
+
+	if key == "" {
+		return tbl.insertQuery, nil
+	}
+
+	tbl.mutex.Lock()
+	defer tbl.mutex.Unlock()
+
+	// check if query was already prepared
+	if q, ok := tbl.insertQueries[key]; ok {
+		return q, nil
+	}
+
+	q, err := tbl.dbHelper.Prepare(tbl.insertSQL(omitted, forceId))
+	if err != nil {
+		return nil, err
+	}
+
+	tbl.insertQueries[key] = q
+	return q, nil
+}
+
 func getNamedPlaceholder(name string) string {
 	return fmt.Sprintf(":%s", name)
 }
 
-func (tbl *dbTable) prepareStandardQueries() error {
-	// error
-	var err error
+// Prepares standard queries if they were not prepared yet.
+func (tbl *dbTable) prepare() error {
+	tbl.mutex.Lock()
+	defer tbl.mutex.Unlock()
 
-	// insert fields and placeholders
-	fields, ph := tbl.getInsertFields()
+	if tbl.prepared {
+		return nil
+	}
 
-	// insert query postfix
-	insertPostfix := ""
-	if sqld, ok := tbl.dbHelper.sqlDialect.(hasInsertPostfix); ok {
-		insertPostfix = sqld.insertPostfix(tbl)
+	err := tbl.prepareStandardQueries()
+	if err != nil {
+		return err
+	}
+
+	tbl.prepared = true
+	return nil
+}
+
+func (tbl *dbTable) prepareStandardQueries() error {
+	// views have only select queries
+	if tbl.view {
+		return tbl.prepareSelectQueries()
 	}
 
-	// insert SQL query
-	insertQuery := fmt.Sprintf("INSERT INTO %s(%s) VALUES(%s) %s",
-		tbl.name, strings.Join(fields, ", "), strings.Join(ph, ", "), insertPostfix)
+	// error
+	var err error
 
 	// prepare insert query
-	tbl.insertQuery, err = tbl.dbHelper.Prepare(insertQuery)
+	tbl.insertQuery, err = tbl.dbHelper.Prepare(tbl.insertSQL(nil, false))
 	if err != nil {
 		return err
 	}
 
 	// update fields and placeholders
-	fields, ph = tbl.getUpdateFields()
+	fields, ph := tbl.getUpdateFields()
 
 	// number of non-auto fields
 	num := len(fields)
 
 	// prepare field assignments
-	updateFields := make([]string, num, num)
+	updateFields := make([]string, 0, num)
 	for i, f := range fields {
-		updateFields[i] = fmt.Sprintf("%s = %s", f, ph[i])
+		switch {
+		case tbl.tenantField != nil && f == tbl.tenantField.column:
+			// tenant of record is not changed
+		case tbl.versionField != nil && f == tbl.versionField.column:
+			updateFields = append(updateFields, fmt.Sprintf("%s = %s + 1", tbl.dbHelper.quote(f), tbl.dbHelper.quote(f)))
+		default:
+			updateFields = append(updateFields, fmt.Sprintf("%s = %s", tbl.dbHelper.quote(f), ph[i]))
+		}
+	}
+
+	// record is updated only if it has the same version
+	where := fmt.Sprintf("%s = %s", tbl.dbHelper.quote(tbl.idField.column), getNamedPlaceholder(tbl.idField.column))
+	if tbl.versionField != nil {
+		where += fmt.Sprintf(" AND %s = %s", tbl.dbHelper.quote(tbl.versionField.column),
+			getNamedPlaceholder(tbl.versionField.column))
 	}
 
+	// tenant is taken from structure
+	where += tbl.scopeSQL(columnParam(tbl.tenantField))
+
 	// update SQL query
-	updateQuery := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s",
-		tbl.name, strings.Join(updateFields, ", "), tbl.idField.column, getNamedPlaceholder(tbl.idField.column))
+	updateQuery := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		tbl.dbHelper.quote(tbl.name), strings.Join(updateFields, ", "), where)
 
 	// prepare udpate query
 	tbl.updateQuery, err = tbl.dbHelper.Prepare(updateQuery)
@@ -335,8 +822,17 @@ func (tbl *dbTable) prepareStandardQueries() error {
 	}
 
 	// delete SQL query
-	deleteQuery := fmt.Sprintf("DELETE FROM %s WHERE %s = %s",
-		tbl.name, tbl.idField.column, getNamedPlaceholder(tbl.idField.column))
+	deleteQuery := fmt.Sprintf("DELETE FROM %s WHERE %s = %s%s",
+		tbl.dbHelper.quote(tbl.name), tbl.dbHelper.quote(tbl.idField.column), getNamedPlaceholder(tbl.idField.column),
+		tbl.scopeSQL(columnParam(tbl.tenantField)))
+
+	// records are marked as deleted
+	if tbl.softDeleteField != nil {
+		deleteQuery = fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s = %s%s",
+			tbl.dbHelper.quote(tbl.name), tbl.dbHelper.quote(tbl.softDeleteField.column),
+			getNamedPlaceholder(tbl.softDeleteField.column), tbl.dbHelper.quote(tbl.idField.column),
+			getNamedPlaceholder(tbl.idField.column), tbl.scopeSQL(columnParam(tbl.tenantField)))
+	}
 
 	// prepare delete query
 	tbl.deleteQuery, err = tbl.dbHelper.Prepare(deleteQuery)
@@ -344,19 +840,34 @@ func (tbl *dbTable) prepareStandardQueries() error {
 		return err
 	}
 
-	// select by id SQL query
-	selectByIdQuery := fmt.Sprintf("SELECT * FROM %s WHERE %s = :%s", tbl.name, tbl.idField.column, tbl.idField.column)
+	return tbl.prepareSelectQueries()
+}
 
-	// prepare get by id query
-	tbl.selectByIdQuery, err = tbl.dbHelper.Prepare(selectByIdQuery)
-	if err != nil {
-		return err
+func (tbl *dbTable) prepareSelectQueries() error {
+	// error
+	var err error
+
+	// select by id query is prepared for views only if they have id field
+	if tbl.idField != nil {
+		// select by id SQL query
+		selectByIdQuery := fmt.Sprintf("SELECT * FROM %s WHERE %s = :%s%s",
+			tbl.dbHelper.quote(tbl.name), tbl.dbHelper.quote(tbl.idField.column), tbl.idField.column,
+			tbl.scopeSQL(tenantParam))
+
+		// prepare get by id query
+		tbl.selectByIdQuery, err = tbl.dbHelper.Prepare(selectByIdQuery)
+		if err != nil {
+			return err
+		}
 	}
 
 	// select all SQL query
-	selectAllQuery := fmt.Sprintf("SELECT * FROM %s", tbl.name)
+	selectAllQuery := fmt.Sprintf("SELECT * FROM %s", tbl.dbHelper.quote(tbl.name))
+	if conds := tbl.scopeConds(tenantParam); len(conds) > 0 {
+		selectAllQuery += " WHERE " + strings.Join(conds, " AND ")
+	}
 
-	// prepare get by id query
+	// prepare select all query
 	tbl.selectAllQuery, err = tbl.dbHelper.Prepare(selectAllQuery)
 	if err != nil {
 		return err
@@ -0,0 +1,181 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Valid table, view or column name, optionally prefixed with schema name.
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Returns column definition for table creation query. If primaryKey is
+// false, id column is not marked as primary key.
+func (tbl *dbTable) columnDefinition(f *dbField, primaryKey bool) string {
+	sqld := tbl.dbHelper.sqlDialect
+	def := fmt.Sprintf("%s %s", tbl.dbHelper.quote(f.column), sqld.ColumnType(f.info()))
+
+	// tables with engine have no constraints, columns are not nullable
+	_, engine := sqld.(HasTableEngine)
+
+	if f.id && primaryKey && !engine {
+		def += " PRIMARY KEY"
+	} else if !f.readonly && !engine {
+		// values of read-only columns are computed by database
+		def += " NOT NULL"
+	}
+
+	// auto-incremented column
+	if f.auto {
+		if sqld, ok := sqld.(HasAutoIncrement); ok {
+			def += " " + sqld.AutoIncrement()
+		}
+	}
+
+	// default value
+	if f.defaultValue != nil || f.defaultNow {
+		def += " " + f.defaultClause(sqld)
+	}
+
+	// valid values of enum
+	if f.enum != nil && !engine {
+		def += " " + f.enumCheck(tbl.dbHelper.quote(f.column))
+	}
+
+	return def
+}
+
+// Returns SQL query creating table.
+func (tbl *dbTable) createTableQuery() string {
+	columns := make([]string, len(tbl.orderedFields))
+	for i, f := range tbl.orderedFields {
+		columns[i] = tbl.columnDefinition(f, true)
+	}
+
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", tbl.dbHelper.quote(tbl.name), strings.Join(columns, ", "))
+	if sqld, ok := tbl.dbHelper.sqlDialect.(HasTableEngine); ok {
+		query += " " + sqld.TableEngine(tbl.dbHelper.quote(tbl.idField.column))
+	}
+
+	return query
+}
+
+// Executes query that is not prepared and has no parameters.
+func (dbh *DbHelper) execRaw(query string) (sql.Result, error) {
+	// refuse to modify data
+	if dbh.readOnly {
+		return nil, &ReadOnlyError{Query: query}
+	}
+
+	// apply default timeout
+	ctx, cancel := dbh.withTimeout(dbh.context())
+	defer cancel()
+
+	var res sql.Result
+	var err error
+	if dbh.tx != nil {
+		res, err = dbh.tx.ExecContext(ctx, query)
+	} else {
+		res, err = dbh.Db.ExecContext(ctx, query)
+	}
+
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	return res, nil
+}
+
+// CreateTable creates database table assigned to type of i, if it does not exist.
+// Column types are chosen according to field types and SQL dialect.
+func (dbh *DbHelper) CreateTable(i interface{}) error {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return err
+	}
+
+	if tbl.view {
+		return errors.New(fmt.Sprintf("dbhelper: cannot create read-only view '%s'", tbl.name))
+	}
+
+	_, err = dbh.execRaw(tbl.createTableQuery())
+	if err != nil {
+		return err
+	}
+
+	// create history table
+	if tbl.history {
+		_, err = dbh.execRaw(tbl.createHistoryTableQuery())
+	}
+
+	return err
+}
+
+// DropTable drops database table assigned to type of i, if it exists.
+func (dbh *DbHelper) DropTable(i interface{}) error {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return err
+	}
+
+	if tbl.view {
+		return errors.New(fmt.Sprintf("dbhelper: cannot drop read-only view '%s'", tbl.name))
+	}
+
+	_, err = dbh.execRaw(fmt.Sprintf("DROP TABLE IF EXISTS %s", dbh.quote(tbl.name)))
+	if err != nil {
+		return err
+	}
+
+	// drop history table
+	if tbl.history {
+		_, err = dbh.execRaw(fmt.Sprintf("DROP TABLE IF EXISTS %s", dbh.quote(tbl.historyName())))
+	}
+
+	return err
+}
+
+// RefreshMaterializedView refreshes materialized view name. If concurrently
+// is true, view is refreshed without locking out concurrent selects.
+// Structures can be assigned to materialized views using AddView.
+// Only Postgresql supports materialized views.
+func (dbh *DbHelper) RefreshMaterializedView(name string, concurrently bool) error {
+	sqld, ok := dbh.sqlDialect.(HasMaterializedViews)
+	if !ok {
+		return errors.New("dbhelper: SQL dialect does not support materialized views")
+	}
+
+	if !identifierRegexp.MatchString(name) {
+		return errors.New(fmt.Sprintf("dbhelper: wrong view name '%s'", name))
+	}
+
+	_, err := dbh.execRaw(sqld.RefreshMaterializedView(name, concurrently))
+	return err
+}
@@ -0,0 +1,79 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Returns default value of field of type t defined by option 'default=s'.
+// Value "now" means current timestamp and can be used for integer fields.
+func parseDefault(t reflect.Type, s string) (value interface{}, now bool, err error) {
+	switch t.Kind() {
+	case reflect.String:
+		return s, false, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "now" {
+			return nil, true, nil
+		}
+
+		value, err = strconv.ParseInt(s, 10, t.Bits())
+	case reflect.Float32, reflect.Float64:
+		value, err = strconv.ParseFloat(s, t.Bits())
+	case reflect.Bool:
+		value, err = strconv.ParseBool(s)
+	default:
+		return nil, false, errors.New(fmt.Sprintf("dbhelper: fields of type '%v' cannot have default value", t))
+	}
+
+	if err != nil {
+		return nil, false, errors.New(fmt.Sprintf("dbhelper: wrong default value '%s' for field of type '%v'", s, t))
+	}
+
+	return value, false, nil
+}
+
+// Assigns default values to fields of structure v that have zero value.
+func (tbl *dbTable) applyDefaults(v reflect.Value, timestamp int64) error {
+	for _, f := range tbl.defaultFields {
+		fv := f.value(v)
+		if !fv.IsZero() {
+			continue
+		}
+
+		value := f.defaultValue
+		if f.defaultNow {
+			value = timestamp
+		}
+
+		err := setFieldValue(fv, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns DEFAULT clause of column definition.
+func (f *dbField) defaultClause(sqld SqlDialect) string {
+	if f.defaultNow {
+		return fmt.Sprintf("DEFAULT (%s)", sqld.UnixTimestamp())
+	}
+
+	// values are strings, numbers or booleans
+	literal, _ := sqlLiteral(f.defaultValue)
+	return fmt.Sprintf("DEFAULT %s", literal)
+}
@@ -0,0 +1,145 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testDefaultStruct struct {
+	Id     int64  `db:"id" dbopt:"id,auto"`
+	Status string `db:"status" dbopt:"default=new"`
+	Count  int    `db:"count" dbopt:"default=1"`
+	Seen   int64  `db:"seen" dbopt:"default=now"`
+}
+
+func TestDefaultValues(t *testing.T) {
+	dbh := New(nil, Sqlite{})
+	err := dbh.AddTable(testDefaultStruct{}, "test_default")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testDefaultStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	s := &testDefaultStruct{Count: 5}
+	err = tbl.applyDefaults(reflect.ValueOf(s).Elem(), 1000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if s.Status != "new" || s.Count != 5 || s.Seen != 1000 {
+		t.Errorf("unexpected values: %v", s)
+		return
+	}
+
+	query := tbl.createTableQuery()
+	if !strings.Contains(query, "status TEXT NOT NULL DEFAULT 'new'") ||
+		!strings.Contains(query, "count INTEGER NOT NULL DEFAULT 1") ||
+		!strings.Contains(query, "seen INTEGER NOT NULL DEFAULT (CAST(strftime('%s', 'now') AS INTEGER))") {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+
+	// wrong default value
+	type wrongDefault struct {
+		Id    int64 `db:"id" dbopt:"id,auto"`
+		Count int   `db:"count" dbopt:"default=many"`
+	}
+
+	err = dbh.AddTable(wrongDefault{}, "test_wrong")
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+type testReadonlyStruct struct {
+	Id    int64  `db:"id" dbopt:"id,auto"`
+	Name  string `db:"name"`
+	Owner int64  `db:"owner" dbopt:"insertonly"`
+	Total int64  `db:"total" dbopt:"readonly"`
+}
+
+func TestReadonlyFields(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testReadonlyStruct{}, "test_readonly")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testReadonlyStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	fields, _ := tbl.getInsertFields()
+	if len(fields) != 2 || strings.Contains(strings.Join(fields, ","), "total") {
+		t.Errorf("unexpected insert fields: %v", fields)
+		return
+	}
+
+	fields, _ = tbl.getUpdateFields()
+	if len(fields) != 1 || fields[0] != "name" {
+		t.Errorf("unexpected update fields: %v", fields)
+		return
+	}
+}
+
+type testOmitemptyStruct struct {
+	Id    int64  `db:"id" dbopt:"id,auto"`
+	Name  string `db:"name" dbopt:"omitempty"`
+	Count int    `db:"count"`
+}
+
+func TestOmitemptyInsert(t *testing.T) {
+	dbh := New(nil, MySql{})
+	err := dbh.AddTable(testOmitemptyStruct{}, "test_omitempty")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testOmitemptyStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(tbl.omitemptyFields) != 1 || tbl.omitemptyFields[0].column != "name" {
+		t.Errorf("unexpected omitempty fields: %v", tbl.omitemptyFields)
+		return
+	}
+
+	query := tbl.insertSQL(map[string]bool{"name": true}, false)
+	if query != "INSERT INTO `test_omitempty`(`count`) VALUES(:count) " {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+
+	// auto-incremented id is inserted
+	query = tbl.insertSQL(nil, true)
+	if query != "INSERT INTO `test_omitempty`(`name`, `count`, `id`) VALUES(:name, :count, :id) " {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+)
+
+// Mappings of structures by tags, used by functions which do not need
+// connection to database.
+var tagMappings = New(nil, Postgresql{})
+
+// Change of column value returned by Diff.
+type Change struct {
+	Column string
+	Old    interface{}
+	New    interface{}
+}
+
+// Returns true if i is nil or nil pointer.
+func isNil(i interface{}) bool {
+	if i == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(i)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// Diff returns changes of column values between records before and after,
+// which are structures of the same type or pointers to them, in order of
+// fields. Columns are mapped by tags as by Insert and Query. If before or
+// after is nil, all columns are changed and values of the missing record
+// are nil.
+func Diff(before interface{}, after interface{}) ([]Change, error) {
+	i := before
+	if isNil(i) {
+		i = after
+	}
+
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return nil, err
+	}
+
+	tbl, err := tagMappings.getMapping(t)
+	if err != nil {
+		return nil, err
+	}
+
+	oldValues, err := tbl.fieldValues(before)
+	if err != nil {
+		return nil, err
+	}
+
+	newValues, err := tbl.fieldValues(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for n, f := range tbl.orderedFields {
+		if !reflect.DeepEqual(oldValues[n], newValues[n]) {
+			changes = append(changes, Change{f.column, oldValues[n], newValues[n]})
+		}
+	}
+
+	return changes, nil
+}
+
+// Returns values of fields of structure i in order of fields, or nil values
+// if i is nil.
+func (tbl *dbTable) fieldValues(i interface{}) ([]interface{}, error) {
+	values := make([]interface{}, len(tbl.orderedFields))
+	if isNil(i) {
+		return values, nil
+	}
+
+	v, err := tbl.structValue(i)
+	if err != nil {
+		return nil, err
+	}
+
+	for n, f := range tbl.orderedFields {
+		values[n] = f.value(v).Interface()
+	}
+
+	return values, nil
+}
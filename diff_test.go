@@ -0,0 +1,58 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	before := &testSecretStruct{Id: 1, Login: "user", Status: "active"}
+	after := *before
+	after.Status = "blocked"
+
+	changes, err := Diff(before, after)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !reflect.DeepEqual(changes, []Change{{"status", "active", "blocked"}}) {
+		t.Errorf("unexpected changes: %v", changes)
+		return
+	}
+
+	// inserted record
+	changes, err = Diff(nil, before)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(changes) != 5 || changes[0].Column != "id" || changes[0].Old != nil || changes[0].New != int64(1) {
+		t.Errorf("unexpected changes: %v", changes)
+		return
+	}
+
+	_, err = Diff(before, &testStruct{})
+	if err == nil {
+		t.Error("error expected for different types")
+		return
+	}
+
+	_, err = Diff(nil, nil)
+	if err == nil {
+		t.Error("error expected for nil records")
+		return
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Driver returning predefined results of queries, used to test reading of
+// query results without database.
+type testDriver struct{}
+
+// Result of query returned by test driver.
+type testResult struct {
+	columns []string
+	rows    [][]driver.Value
+
+	// Error returned after all rows were read.
+	err error
+
+	// Optional error of execution with args.
+	execErr func(args []driver.Value) error
+
+	// Optional function returning rows of query executed with args, used
+	// instead of rows.
+	queryRows func(args []driver.Value) [][]driver.Value
+}
+
+var testResults = struct {
+	queries map[string]*testResult
+	mutex   sync.Mutex
+}{queries: make(map[string]*testResult)}
+
+func init() {
+	sql.Register("dbhelper_test", testDriver{})
+}
+
+// Returns DbHelper using test driver, which returns result for query.
+func newTestDriverDb(query string, result *testResult) *DbHelper {
+	testResults.mutex.Lock()
+	testResults.queries[query] = result
+	testResults.mutex.Unlock()
+
+	db, _ := sql.Open("dbhelper_test", "")
+	return New(db, Postgresql{})
+}
+
+func (d testDriver) Open(name string) (driver.Conn, error) {
+	return testConn{}, nil
+}
+
+type testConn struct{}
+
+func (c testConn) Prepare(query string) (driver.Stmt, error) {
+	testResults.mutex.Lock()
+	defer testResults.mutex.Unlock()
+
+	result, ok := testResults.queries[query]
+	if !ok {
+		return nil, errors.New("unknown query: " + query)
+	}
+
+	return testStmt{result}, nil
+}
+
+func (c testConn) Close() error {
+	return nil
+}
+
+func (c testConn) Begin() (driver.Tx, error) {
+	return testTx{}, nil
+}
+
+type testTx struct{}
+
+func (tx testTx) Commit() error {
+	return nil
+}
+
+func (tx testTx) Rollback() error {
+	return nil
+}
+
+type testStmt struct {
+	result *testResult
+}
+
+func (s testStmt) Close() error {
+	return nil
+}
+
+func (s testStmt) NumInput() int {
+	return -1
+}
+
+func (s testStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.result.execErr != nil {
+		err := s.result.execErr(args)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return driver.RowsAffected(len(s.result.rows)), nil
+}
+
+func (s testStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if s.result.queryRows != nil {
+		result := *s.result
+		result.rows = s.result.queryRows(args)
+		return &testRows{result: &result}, nil
+	}
+
+	return &testRows{result: s.result}, nil
+}
+
+type testRows struct {
+	result *testResult
+	next   int
+}
+
+func (r *testRows) Columns() []string {
+	return r.result.columns
+}
+
+func (r *testRows) Close() error {
+	return nil
+}
+
+func (r *testRows) Next(dest []driver.Value) error {
+	if r.next == len(r.result.rows) {
+		if r.result.err != nil {
+			return r.result.err
+		}
+
+		return io.EOF
+	}
+
+	copy(dest, r.result.rows[r.next])
+	r.next++
+	return nil
+}
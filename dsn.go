@@ -0,0 +1,219 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PostgresqlConfig describes connection to Postgresql database.
+type PostgresqlConfig struct {
+	Host     string
+	Port     int
+	Database string
+	User     string
+	Password string
+
+	// SSL mode, e.g. "disable" or "verify-full".
+	SSLMode string
+
+	// Maximum time of establishing connection, rounded to seconds.
+	ConnectTimeout time.Duration
+
+	// Other connection parameters, e.g. "application_name".
+	Params map[string]string
+}
+
+// DSN returns connection string in key=value format understood by lib/pq
+// and pgx. Empty settings are omitted.
+func (c PostgresqlConfig) DSN() string {
+	var pairs []string
+	add := func(key string, value string) {
+		if value != "" {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", key, quoteDSNValue(value)))
+		}
+	}
+
+	add("host", c.Host)
+	if c.Port > 0 {
+		add("port", fmt.Sprint(c.Port))
+	}
+
+	add("dbname", c.Database)
+	add("user", c.User)
+	add("password", c.Password)
+	add("sslmode", c.SSLMode)
+	if c.ConnectTimeout > 0 {
+		add("connect_timeout", fmt.Sprint(int64((c.ConnectTimeout+time.Second-1)/time.Second)))
+	}
+
+	for _, key := range sortedKeys(c.Params) {
+		add(key, c.Params[key])
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+// Returns value of key=value connection string, quoted if necessary.
+func quoteDSNValue(value string) string {
+	if !strings.ContainsAny(value, " '\\") {
+		return value
+	}
+
+	value = strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(value)
+	return "'" + value + "'"
+}
+
+// Returns keys of m in sorted order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// MySqlConfig describes connection to MySql database.
+type MySqlConfig struct {
+	Host     string
+	Port     int
+	Database string
+	User     string
+	Password string
+
+	// Other connection parameters, e.g. "parseTime" or "charset".
+	Params map[string]string
+}
+
+// DSN returns data source name understood by go-sql-driver/mysql:
+// user:password@tcp(host:port)/database?params.
+func (c MySqlConfig) DSN() string {
+	dsn := ""
+	if c.User != "" {
+		dsn = c.User
+		if c.Password != "" {
+			dsn += ":" + c.Password
+		}
+
+		dsn += "@"
+	}
+
+	if c.Host != "" {
+		addr := c.Host
+		if c.Port > 0 {
+			addr = fmt.Sprintf("%s:%d", c.Host, c.Port)
+		}
+
+		dsn += fmt.Sprintf("tcp(%s)", addr)
+	}
+
+	dsn += "/" + c.Database
+	return dsn + encodeDSNParams(c.Params)
+}
+
+// SqliteConfig describes Sqlite database.
+type SqliteConfig struct {
+	// Path of database file or ":memory:".
+	Path string
+
+	// Connection parameters, e.g. "_busy_timeout" or "mode".
+	Params map[string]string
+}
+
+// DSN returns URI of database file.
+func (c SqliteConfig) DSN() string {
+	return "file:" + c.Path + encodeDSNParams(c.Params)
+}
+
+// Returns query string of parameters in sorted order, or empty string.
+func encodeDSNParams(params map[string]string) string {
+	if len(params) == 0 {
+		return ""
+	}
+
+	values := make(url.Values, len(params))
+	for key, value := range params {
+		values.Set(key, value)
+	}
+
+	return "?" + values.Encode()
+}
+
+// PoolOptions are settings of connection pool of sql.DB applied by NewWith
+// and Open. Zero values keep defaults of database/sql.
+type PoolOptions struct {
+	// Maximum number of open connections.
+	MaxOpenConns int
+
+	// Maximum number of idle connections.
+	MaxIdleConns int
+
+	// Number of connections opened by Warmup. If MaxIdleConns is not set,
+	// it is set to MinIdleConns to keep them idle.
+	MinIdleConns int
+
+	// Maximum time connection may be reused.
+	ConnMaxLifetime time.Duration
+
+	// Maximum time connection may be idle.
+	ConnMaxIdleTime time.Duration
+}
+
+// Applies options to db.
+func (opts PoolOptions) apply(db *sql.DB) {
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	} else if opts.MinIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MinIdleConns)
+	}
+
+	if opts.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+
+	if opts.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	}
+}
+
+// NewWith returns new DbHelper, applying options to connection pool of db.
+func NewWith(db *sql.DB, sqlDialect SqlDialect, opts PoolOptions) *DbHelper {
+	opts.apply(db)
+
+	dbh := New(db, sqlDialect)
+	dbh.MinIdleConns = opts.MinIdleConns
+	return dbh
+}
+
+// Open opens database using driver registered as driverName and data source
+// name, e.g. returned by DSN of PostgresqlConfig, and returns DbHelper using
+// it, applying options to connection pool. Connection is not established
+// until it is needed, HealthCheck can be used to check it.
+func Open(driverName string, dsn string, sqlDialect SqlDialect, opts PoolOptions) (*DbHelper, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	return NewWith(db, sqlDialect, opts), nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDSN(t *testing.T) {
+	pg := PostgresqlConfig{
+		Host:           "localhost",
+		Port:           5432,
+		Database:       "test",
+		User:           "test",
+		Password:       "it's secret",
+		SSLMode:        "disable",
+		ConnectTimeout: 1500 * time.Millisecond,
+		Params:         map[string]string{"application_name": "checkout"},
+	}
+
+	dsn := pg.DSN()
+	if dsn != `host=localhost port=5432 dbname=test user=test password='it\'s secret' sslmode=disable connect_timeout=2 application_name=checkout` {
+		t.Errorf("unexpected DSN: %s", dsn)
+		return
+	}
+
+	my := MySqlConfig{
+		Host:     "db",
+		Port:     3306,
+		Database: "test",
+		User:     "test",
+		Password: "test",
+		Params:   map[string]string{"parseTime": "true", "charset": "utf8mb4"},
+	}
+
+	dsn = my.DSN()
+	if dsn != "test:test@tcp(db:3306)/test?charset=utf8mb4&parseTime=true" {
+		t.Errorf("unexpected DSN: %s", dsn)
+		return
+	}
+
+	lite := SqliteConfig{Path: "test.db", Params: map[string]string{"_busy_timeout": "5000"}}
+	dsn = lite.DSN()
+	if dsn != "file:test.db?_busy_timeout=5000" {
+		t.Errorf("unexpected DSN: %s", dsn)
+		return
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dbh, err := Open("dbhelper_test", "", Postgresql{}, PoolOptions{MaxOpenConns: 3, ConnMaxLifetime: time.Minute})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer dbh.Db.Close()
+
+	if dbh.Db.Stats().MaxOpenConnections != 3 {
+		t.Errorf("unexpected pool settings: %+v", dbh.Db.Stats())
+		return
+	}
+
+	_, err = Open("unknown", "", Postgresql{}, PoolOptions{})
+	if err == nil {
+		t.Error("error expected for unknown driver")
+		return
+	}
+}
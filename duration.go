@@ -0,0 +1,225 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind of interval column.
+const kindInterval = "interval"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Assigns codec to time.Duration field according to option. By default
+// durations are stored as integer nanoseconds. Option 'ms' stores integer
+// milliseconds. Option 'interval' stores native interval, if SQL dialect
+// supports it, and integer nanoseconds otherwise.
+func (tbl *dbTable) setDurationCodec(f *dbField, opt string) error {
+	if f.typ != durationType {
+		return errors.New(fmt.Sprintf("dbhelper: option '%s' can only be used for fields of type '%v', column '%s' has type '%v'",
+			opt, durationType, f.column, f.typ))
+	}
+
+	switch opt {
+	case "ms":
+		f.codec = durationCodec{unit: time.Millisecond}
+	case "interval":
+		sqld, ok := tbl.dbHelper.sqlDialect.(HasInterval)
+		if !ok {
+			return nil
+		}
+
+		f.codec = intervalCodec{sqld: sqld}
+	}
+
+	f.kind = f.codec.kind()
+	return nil
+}
+
+// Converts time.Duration fields to and from integer number of units.
+type durationCodec struct {
+	unit time.Duration
+}
+
+// Returns kind of the column.
+func (c durationCodec) kind() string {
+	return ""
+}
+
+// Returns scanner assigning value to v.
+func (c durationCodec) dest(v reflect.Value) sql.Scanner {
+	return &durationScanner{v: v, unit: c.unit}
+}
+
+// Returns number of units.
+func (c durationCodec) arg(v reflect.Value) interface{} {
+	return v.Int() / int64(c.unit)
+}
+
+// Scans integer column to time.Duration field.
+type durationScanner struct {
+	v    reflect.Value
+	unit time.Duration
+}
+
+// Scan implements sql.Scanner.
+func (s *durationScanner) Scan(src interface{}) error {
+	var n int64
+	switch x := src.(type) {
+	case nil:
+	case int64:
+		n = x
+	case []byte, string:
+		var err error
+		n, err = strconv.ParseInt(fmt.Sprintf("%s", x), 10, 64)
+		if err != nil {
+			return wrapError(err)
+		}
+	default:
+		return errors.New(fmt.Sprintf("dbhelper: cannot assign value of type '%T' to field of type '%v'", src, s.v.Type()))
+	}
+
+	s.v.SetInt(n * int64(s.unit))
+	return nil
+}
+
+// Converts time.Duration fields to and from native interval.
+type intervalCodec struct {
+	sqld HasInterval
+}
+
+// Returns kind of the column.
+func (c intervalCodec) kind() string {
+	return kindInterval
+}
+
+// Returns scanner assigning value to v.
+func (c intervalCodec) dest(v reflect.Value) sql.Scanner {
+	return &intervalScanner{v: v}
+}
+
+// Returns interval value.
+func (c intervalCodec) arg(v reflect.Value) interface{} {
+	return c.sqld.Interval(time.Duration(v.Int()))
+}
+
+// Scans interval column to time.Duration field.
+type intervalScanner struct {
+	v reflect.Value
+}
+
+// Scan implements sql.Scanner.
+func (s *intervalScanner) Scan(src interface{}) error {
+	var str string
+	switch x := src.(type) {
+	case nil:
+		s.v.SetInt(0)
+		return nil
+	case []byte:
+		str = string(x)
+	case string:
+		str = x
+	default:
+		return errors.New(fmt.Sprintf("dbhelper: cannot assign value of type '%T' to field of type '%v'", src, s.v.Type()))
+	}
+
+	d, err := parseInterval(str)
+	if err != nil {
+		return err
+	}
+
+	s.v.SetInt(int64(d))
+	return nil
+}
+
+// Durations of interval units. Months and years have the same length as
+// in interval arithmetic of Postgresql.
+var intervalUnits = map[string]time.Duration{
+	"year":  8766 * time.Hour,
+	"years": 8766 * time.Hour,
+	"mon":   720 * time.Hour,
+	"mons":  720 * time.Hour,
+	"day":   24 * time.Hour,
+	"days":  24 * time.Hour,
+}
+
+// Parses interval in Postgresql output format, e.g. "1 year 2 mons 3 days -04:05:06.789".
+func parseInterval(s string) (time.Duration, error) {
+	errorFormat := errors.New(fmt.Sprintf("dbhelper: wrong interval format '%s'", s))
+
+	var d time.Duration
+	parts := strings.Fields(s)
+	for i := 0; i < len(parts); i++ {
+		// time of day
+		if strings.Contains(parts[i], ":") {
+			t := parts[i]
+			sign := time.Duration(1)
+			if strings.HasPrefix(t, "-") {
+				sign = -1
+				t = t[1:]
+			} else if strings.HasPrefix(t, "+") {
+				t = t[1:]
+			}
+
+			hms := strings.Split(t, ":")
+			if len(hms) != 3 {
+				return 0, errorFormat
+			}
+
+			h, err := strconv.ParseInt(hms[0], 10, 64)
+			if err != nil {
+				return 0, errorFormat
+			}
+
+			m, err := strconv.ParseInt(hms[1], 10, 64)
+			if err != nil {
+				return 0, errorFormat
+			}
+
+			sec, err := strconv.ParseFloat(hms[2], 64)
+			if err != nil {
+				return 0, errorFormat
+			}
+
+			d += sign * (time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+				time.Duration(sec*float64(time.Second)+0.5))
+			continue
+		}
+
+		// number of units
+		if i+1 >= len(parts) {
+			return 0, errorFormat
+		}
+
+		n, err := strconv.ParseInt(parts[i], 10, 64)
+		if err != nil {
+			return 0, errorFormat
+		}
+
+		unit, ok := intervalUnits[parts[i+1]]
+		if !ok {
+			return 0, errorFormat
+		}
+
+		d += time.Duration(n) * unit
+		i++
+	}
+
+	return d, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testDurationStruct struct {
+	Id      int64         `db:"id" dbopt:"id,auto"`
+	Timeout time.Duration `db:"timeout" dbopt:"ms"`
+	Period  time.Duration `db:"period" dbopt:"interval"`
+}
+
+func TestParseInterval(t *testing.T) {
+	intervals := map[string]time.Duration{
+		"00:00:00":                0,
+		"01:30:00":                90 * time.Minute,
+		"-00:00:01.5":             -1500 * time.Millisecond,
+		"1 day 02:00:00":          26 * time.Hour,
+		"1 year 2 mons -3 days":   8766*time.Hour + 1440*time.Hour - 72*time.Hour,
+		"2 days -00:00:00.000001": 48*time.Hour - time.Microsecond,
+	}
+
+	for s, expected := range intervals {
+		d, err := parseInterval(s)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if d != expected {
+			t.Errorf("interval '%s' parsed as %v, %v expected", s, d, expected)
+			return
+		}
+	}
+
+	_, err := parseInterval("1 week")
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestDurationFields(t *testing.T) {
+	for _, sqld := range []SqlDialect{Postgresql{}, MySql{}} {
+		dbh := New(nil, sqld)
+		err := dbh.AddTable(testDurationStruct{}, "test_duration")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		tbl, err := dbh.getTable(reflect.TypeOf(testDurationStruct{}))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		s := &testDurationStruct{}
+		v := reflect.ValueOf(s).Elem()
+
+		err = tbl.fields["timeout"].dest(v).(sql.Scanner).Scan(int64(1500))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if s.Timeout != 1500*time.Millisecond || tbl.fields["timeout"].arg(v) != int64(1500) {
+			t.Errorf("unexpected value: %v", s.Timeout)
+			return
+		}
+
+		// interval is stored as nanoseconds if it is not supported
+		s.Period = time.Second
+		expected := interface{}(time.Second)
+		if _, ok := sqld.(Postgresql); ok {
+			expected = "1000000 microseconds"
+		}
+
+		if tbl.fields["period"].arg(v) != expected {
+			t.Errorf("unexpected value: %v", tbl.fields["period"].arg(v))
+			return
+		}
+	}
+}
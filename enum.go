@@ -0,0 +1,131 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Enum is implemented by string or integer types that have a fixed set of
+// valid values. Values of fields of such types are checked by Insert and
+// Update, and CreateTable adds CHECK constraint for corresponding columns.
+// Valid values can also be defined by field option 'enum=value1|value2'.
+type Enum interface {
+	// EnumValues returns valid values.
+	EnumValues() []interface{}
+}
+
+var enumType = reflect.TypeOf((*Enum)(nil)).Elem()
+
+// Returns value of enum field converted to string or int64.
+func enumValue(v reflect.Value) (interface{}, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("dbhelper: type '%v' cannot be an enum, string or integer type expected", v.Type()))
+}
+
+// Returns valid values of type t, if it implements Enum.
+func enumTypeValues(t reflect.Type) ([]interface{}, error) {
+	if !t.Implements(enumType) {
+		return nil, nil
+	}
+
+	list := reflect.Zero(t).Interface().(Enum).EnumValues()
+	values := make([]interface{}, len(list))
+	for i, value := range list {
+		v := reflect.ValueOf(value)
+		if !v.IsValid() || v.Kind() != t.Kind() {
+			return nil, errors.New(fmt.Sprintf("dbhelper: wrong value '%v' of enum type '%v'", value, t))
+		}
+
+		ev, err := enumValue(v)
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = ev
+	}
+
+	return values, nil
+}
+
+// Returns valid values of type t listed in field option.
+func enumOptionValues(t reflect.Type, list string) ([]interface{}, error) {
+	strs := strings.Split(list, "|")
+	values := make([]interface{}, len(strs))
+	for i, s := range strs {
+		switch t.Kind() {
+		case reflect.String:
+			values[i] = s
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(s, 10, t.Bits())
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("dbhelper: wrong value '%s' of enum type '%v'", s, t))
+			}
+
+			values[i] = n
+		default:
+			return nil, errors.New(fmt.Sprintf("dbhelper: type '%v' cannot be an enum, string or integer type expected", t))
+		}
+	}
+
+	return values, nil
+}
+
+// Returns error if value of enum field of structure v is not valid.
+func (f *dbField) checkEnum(v reflect.Value) error {
+	value, err := enumValue(f.value(v))
+	if err != nil {
+		return err
+	}
+
+	for _, ev := range f.enum {
+		if ev == value {
+			return nil
+		}
+	}
+
+	return errors.New(fmt.Sprintf("dbhelper: value '%v' of column '%s' is not one of %v", f.redact(value), f.column, f.enum))
+}
+
+// Returns error if value of some enum field of structure v is not valid.
+func (tbl *dbTable) checkEnums(v reflect.Value) error {
+	for _, f := range tbl.enumFields {
+		err := f.checkEnum(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns CHECK constraint limiting values of column, which is quoted name
+// of the field's column, to valid values of enum.
+func (f *dbField) enumCheck(column string) string {
+	literals := make([]string, len(f.enum))
+	for i, value := range f.enum {
+		// values are strings or integers
+		literals[i], _ = sqlLiteral(value)
+	}
+
+	return fmt.Sprintf("CHECK (%s IN (%s))", column, strings.Join(literals, ", "))
+}
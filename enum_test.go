@@ -0,0 +1,70 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testColor string
+
+func (c testColor) EnumValues() []interface{} {
+	return []interface{}{testColor("red"), testColor("green")}
+}
+
+type testEnumStruct struct {
+	Id    int64     `db:"id" dbopt:"id,auto"`
+	Color testColor `db:"color"`
+	Size  int       `db:"size" dbopt:"enum=1|2|3"`
+}
+
+func TestEnumFields(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testEnumStruct{}, "test_enum")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testEnumStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = tbl.checkEnums(reflect.ValueOf(testEnumStruct{Color: "red", Size: 2}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = tbl.checkEnums(reflect.ValueOf(testEnumStruct{Color: "blue", Size: 2}))
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	err = tbl.checkEnums(reflect.ValueOf(testEnumStruct{Color: "red", Size: 4}))
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	query := tbl.createTableQuery()
+	if !strings.Contains(query, "color text NOT NULL CHECK (color IN ('red', 'green'))") ||
+		!strings.Contains(query, "size bigint NOT NULL CHECK (size IN (1, 2, 3))") {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+}
@@ -0,0 +1,30 @@
+//go:build dbhelper_unsafe
+// +build dbhelper_unsafe
+
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// Returns field of structure v. Field is accessed using its offset
+// if v is addressable.
+func (f *dbField) value(v reflect.Value) reflect.Value {
+	if !v.CanAddr() {
+		return v.FieldByIndex(f.index)
+	}
+
+	return reflect.NewAt(f.typ, unsafe.Add(v.Addr().UnsafePointer(), f.offset)).Elem()
+}
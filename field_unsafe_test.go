@@ -0,0 +1,98 @@
+//go:build dbhelper_unsafe
+// +build dbhelper_unsafe
+
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestUnsafeFieldValue(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	s := testStruct{Id: 1, Bool: true, Created: 2, Modified: 3}
+	s.Text = "text"
+	v := reflect.ValueOf(&s).Elem()
+
+	// fields accessed by offset are the same as fields accessed by index
+	for _, f := range tbl.orderedFields {
+		field := f.value(v)
+		if field.Addr().Pointer() != v.FieldByIndex(f.index).Addr().Pointer() {
+			t.Errorf("unexpected address of field '%s'", f.column)
+			return
+		}
+
+		if field.Interface() != v.FieldByIndex(f.index).Interface() {
+			t.Errorf("unexpected value of field '%s': %v", f.column, field.Interface())
+			return
+		}
+	}
+
+	// fields of embedded structure are assigned
+	tbl.fields["text"].value(v).SetString("new text")
+	if s.Text != "new text" {
+		t.Errorf("unexpected value: %s", s.Text)
+		return
+	}
+
+	// structure which is not addressable is accessed by index
+	if tbl.fields["text"].value(reflect.ValueOf(s)).String() != "new text" {
+		t.Error("unexpected value of field which is not addressable")
+		return
+	}
+}
+
+func TestUnsafeQuery(t *testing.T) {
+	dbh := newTestDriverDb("SELECT * FROM test", &testResult{
+		columns: []string{"id", "b", "c", "m", "text"},
+		rows:    [][]driver.Value{{int64(1), true, int64(2), int64(3), "a"}, {int64(4), false, int64(5), int64(6), "b"}},
+	})
+
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	q, err := dbh.Prepare("SELECT * FROM test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var list []*testStruct
+	num, err := q.Query(&list, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if num != 2 || list[0].Id != 1 || !list[0].Bool || list[0].Modified != 3 || list[1].Text != "b" {
+		t.Errorf("unexpected records: %d, %v", num, list)
+		return
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Filter operators that can be used as suffixes of column names in query strings.
+var filterOperators = map[string]string{
+	"eq":  "=",
+	"ne":  "<>",
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+}
+
+// Name of query string parameter defining sort order.
+const filterSortKey = "sort"
+
+// Filter contains WHERE and ORDER BY clauses parsed from URL query string.
+// Clauses use named parameters, so they can be safely used in prepared queries.
+type Filter struct {
+	// Conditions without WHERE keyword, empty if there are no conditions.
+	Where string
+
+	// Sort order without ORDER BY keyword, empty if order is not defined.
+	OrderBy string
+
+	// Values of named parameters used in Where.
+	Params map[string]interface{}
+}
+
+// Returns field for query string key and corresponding SQL operator.
+func (tbl *dbTable) parseFilterKey(key string) (*dbField, string, error) {
+	// exact column name
+	if f, ok := tbl.fields[key]; ok {
+		return f, "=", nil
+	}
+
+	// column name with operator suffix
+	n := strings.LastIndex(key, "_")
+	if n > 0 {
+		if op, ok := filterOperators[key[n+1:]]; ok {
+			if f, ok := tbl.fields[key[:n]]; ok {
+				return f, op, nil
+			}
+		}
+	}
+
+	return nil, "", errors.New(fmt.Sprintf("dbhelper: cannot filter table '%s' by '%s'", tbl.name, key))
+}
+
+// ParseFilter converts URL query string values to a filter on the table
+// assigned to type of i. Keys are column names, optionally followed by one of
+// the suffixes _eq, _ne, _gt, _gte, _lt, _lte, e.g. ?status=active&created_gt=123.
+// Conditions are joined with AND. Special key 'sort' contains comma-separated
+// column names, prefixed with '-' for descending order, e.g. sort=-created,id.
+// Only mapped columns are allowed.
+func (dbh *DbHelper) ParseFilter(i interface{}, values url.Values) (*Filter, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return nil, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &Filter{
+		Params: make(map[string]interface{}),
+	}
+
+	// sort keys to get the same query for the same values
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if key != filterSortKey {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	// conditions
+	conds := make([]string, 0, len(keys))
+	for _, key := range keys {
+		f, op, err := tbl.parseFilterKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range values[key] {
+			// convert value to field type
+			v := reflect.New(f.typ).Elem()
+			err = setFieldString(v, s)
+			if err != nil {
+				return nil, err
+			}
+
+			param := fmt.Sprintf("f%d", len(conds))
+			filter.Params[param] = v.Interface()
+			conds = append(conds, fmt.Sprintf("%s %s %s", dbh.quote(f.column), op, getNamedPlaceholder(param)))
+		}
+	}
+
+	filter.Where = strings.Join(conds, " AND ")
+
+	// sort order
+	var order []string
+	for _, s := range values[filterSortKey] {
+		for _, col := range strings.Split(s, ",") {
+			dir := "ASC"
+			if strings.HasPrefix(col, "-") {
+				dir = "DESC"
+				col = col[1:]
+			}
+
+			if _, ok := tbl.fields[col]; !ok {
+				return nil, errors.New(fmt.Sprintf("dbhelper: cannot sort table '%s' by '%s'", tbl.name, col))
+			}
+
+			order = append(order, fmt.Sprintf("%s %s", dbh.quote(col), dir))
+		}
+	}
+
+	filter.OrderBy = strings.Join(order, ", ")
+
+	return filter, nil
+}
+
+// SelectFilter selects records of the table assigned to type of i using
+// filter parsed from URL query string values. See ParseFilter for the format.
+// As other selectors, it skips soft-deleted records and records of other
+// tenants.
+func (dbh *DbHelper) SelectFilter(i interface{}, values url.Values) (int64, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return 0, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return 0, err
+	}
+
+	// parse filter
+	filter, err := dbh.ParseFilter(i, values)
+	if err != nil {
+		return 0, err
+	}
+
+	return dbh.selectFilter(tbl, i, filter)
+}
+
+// SelectWithFilter is like SelectFilter, but uses filter returned by
+// ParseFilter, so values can be validated before the query is executed.
+func (dbh *DbHelper) SelectWithFilter(i interface{}, filter *Filter) (int64, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return 0, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return 0, err
+	}
+
+	return dbh.selectFilter(tbl, i, filter)
+}
+
+// Selects records of table using filter limited to scope of dbh.
+func (dbh *DbHelper) selectFilter(tbl *dbTable, i interface{}, filter *Filter) (int64, error) {
+	// conditions of filter and scope
+	var conds []string
+	if filter.Where != "" {
+		conds = append(conds, filter.Where)
+	}
+
+	conds = append(conds, tbl.scopeConds(tenantParam)...)
+
+	// parameters of filter are not modified
+	params := make(map[string]interface{}, len(filter.Params)+1)
+	for name, value := range filter.Params {
+		params[name] = value
+	}
+
+	err := dbh.scopeParams(tbl, params)
+	if err != nil {
+		return 0, err
+	}
+
+	// select query
+	query := fmt.Sprintf("SELECT * FROM %s", dbh.quote(tbl.name))
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	if filter.OrderBy != "" {
+		query += " ORDER BY " + filter.OrderBy
+	}
+
+	// reuse query prepared for the same filter shape
+	q, err := tbl.getQuery(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.in(dbh).Query(i, params)
+}
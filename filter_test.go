@@ -0,0 +1,132 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql/driver"
+	"net/url"
+	"testing"
+)
+
+// Columns of test_options table.
+var testScopedColumns = []string{"id", "name", "deleted", "version", "tenant"}
+
+// Records of test_options table: active and soft-deleted records of tenant
+// acme and a record of another tenant.
+var testScopedRows = [][]driver.Value{
+	{int64(1), "a", int64(0), int64(1), "acme"},
+	{int64(2), "a", int64(123), int64(1), "acme"},
+	{int64(3), "a", int64(0), int64(1), "other"},
+}
+
+// Returns records of testScopedRows, which are not deleted and belong to
+// tenant passed as the last argument, as scoped query does.
+func testScopedQuery(args []driver.Value) [][]driver.Value {
+	var rows [][]driver.Value
+	for _, row := range testScopedRows {
+		if row[2] == int64(0) && row[4] == args[len(args)-1] {
+			rows = append(rows, row)
+		}
+	}
+
+	return rows
+}
+
+// Returns DbHelper with test_options table having soft delete and tenant
+// columns, scoped query returns records of testScopedRows.
+func newTestScopedDb(t *testing.T, query string) *DbHelper {
+	dbh := newTestDriverDb(query, &testResult{
+		columns:   testScopedColumns,
+		queryRows: testScopedQuery,
+	})
+
+	err := dbh.AddTableWith(testOptionsStruct{}, TableOptions{
+		Name:       "test_options",
+		SoftDelete: "deleted",
+		Tenant:     "tenant",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dbh
+}
+
+func TestParseFilter(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	values, err := url.ParseQuery("text=abc&c_gt=123&sort=-c,id")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	filter, err := dbh.ParseFilter(testStruct{}, values)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if filter.Where != "c > :f0 AND text = :f1" {
+		t.Errorf("unexpected conditions: %s", filter.Where)
+		return
+	}
+
+	if filter.OrderBy != "c DESC, id ASC" {
+		t.Errorf("unexpected order: %s", filter.OrderBy)
+		return
+	}
+
+	if filter.Params["f0"] != int64(123) || filter.Params["f1"] != "abc" {
+		t.Errorf("unexpected parameters: %v", filter.Params)
+		return
+	}
+
+	// unknown columns are not allowed
+	values, err = url.ParseQuery("password=1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = dbh.ParseFilter(testStruct{}, values)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestSelectFilterScope(t *testing.T) {
+	dbh := newTestScopedDb(t, "SELECT * FROM test_options WHERE name = $1 AND deleted = 0 AND tenant = $2")
+
+	values := url.Values{"name": {"a"}}
+
+	// tenant is required
+	var list []*testOptionsStruct
+	_, err := dbh.SelectFilter(&list, values)
+	if err != ErrNoTenant {
+		t.Errorf("ErrNoTenant expected: %v", err)
+		return
+	}
+
+	// deleted records and records of other tenants are skipped
+	num, err := dbh.WithTenant("acme").SelectFilter(&list, values)
+	if err != nil || num != 1 || list[0].Id != 1 {
+		t.Errorf("unexpected result: %d, %v", num, err)
+		return
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Returns table registered with name. Table assigned to several structure
+// types is ambiguous, because it is not known which type rows belong to.
+func (dbh *DbHelper) getTableByName(name string) (*dbTable, error) {
+	var found *dbTable
+	for _, tbl := range dbh.sortedTables() {
+		if tbl.name != name {
+			continue
+		}
+
+		if found != nil {
+			return nil, errors.New(fmt.Sprintf("dbhelper: table '%s' is assigned to several structure types '%v' and '%v'",
+				name, found.structType, tbl.structType))
+		}
+
+		found = tbl
+	}
+
+	if found == nil {
+		return nil, errors.New(fmt.Sprintf("dbhelper: table '%s' is not registered", name))
+	}
+
+	return found, nil
+}
+
+// Assigns value to structure field v. Numeric values are converted
+// to the type of the field if they are within the range of the field.
+// Floating point values are assigned to integer fields only if they are
+// whole numbers.
+func setFieldValue(v reflect.Value, value interface{}) error {
+	if value == nil {
+		return errors.New(fmt.Sprintf("dbhelper: cannot assign nil to field of type '%v'", v.Type()))
+	}
+
+	val := reflect.ValueOf(value)
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if !isNumberKind(val.Kind()) {
+			break
+		}
+
+		if !canConvertNumber(val, v) {
+			return errors.New(fmt.Sprintf("dbhelper: cannot assign %v to field of type '%v'", value, v.Type()))
+		}
+
+		v.Set(val.Convert(v.Type()))
+		return nil
+	case reflect.String, reflect.Bool:
+		if val.Kind() == v.Kind() {
+			v.Set(val.Convert(v.Type()))
+			return nil
+		}
+	}
+
+	return errors.New(fmt.Sprintf("dbhelper: cannot assign value of type '%v' to field of type '%v'",
+		val.Type(), v.Type()))
+}
+
+// Returns true if numeric value val can be converted to the type of field v
+// without loss.
+func canConvertNumber(val, v reflect.Value) bool {
+	switch {
+	case isFloatKind(v.Kind()):
+		if isFloatKind(val.Kind()) {
+			return !v.OverflowFloat(val.Float())
+		}
+
+		return true
+	case isUintKind(v.Kind()):
+		switch {
+		case isFloatKind(val.Kind()):
+			f := val.Float()
+			return f == math.Trunc(f) && f >= 0 && f < math.MaxUint64 && !v.OverflowUint(uint64(f))
+		case isUintKind(val.Kind()):
+			return !v.OverflowUint(val.Uint())
+		default:
+			return val.Int() >= 0 && !v.OverflowUint(uint64(val.Int()))
+		}
+	default:
+		switch {
+		case isFloatKind(val.Kind()):
+			f := val.Float()
+			return f == math.Trunc(f) && math.Abs(f) < math.MaxInt64 && !v.OverflowInt(int64(f))
+		case isUintKind(val.Kind()):
+			return val.Uint() <= math.MaxInt64 && !v.OverflowInt(int64(val.Uint()))
+		default:
+			return !v.OverflowInt(val.Int())
+		}
+	}
+}
+
+// Returns true if k is a numeric kind.
+func isNumberKind(k reflect.Kind) bool {
+	return isFloatKind(k) || isUintKind(k) || (k >= reflect.Int && k <= reflect.Int64)
+}
+
+// Returns true if k is an unsigned integer kind.
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uint64
+}
+
+// Returns true if k is a floating point kind.
+func isFloatKind(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+// LoadFixtures reads rows from r and inserts them to registered tables.
+// Data must be an object keyed by table name, containing a list of rows, each
+// of which maps column names to values. Rows are inserted using Insert, so auto
+// incremented ids and timestamps are set automatically. Data is decoded using
+// unmarshal function, which can be json.Unmarshal (used if unmarshal is nil),
+// yaml.Unmarshal or any other function with the same signature.
+// Tables are processed within one transaction in the given order, so that
+// rows referenced by foreign keys are inserted first. If order is given, it
+// must contain all tables of data. Otherwise tables are processed in
+// alphabetical order.
+func (dbh *DbHelper) LoadFixtures(r io.Reader, unmarshal func([]byte, interface{}) error, order ...string) error {
+	if unmarshal == nil {
+		unmarshal = json.Unmarshal
+	}
+
+	// read data
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	// decode data
+	var fixtures map[string][]map[string]interface{}
+	err = unmarshal(data, &fixtures)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	// order table names
+	names, err := fixturesOrder(fixtures, order)
+	if err != nil {
+		return err
+	}
+
+	// start transaction if needed
+	txh := dbh
+	if dbh.tx == nil {
+		txh, err = dbh.Begin()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = txh.loadFixtures(names, fixtures)
+	if err != nil {
+		if txh != dbh {
+			txh.Rollback()
+		}
+
+		return err
+	}
+
+	if txh != dbh {
+		return txh.Commit()
+	}
+
+	return nil
+}
+
+// Returns names of tables of fixtures in order. Tables are sorted
+// alphabetically if order is empty.
+func fixturesOrder(fixtures map[string][]map[string]interface{}, order []string) ([]string, error) {
+	if len(order) == 0 {
+		names := make([]string, 0, len(fixtures))
+		for name := range fixtures {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		return names, nil
+	}
+
+	listed := make(map[string]bool, len(order))
+	for _, name := range order {
+		if listed[name] {
+			return nil, errors.New(fmt.Sprintf("dbhelper: table '%s' is listed several times in order of fixtures", name))
+		}
+
+		listed[name] = true
+	}
+
+	for name := range fixtures {
+		if !listed[name] {
+			return nil, errors.New(fmt.Sprintf("dbhelper: table '%s' is missing in order of fixtures", name))
+		}
+	}
+
+	return order, nil
+}
+
+func (dbh *DbHelper) loadFixtures(names []string, fixtures map[string][]map[string]interface{}) error {
+	for _, name := range names {
+		// get table
+		tbl, err := dbh.getTableByName(name)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range fixtures[name] {
+			// create new structure
+			ptr := reflect.New(tbl.structType)
+
+			// fill fields
+			err = tbl.setColumns(ptr.Elem(), row)
+			if err != nil {
+				return err
+			}
+
+			// insert record
+			err = dbh.Insert(ptr.Interface())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
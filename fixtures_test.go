@@ -0,0 +1,156 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSetFieldValue(t *testing.T) {
+	var s testStruct
+	v := reflect.ValueOf(&s).Elem()
+
+	// JSON numbers are decoded as float64
+	err := setFieldValue(v.FieldByName("Id"), float64(10))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = setFieldValue(v.FieldByName("Text"), "text")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = setFieldValue(v.FieldByName("Bool"), true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if s.Id != 10 || s.Text != "text" || !s.Bool {
+		t.Errorf("unexpected structure: %v", s)
+		return
+	}
+
+	// string cannot be assigned to integer field
+	err = setFieldValue(v.FieldByName("Created"), "1")
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	// fractional and too large numbers cannot be assigned to integer field
+	for _, f := range []float64{1.5, 1e19, math.NaN()} {
+		err = setFieldValue(v.FieldByName("Created"), f)
+		if err == nil {
+			t.Errorf("error expected for %v", f)
+			return
+		}
+	}
+
+	var small struct{ N int8 }
+	for _, n := range []interface{}{float64(300), 300, uint64(300)} {
+		err = setFieldValue(reflect.ValueOf(&small).Elem().Field(0), n)
+		if err == nil {
+			t.Errorf("error expected for %v", n)
+			return
+		}
+	}
+
+	// unsigned fields
+	var u struct{ N uint8 }
+	err = setFieldValue(reflect.ValueOf(&u).Elem().Field(0), 200)
+	if err != nil || u.N != 200 {
+		t.Errorf("unexpected value: %d, %v", u.N, err)
+		return
+	}
+
+	for _, n := range []interface{}{-1, float64(-1), 256} {
+		err = setFieldValue(reflect.ValueOf(&u).Elem().Field(0), n)
+		if err == nil {
+			t.Errorf("error expected for %v", n)
+			return
+		}
+	}
+}
+
+func TestFixturesOrder(t *testing.T) {
+	fixtures := map[string][]map[string]interface{}{
+		"orders": nil,
+		"users":  nil,
+		"items":  nil,
+	}
+
+	names, err := fixturesOrder(fixtures, nil)
+	if err != nil || !reflect.DeepEqual(names, []string{"items", "orders", "users"}) {
+		t.Errorf("unexpected order: %v, %v", names, err)
+		return
+	}
+
+	names, err = fixturesOrder(fixtures, []string{"users", "items", "orders"})
+	if err != nil || !reflect.DeepEqual(names, []string{"users", "items", "orders"}) {
+		t.Errorf("unexpected order: %v, %v", names, err)
+		return
+	}
+
+	// table is missing
+	_, err = fixturesOrder(fixtures, []string{"users", "orders"})
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	// table is listed twice
+	_, err = fixturesOrder(fixtures, []string{"users", "items", "orders", "users"})
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestGetTableByName(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTableByName("test")
+	if err != nil || tbl.structType != reflect.TypeOf(testStruct{}) {
+		t.Errorf("unexpected table: %v", err)
+		return
+	}
+
+	// several types assigned to the same table
+	err = dbh.AddTable(testCSVStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = dbh.getTableByName("test")
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	_, err = dbh.getTableByName("unknown")
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
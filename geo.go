@@ -0,0 +1,186 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// Kind of geometry column.
+const kindGeometry = "geometry"
+
+// Spatial reference system of stored points, WGS 84.
+const pointSRID = 4326
+
+// Point is a geometry point with longitude X and latitude Y. Fields of type
+// Point are stored in geometry columns of PostGIS, MySQL and SpatiaLite.
+// Values are passed to database as WKT and read from native binary formats
+// returned by databases, so select queries do not need wrapping.
+type Point struct {
+	X float64
+	Y float64
+}
+
+// Returns WKT representation of p.
+func (p Point) String() string {
+	return fmt.Sprintf("POINT(%s %s)", strconv.FormatFloat(p.X, 'f', -1, 64), strconv.FormatFloat(p.Y, 'f', -1, 64))
+}
+
+// Converts Point fields to WKT and from binary formats.
+type geometryCodec struct {
+}
+
+// Returns kind of the column.
+func (c geometryCodec) kind() string {
+	return kindGeometry
+}
+
+// Returns scanner assigning value to v.
+func (c geometryCodec) dest(v reflect.Value) sql.Scanner {
+	return &geometryScanner{v: v}
+}
+
+// Returns WKT representation.
+func (c geometryCodec) arg(v reflect.Value) interface{} {
+	return v.Interface().(Point).String()
+}
+
+// Scans geometry column to Point field.
+type geometryScanner struct {
+	v reflect.Value
+}
+
+// Scan implements sql.Scanner.
+func (s *geometryScanner) Scan(src interface{}) error {
+	var b []byte
+	switch x := src.(type) {
+	case nil:
+		s.v.Set(reflect.Zero(s.v.Type()))
+		return nil
+	case []byte:
+		b = x
+	case string:
+		b = []byte(x)
+	default:
+		return errors.New(fmt.Sprintf("dbhelper: cannot assign value of type '%T' to field of type '%v'", src, s.v.Type()))
+	}
+
+	p, err := parsePoint(b)
+	if err != nil {
+		return err
+	}
+
+	s.v.Set(reflect.ValueOf(p))
+	return nil
+}
+
+// Parses point in hex encoded EWKB (PostGIS), SRID followed by WKB (MySQL),
+// SpatiaLite BLOB or WKB format.
+func parsePoint(b []byte) (Point, error) {
+	// hex encoded EWKB
+	if len(b) > 0 && b[0] == '0' {
+		decoded, err := hex.DecodeString(string(b))
+		if err != nil {
+			return Point{}, wrapError(err)
+		}
+
+		b = decoded
+	}
+
+	switch {
+	case len(b) == 60 && b[0] == 0x00 && b[38] == 0x7c && b[59] == 0xfe:
+		// SpatiaLite BLOB: start, byte order, SRID, MBR, class, coordinates, end
+		order := byteOrder(b[1])
+		if order == nil || order.Uint32(b[39:]) != 1 {
+			break
+		}
+
+		return Point{
+			X: math.Float64frombits(order.Uint64(b[43:])),
+			Y: math.Float64frombits(order.Uint64(b[51:])),
+		}, nil
+	case len(b) == 25 && byteOrder(b[4]) != nil:
+		// MySQL: SRID and WKB
+		return parseWKBPoint(b[4:])
+	default:
+		return parseWKBPoint(b)
+	}
+
+	return Point{}, errors.New("dbhelper: wrong format of geometry point")
+}
+
+// Returns byte order encoded by b.
+func byteOrder(b byte) binary.ByteOrder {
+	switch b {
+	case 0:
+		return binary.BigEndian
+	case 1:
+		return binary.LittleEndian
+	}
+
+	return nil
+}
+
+// Parses point in WKB or EWKB format.
+func parseWKBPoint(b []byte) (Point, error) {
+	errorFormat := errors.New("dbhelper: wrong format of geometry point")
+
+	if len(b) < 21 {
+		return Point{}, errorFormat
+	}
+
+	order := byteOrder(b[0])
+	if order == nil {
+		return Point{}, errorFormat
+	}
+
+	typ := order.Uint32(b[1:])
+	b = b[5:]
+
+	// EWKB has SRID flag
+	if typ&0x20000000 != 0 {
+		if len(b) < 20 {
+			return Point{}, errorFormat
+		}
+
+		typ &^= 0x20000000
+		b = b[4:]
+	}
+
+	if typ != 1 {
+		return Point{}, errorFormat
+	}
+
+	return Point{
+		X: math.Float64frombits(order.Uint64(b)),
+		Y: math.Float64frombits(order.Uint64(b[8:])),
+	}, nil
+}
+
+// Returns placeholder of parameter for field f used in insert and update queries.
+func (tbl *dbTable) fieldPlaceholder(f *dbField) string {
+	ph := getNamedPlaceholder(f.column)
+	if f.kind == kindGeometry {
+		if sqld, ok := tbl.dbHelper.sqlDialect.(HasGeometry); ok {
+			return sqld.GeometryFromText(ph, pointSRID)
+		}
+	}
+
+	return ph
+}
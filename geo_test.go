@@ -0,0 +1,101 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+)
+
+type testGeoStruct struct {
+	Id       int64 `db:"id" dbopt:"id,auto"`
+	Location Point `db:"location"`
+}
+
+func TestParsePoint(t *testing.T) {
+	// WKB
+	wkb := make([]byte, 21)
+	wkb[0] = 1
+	binary.LittleEndian.PutUint32(wkb[1:], 1)
+	binary.LittleEndian.PutUint64(wkb[5:], math.Float64bits(30.5))
+	binary.LittleEndian.PutUint64(wkb[13:], math.Float64bits(50.25))
+
+	// EWKB with SRID
+	ewkb := make([]byte, 25)
+	ewkb[0] = 1
+	binary.LittleEndian.PutUint32(ewkb[1:], 0x20000001)
+	binary.LittleEndian.PutUint32(ewkb[5:], pointSRID)
+	copy(ewkb[9:], wkb[5:])
+
+	// MySQL: SRID and WKB
+	mysql := make([]byte, 25)
+	binary.LittleEndian.PutUint32(mysql, pointSRID)
+	copy(mysql[4:], wkb)
+
+	// SpatiaLite BLOB
+	spatialite := make([]byte, 60)
+	spatialite[1] = 1
+	binary.LittleEndian.PutUint32(spatialite[2:], pointSRID)
+	spatialite[38] = 0x7c
+	binary.LittleEndian.PutUint32(spatialite[39:], 1)
+	copy(spatialite[43:], wkb[5:])
+	spatialite[59] = 0xfe
+
+	values := [][]byte{wkb, []byte(strings.ToUpper(hex.EncodeToString(ewkb))), mysql, spatialite}
+	for _, b := range values {
+		p, err := parsePoint(b)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if p.X != 30.5 || p.Y != 50.25 {
+			t.Errorf("unexpected point: %v", p)
+			return
+		}
+	}
+
+	_, err := parsePoint([]byte{1, 2, 3})
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestGeometryPlaceholders(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testGeoStruct{}, "test_geo")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTableByName("test_geo")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, holders := tbl.getInsertFields()
+	if len(holders) != 1 || holders[0] != "ST_GeomFromText(:location, 4326)" {
+		t.Errorf("unexpected placeholders: %v", holders)
+		return
+	}
+
+	if (Point{X: 30.5, Y: -1}).String() != "POINT(30.5 -1)" {
+		t.Errorf("unexpected WKT: %s", Point{X: 30.5, Y: -1})
+		return
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Timeout of HealthCheck if neither context nor DefaultTimeout limit it.
+const healthTimeout = 5 * time.Second
+
+// HasHealthProbe is implemented by dialects checking database with query
+// other than "SELECT 1".
+type HasHealthProbe interface {
+	HealthProbe() string
+}
+
+// HealthStatus is returned by HealthCheck and can be encoded to JSON.
+type HealthStatus struct {
+	// Database responded to probe query.
+	Healthy bool `json:"healthy"`
+
+	// Time taken by ping and probe query.
+	Latency time.Duration `json:"latency"`
+
+	// Error of failed check.
+	Error string `json:"error,omitempty"`
+
+	// Error of failed check is transient, see IsTransient.
+	Transient bool `json:"transient,omitempty"`
+
+	// Statistics of connection pool.
+	Stats sql.DBStats `json:"stats"`
+}
+
+// HealthCheck pings database and executes cheap probe query of SQL dialect,
+// returning status with statistics of connection pool, e.g. for /healthz
+// endpoints. Returned error is the error of failed check. Check is limited
+// by DefaultTimeout or by 5 seconds, unless ctx has a deadline.
+func (dbh *DbHelper) HealthCheck(ctx context.Context) (*HealthStatus, error) {
+	if _, ok := ctx.Deadline(); !ok && dbh.DefaultTimeout <= 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, healthTimeout)
+		defer cancel()
+	}
+
+	ctx, cancel := dbh.withTimeout(ctx)
+	defer cancel()
+
+	probe := "SELECT 1"
+	if sqld, ok := dbh.sqlDialect.(HasHealthProbe); ok {
+		probe = sqld.HealthProbe()
+	}
+
+	start := time.Now()
+	err := dbh.Db.PingContext(ctx)
+	if err == nil {
+		var result interface{}
+		err = dbh.Db.QueryRowContext(ctx, probe).Scan(&result)
+	}
+
+	status := &HealthStatus{
+		Healthy: err == nil,
+		Latency: time.Since(start),
+		Stats:   dbh.Db.Stats(),
+	}
+
+	if err != nil {
+		// wrapping loses type of error, so it is classified first
+		status.Transient = dbh.IsTransient(err)
+		err = wrapError(err)
+		status.Error = err.Error()
+		return status, err
+	}
+
+	return status, nil
+}
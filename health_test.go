@@ -0,0 +1,46 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+)
+
+func TestHealthCheck(t *testing.T) {
+	dbh := newTestDriverDb("SELECT 1", &testResult{
+		columns: []string{"?column?"},
+		rows:    [][]driver.Value{{int64(1)}},
+	})
+
+	status, err := dbh.HealthCheck(context.Background())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !status.Healthy || status.Stats.OpenConnections == 0 {
+		t.Errorf("unexpected status: %+v", status)
+		return
+	}
+
+	// canceled check fails
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	status, err = dbh.HealthCheck(ctx)
+	if err == nil || status.Healthy || status.Error == "" {
+		t.Errorf("failed check expected: %+v", status)
+		return
+	}
+}
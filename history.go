@@ -0,0 +1,236 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Returns name of history table.
+func (tbl *dbTable) historyName() string {
+	return tbl.name + "_history"
+}
+
+// Returns quoted column or value used as the beginning of validity of the record.
+func (tbl *dbTable) validFrom() string {
+	if tbl.modifiedField != nil {
+		return tbl.dbHelper.quote(tbl.modifiedField.column)
+	}
+
+	if tbl.createdField != nil {
+		return tbl.dbHelper.quote(tbl.createdField.column)
+	}
+
+	return "0"
+}
+
+// Returns comma separated list of quoted table columns.
+func (tbl *dbTable) columnList() string {
+	columns := make([]string, len(tbl.orderedFields))
+	for i, f := range tbl.orderedFields {
+		columns[i] = tbl.dbHelper.quote(f.column)
+	}
+
+	return strings.Join(columns, ", ")
+}
+
+// Returns SQL query creating history table.
+func (tbl *dbTable) createHistoryTableQuery() string {
+	columns := make([]string, 0, len(tbl.orderedFields)+2)
+	for _, f := range tbl.orderedFields {
+		// history table has no primary key and auto incremented columns
+		hf := *f
+		hf.id = false
+		hf.auto = false
+		columns = append(columns, tbl.columnDefinition(&hf, false))
+	}
+
+	columns = append(columns, "valid_from BIGINT NOT NULL", "valid_to BIGINT NOT NULL")
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", tbl.dbHelper.quote(tbl.historyName()), strings.Join(columns, ", "))
+}
+
+// EnableHistory makes Update and Delete copy current state of the record
+// to history table before modifying it. History table has the name of the
+// table assigned to type of i with suffix "_history" and is created and dropped
+// by CreateTable and DropTable. Besides table columns it has columns valid_from
+// and valid_to with timestamps of the period when the state was current.
+// Beginning of the period is taken from the field with option 'modified' or
+// 'created'. Past states can be selected using SelectAsOf.
+func (dbh *DbHelper) EnableHistory(i interface{}) error {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return err
+	}
+
+	if tbl.view {
+		return errors.New(fmt.Sprintf("dbhelper: read-only view '%s' cannot have history", tbl.name))
+	}
+
+	tbl.history = true
+	return nil
+}
+
+// Returns prepared query copying record to history table.
+func (tbl *dbTable) getHistoryQuery() (*Pstmt, error) {
+	tbl.mutex.Lock()
+	defer tbl.mutex.Unlock()
+
+	if tbl.historyQuery != nil {
+		return tbl.historyQuery, nil
+	}
+
+	columns := tbl.columnList()
+	query := fmt.Sprintf("INSERT INTO %s (%s, valid_from, valid_to) SELECT %s, %s, :dbhelper_valid_to FROM %s WHERE %s = %s",
+		tbl.dbHelper.quote(tbl.historyName()), columns, columns, tbl.validFrom(), tbl.dbHelper.quote(tbl.name),
+		tbl.dbHelper.quote(tbl.idField.column), getNamedPlaceholder(tbl.idField.column))
+
+	q, err := tbl.dbHelper.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	tbl.historyQuery = q
+	return q, nil
+}
+
+// Executes modify function, copying the record defined by id field of v to
+// history table first, if table has history. If dbh is not bound to a
+// transaction, both queries are executed within a new transaction.
+func (dbh *DbHelper) withHistory(tbl *dbTable, v reflect.Value, timestamp int64,
+	modify func(dbh *DbHelper) (int64, error)) (int64, error) {
+	if !tbl.history {
+		return modify(dbh)
+	}
+
+	if dbh.tx == nil {
+		txh, err := dbh.Begin()
+		if err != nil {
+			return 0, err
+		}
+
+		num, err := txh.withHistory(tbl, v, timestamp, modify)
+		if err != nil {
+			txh.Rollback()
+			return 0, err
+		}
+
+		return num, txh.Commit()
+	}
+
+	q, err := tbl.getHistoryQuery()
+	if err != nil {
+		return 0, err
+	}
+
+	// copy current state
+	_, err = q.in(dbh).Exec(map[string]interface{}{
+		"dbhelper_valid_to": timestamp,
+		tbl.idField.column:  tbl.idField.value(v).Interface(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return modify(dbh)
+}
+
+// Returns prepared queries selecting record state from history table and
+// current record state.
+func (tbl *dbTable) getAsOfQueries() (*Pstmt, *Pstmt, error) {
+	tbl.mutex.Lock()
+	defer tbl.mutex.Unlock()
+
+	if tbl.asOfQueries[0] != nil {
+		return tbl.asOfQueries[0], tbl.asOfQueries[1], nil
+	}
+
+	id := tbl.dbHelper.quote(tbl.idField.column)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = :id AND valid_from <= :time AND valid_to > :time",
+		tbl.columnList(), tbl.dbHelper.quote(tbl.historyName()), id)
+
+	past, err := tbl.dbHelper.Prepare(query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query = fmt.Sprintf("SELECT * FROM %s WHERE %s = :id AND %s <= :time", tbl.dbHelper.quote(tbl.name), id, tbl.validFrom())
+
+	current, err := tbl.dbHelper.Prepare(query)
+	if err != nil {
+		past.Close()
+		return nil, nil, err
+	}
+
+	tbl.asOfQueries = [2]*Pstmt{past, current}
+	return past, current, nil
+}
+
+// SelectAsOf selects state of the record with id that was current at time t.
+// History must be enabled for the table using EnableHistory.
+// If i is a pointer to structure and record was not found, ErrNotFound is returned.
+func (dbh *DbHelper) SelectAsOf(i interface{}, id int64, t time.Time) (int64, error) {
+	// get type
+	typ, err := typeOf(i)
+	if err != nil {
+		return 0, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(typ)
+	if err != nil {
+		return 0, err
+	}
+
+	if !tbl.history {
+		return 0, errors.New(fmt.Sprintf("dbhelper: table '%s' has no history", tbl.name))
+	}
+
+	past, current, err := tbl.getAsOfQueries()
+	if err != nil {
+		return 0, err
+	}
+
+	params := map[string]interface{}{
+		"id":   id,
+		"time": t.UTC().Unix(),
+	}
+
+	// state from history table
+	num, err := past.in(dbh).Query(i, params)
+	if err != nil {
+		return 0, err
+	}
+
+	if num > 0 {
+		return num, nil
+	}
+
+	// record was not modified since t
+	num, err = current.in(dbh).Query(i, params)
+	if err != nil {
+		return 0, err
+	}
+
+	return num, checkFound(i, num)
+}
@@ -0,0 +1,51 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHistoryTableQuery(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = dbh.EnableHistory(testStruct{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	query := tbl.createHistoryTableQuery()
+	if !strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS test_history (id bigint NOT NULL, ") ||
+		!strings.HasSuffix(query, ", valid_from BIGINT NOT NULL, valid_to BIGINT NOT NULL)") {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+
+	if tbl.validFrom() != "m" {
+		t.Errorf("unexpected valid_from column: %s", tbl.validFrom())
+		return
+	}
+}
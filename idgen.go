@@ -0,0 +1,227 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// IdGenerator generates ids of inserted records on the client side instead
+// of relying on auto incremented columns. Generated value is assigned to
+// the field with option 'id' before the record is inserted.
+type IdGenerator interface {
+	// NextId returns new id. Dbh is the DbHelper used for insertion.
+	NextId(dbh *DbHelper) (interface{}, error)
+}
+
+// SetIdGenerator assigns id generator to the table assigned to type of i.
+// Field with option 'id' must not have option 'auto'. If gen is nil, ids
+// are taken from the database again.
+func (dbh *DbHelper) SetIdGenerator(i interface{}, gen IdGenerator) error {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return err
+	}
+
+	if tbl.view {
+		return errors.New(fmt.Sprintf("dbhelper: cannot modify read-only view '%s'", tbl.name))
+	}
+
+	if gen != nil && tbl.idField.auto {
+		return errors.New(fmt.Sprintf("dbhelper: id field of structure type '%v' is auto incremented", t))
+	}
+
+	tbl.idGenerator = gen
+	return nil
+}
+
+// Returns n random bytes.
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	return b, nil
+}
+
+// UUIDv7Generator generates time ordered UUIDs version 7 as strings.
+type UUIDv7Generator struct {
+}
+
+// NextId returns new UUID.
+func (gen UUIDv7Generator) NextId(dbh *DbHelper) (interface{}, error) {
+	b, err := randomBytes(16)
+	if err != nil {
+		return nil, err
+	}
+
+	// 48 bits of unix time in milliseconds
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+
+	// version and variant
+	b[6] = b[6]&0x0f | 0x70
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// Crockford's base32 alphabet used by ULID.
+const ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator generates lexicographically sortable ULIDs as strings.
+type ULIDGenerator struct {
+}
+
+// NextId returns new ULID.
+func (gen ULIDGenerator) NextId(dbh *DbHelper) (interface{}, error) {
+	b, err := randomBytes(16)
+	if err != nil {
+		return nil, err
+	}
+
+	// 48 bits of unix time in milliseconds
+	ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+	binary.BigEndian.PutUint16(b[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(b[2:6], uint32(ms))
+
+	// 128 bits are encoded by 26 characters of 5 bits, first character has 3 bits
+	id := make([]byte, 26)
+	for i := range id {
+		c := 0
+		for j := 0; j < 5; j++ {
+			pos := i*5 + j - 2
+			c <<= 1
+			if pos >= 0 && b[pos/8]&(0x80>>uint(pos%8)) != 0 {
+				c |= 1
+			}
+		}
+
+		id[i] = ulidAlphabet[c]
+	}
+
+	return string(id), nil
+}
+
+// Beginning of time of Snowflake ids, 2015-01-01 UTC in milliseconds.
+const snowflakeEpoch = 1420070400000
+
+// SnowflakeGenerator generates time ordered int64 ids consisting of 41 bits
+// of time in milliseconds, 10 bits of node number and 12 bits of sequence
+// number. Every process generating ids for the same table must use unique node.
+type SnowflakeGenerator struct {
+	node     int64
+	lastTime int64
+	sequence int64
+	mutex    sync.Mutex
+}
+
+// NewSnowflakeGenerator returns new SnowflakeGenerator for node, which
+// must be in range from 0 to 1023.
+func NewSnowflakeGenerator(node int64) (*SnowflakeGenerator, error) {
+	if node < 0 || node > 1023 {
+		return nil, errors.New(fmt.Sprintf("dbhelper: wrong Snowflake node %d", node))
+	}
+
+	return &SnowflakeGenerator{node: node}, nil
+}
+
+// NextId returns new id.
+func (gen *SnowflakeGenerator) NextId(dbh *DbHelper) (interface{}, error) {
+	gen.mutex.Lock()
+	defer gen.mutex.Unlock()
+
+	now := time.Now().UnixNano()/int64(time.Millisecond) - snowflakeEpoch
+	if now < gen.lastTime {
+		// clock moved backwards
+		now = gen.lastTime
+	}
+
+	if now == gen.lastTime {
+		gen.sequence = (gen.sequence + 1) & 0xfff
+		if gen.sequence == 0 {
+			// sequence is exhausted, wait for the next millisecond
+			for now <= gen.lastTime {
+				time.Sleep(100 * time.Microsecond)
+				now = time.Now().UnixNano()/int64(time.Millisecond) - snowflakeEpoch
+			}
+		}
+	} else {
+		gen.sequence = 0
+	}
+
+	gen.lastTime = now
+	return now<<22 | gen.node<<12 | gen.sequence, nil
+}
+
+// SequenceGenerator takes ids from database sequence before insertion.
+// Only Postgresql supports sequences.
+type SequenceGenerator struct {
+	// Name of the sequence.
+	Sequence string
+
+	pstmt *Pstmt
+	mutex sync.Mutex
+}
+
+// NextId returns next value of the sequence.
+func (gen *SequenceGenerator) NextId(dbh *DbHelper) (interface{}, error) {
+	gen.mutex.Lock()
+	if gen.pstmt == nil {
+		sqld, ok := dbh.sqlDialect.(HasSequences)
+		if !ok {
+			gen.mutex.Unlock()
+			return nil, errors.New("dbhelper: SQL dialect does not support sequences")
+		}
+
+		if !identifierRegexp.MatchString(gen.Sequence) {
+			gen.mutex.Unlock()
+			return nil, errors.New(fmt.Sprintf("dbhelper: wrong sequence name '%s'", gen.Sequence))
+		}
+
+		pstmt, err := dbh.Prepare(sqld.NextValue(gen.Sequence))
+		if err != nil {
+			gen.mutex.Unlock()
+			return nil, err
+		}
+
+		gen.pstmt = pstmt
+	}
+	gen.mutex.Unlock()
+
+	var id int64
+	err := gen.pstmt.in(dbh).QueryValue(&id, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return id, nil
+}
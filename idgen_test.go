@@ -0,0 +1,100 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql/driver"
+	"regexp"
+	"testing"
+)
+
+func TestIdGenerators(t *testing.T) {
+	id, err := UUIDv7Generator{}.NextId(nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id.(string)) {
+		t.Errorf("unexpected UUID: %v", id)
+		return
+	}
+
+	id, err = ULIDGenerator{}.NextId(nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`).MatchString(id.(string)) {
+		t.Errorf("unexpected ULID: %v", id)
+		return
+	}
+
+	gen, err := NewSnowflakeGenerator(1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	last := int64(0)
+	for i := 0; i < 10000; i++ {
+		id, err := gen.NextId(nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if id.(int64) <= last {
+			t.Errorf("id %v is not greater than previous id %d", id, last)
+			return
+		}
+
+		last = id.(int64)
+	}
+}
+
+func TestSetIdGenerator(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// id is auto incremented
+	err = dbh.SetIdGenerator(testStruct{}, UUIDv7Generator{})
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestSequenceGenerator(t *testing.T) {
+	dbh := newTestDriverDb("SELECT nextval('test_ids')", &testResult{
+		columns: []string{"nextval"},
+		rows:    [][]driver.Value{{int64(5)}},
+	})
+
+	id, err := (&SequenceGenerator{Sequence: "test_ids"}).NextId(dbh)
+	if err != nil || id != int64(5) {
+		t.Errorf("unexpected id: %v, %v", id, err)
+		return
+	}
+
+	// wrong sequence name
+	_, err = (&SequenceGenerator{Sequence: "ids; DROP TABLE test"}).NextId(dbh)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
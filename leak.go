@@ -0,0 +1,70 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"log"
+	"runtime/debug"
+	"time"
+)
+
+// LeakReport describes transaction which was not committed or rolled back
+// within LeakTimeout.
+type LeakReport struct {
+	// Time when transaction was started.
+	Started time.Time
+
+	// Stack of goroutine which started transaction.
+	Stack string
+}
+
+// Reports transaction which is not finished in time.
+type leakTimer struct {
+	timer *time.Timer
+}
+
+// Starts leak detection of transaction if LeakTimeout is set, recording
+// stack of the caller. Report is made once, transaction is not aborted.
+func (dbh *DbHelper) watchLeak() *leakTimer {
+	if dbh.LeakTimeout <= 0 {
+		return nil
+	}
+
+	report := LeakReport{
+		Started: time.Now(),
+		Stack:   string(debug.Stack()),
+	}
+
+	onLeak := dbh.OnLeak
+	if onLeak == nil {
+		onLeak = logLeak
+	}
+
+	return &leakTimer{
+		timer: time.AfterFunc(dbh.LeakTimeout, func() {
+			onLeak(report)
+		}),
+	}
+}
+
+// Stops leak detection of transaction.
+func (l *leakTimer) stop() {
+	if l != nil {
+		l.timer.Stop()
+	}
+}
+
+// Default OnLeak handler.
+func logLeak(r LeakReport) {
+	log.Printf("dbhelper: transaction started at %s is not finished after %v\n%s",
+		r.Started.Format(time.RFC3339), time.Since(r.Started), r.Stack)
+}
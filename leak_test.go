@@ -0,0 +1,66 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLeakTimeout(t *testing.T) {
+	dbh := newTestDriverDb("SELECT 1", &testResult{})
+	leaks := make(chan LeakReport, 2)
+	dbh.LeakTimeout = 10 * time.Millisecond
+	dbh.OnLeak = func(r LeakReport) {
+		leaks <- r
+	}
+
+	// transaction is not finished in time
+	txh, err := dbh.Begin()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	select {
+	case r := <-leaks:
+		if !strings.Contains(r.Stack, "TestLeakTimeout") {
+			t.Errorf("stack of Begin expected: %s", r.Stack)
+			return
+		}
+	case <-time.After(time.Second):
+		t.Error("leak is not reported")
+		return
+	}
+
+	txh.Rollback()
+
+	// finished transaction is not reported
+	txh, err = dbh.Begin()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = txh.Commit()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	select {
+	case <-leaks:
+		t.Error("finished transaction is reported")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ToMap returns values of fields of structure i keyed by column names.
+// Columns are mapped by tags as by Insert and Query.
+func ToMap(i interface{}) (map[string]interface{}, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return nil, err
+	}
+
+	tbl, err := tagMappings.getMapping(t)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := tbl.structValue(i)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]interface{}, len(tbl.orderedFields))
+	for _, f := range tbl.orderedFields {
+		m[f.column] = f.value(v).Interface()
+	}
+
+	return m, nil
+}
+
+// FromMap assigns values of m keyed by column names to fields of structure
+// i, which must be a pointer. Values are converted as values of fixtures,
+// e.g. float64 decoded from JSON can be assigned to integer field. Columns
+// missing from m are not changed.
+func FromMap(i interface{}, m map[string]interface{}) error {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return err
+	}
+
+	tbl, err := tagMappings.getMapping(t)
+	if err != nil {
+		return err
+	}
+
+	v, err := tbl.structValue(i)
+	if err != nil {
+		return err
+	}
+
+	if !v.CanSet() {
+		return errors.New(fmt.Sprintf("dbhelper: pointer to structure type '%v' is required", tbl.structType))
+	}
+
+	return tbl.setColumns(v, m)
+}
+
+// Assigns values keyed by column names to fields of structure v.
+func (tbl *dbTable) setColumns(v reflect.Value, values map[string]interface{}) error {
+	for col, value := range values {
+		f, ok := tbl.fields[col]
+		if !ok {
+			return errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field assigned to column '%s' of table '%s'",
+				tbl.structType, col, tbl.name))
+		}
+
+		// values of field type are assigned as is
+		fv := f.value(v)
+		if value != nil && reflect.TypeOf(value).AssignableTo(fv.Type()) {
+			fv.Set(reflect.ValueOf(value))
+			continue
+		}
+
+		err := setFieldValue(fv, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"testing"
+)
+
+func TestToMap(t *testing.T) {
+	record := testSecretStruct{Id: 1, Login: "user", Status: "active"}
+	m, err := ToMap(record)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(m) != 5 || m["id"] != int64(1) || m["login"] != "user" {
+		t.Errorf("unexpected map: %v", m)
+		return
+	}
+
+	// values decoded from JSON are converted
+	var copied testSecretStruct
+	err = FromMap(&copied, map[string]interface{}{"id": float64(2), "login": "admin"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if copied.Id != 2 || copied.Login != "admin" {
+		t.Errorf("unexpected record: %v", copied)
+		return
+	}
+
+	err = FromMap(&copied, map[string]interface{}{"unknown": 1})
+	if err == nil {
+		t.Error("error expected for unknown column")
+		return
+	}
+
+	err = FromMap(copied, m)
+	if err == nil {
+		t.Error("error expected for structure passed by value")
+		return
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Key of roles stored in context.
+type rolesKey struct{}
+
+// WithRoles returns copy of ctx carrying roles of the reader, which define
+// whether values of masked columns are returned unmasked.
+func WithRoles(ctx context.Context, roles ...string) context.Context {
+	return context.WithValue(ctx, rolesKey{}, roles)
+}
+
+// Returns roles stored in ctx.
+func rolesOf(ctx context.Context) []string {
+	roles, _ := ctx.Value(rolesKey{}).([]string)
+	return roles
+}
+
+// SetMask sets mask for column of table assigned to type of i. Values of the
+// column in query results are replaced by mask, unless context used for the
+// query carries one of roles (see WithRoles and WithContext). Masked value
+// must have the type of the field, otherwise the field gets zero value, so
+// RedactKeepLast can only mask string fields. Records with masked values
+// must not be updated, since masked values would be stored.
+func (dbh *DbHelper) SetMask(i interface{}, column string, mask Redactor, roles ...string) error {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return err
+	}
+
+	f, ok := tbl.fields[column]
+	if !ok {
+		return errors.New(fmt.Sprintf("dbhelper: table '%s' has no mapped column '%s'", tbl.name, column))
+	}
+
+	if f.id {
+		return errors.New(fmt.Sprintf("dbhelper: id column '%s' of table '%s' cannot be masked", column, tbl.name))
+	}
+
+	if f.mask == nil {
+		tbl.maskFields = append(tbl.maskFields, f)
+	}
+
+	f.mask = mask
+	f.maskRoles = roles
+	return nil
+}
+
+// Returns true if value of the field is masked for reader with roles.
+func (f *dbField) masked(roles []string) bool {
+	if f.mask == nil {
+		return false
+	}
+
+	for _, role := range roles {
+		for _, allowed := range f.maskRoles {
+			if role == allowed {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Returns fields of query result plan masked for reader using ctx.
+func maskedFields(ctx context.Context, plan []*dbField) []*dbField {
+	roles := rolesOf(ctx)
+
+	var masked []*dbField
+	for _, f := range plan {
+		if f.masked(roles) {
+			masked = append(masked, f)
+		}
+	}
+
+	return masked
+}
+
+// Replaces value of the field of structure v with masked value, or with zero
+// value if masked value has different type.
+func (f *dbField) setMasked(v reflect.Value) {
+	fv := f.value(v)
+	m := reflect.ValueOf(f.mask(fv.Interface()))
+	if m.IsValid() && m.Kind() == fv.Kind() && m.Type().ConvertibleTo(fv.Type()) {
+		fv.Set(m.Convert(fv.Type()))
+		return
+	}
+
+	fv.Set(reflect.Zero(fv.Type()))
+}
+
+// Returns cache key of results masked for reader of dbh, so that readers
+// with different roles do not share results.
+func (dbh *DbHelper) maskKey(tbl *dbTable, key string) string {
+	if len(tbl.maskFields) == 0 {
+		return key
+	}
+
+	roles := rolesOf(dbh.context())
+
+	var masked []string
+	for _, f := range tbl.maskFields {
+		if f.masked(roles) {
+			masked = append(masked, f.column)
+		}
+	}
+
+	return fmt.Sprintf("masked:%s:%s", strings.Join(masked, ","), key)
+}
@@ -0,0 +1,91 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestMask(t *testing.T) {
+	query := "SELECT id, card, status FROM test_masks"
+	dbh := newTestDriverDb(query, &testResult{
+		columns: []string{"id", "card", "status"},
+		rows:    [][]driver.Value{{int64(1), "4111111111111111", "active"}},
+	})
+
+	err := dbh.AddTable(testSecretStruct{}, "test_masks")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = dbh.SetMask(testSecretStruct{}, "id", RedactAll)
+	if err == nil {
+		t.Error("id column cannot be masked")
+		return
+	}
+
+	err = dbh.SetMask(testSecretStruct{}, "card", RedactKeepLast(4), "support")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	q, err := dbh.Prepare(query)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	// readers without roles get masked values
+	var list []*testSecretStruct
+	_, err = q.Query(&list, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if list[0].Card != "************1111" || list[0].Status != "active" {
+		t.Errorf("unexpected record: %v", list[0])
+		return
+	}
+
+	// reader with role gets original values
+	ctx := WithRoles(context.Background(), "support")
+	_, err = q.in(dbh.WithContext(ctx)).Query(&list, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if list[0].Card != "4111111111111111" {
+		t.Errorf("unexpected record: %v", list[0])
+		return
+	}
+
+	// results are cached separately
+	tbl, err := dbh.getTable(reflect.TypeOf(testSecretStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if dbh.scopeKey(tbl, "id:1") == dbh.WithContext(ctx).scopeKey(tbl, "id:1") {
+		t.Error("readers with different roles share cache key")
+		return
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Number of errors listed in message of MultiError.
+const multiErrorListed = 3
+
+// MultiError is returned by bulk operations, e.g. ExecBatch or InsertBatch,
+// when some of their elements failed. It maps indexes of failed elements to
+// their errors, so that callers can retry or report exactly these elements.
+type MultiError struct {
+	// Errors of failed elements by their indexes.
+	Errors map[int]error
+}
+
+func (e *MultiError) Error() string {
+	indexes := e.Indexes()
+
+	list := make([]string, 0, multiErrorListed+1)
+	for _, n := range indexes {
+		if len(list) == multiErrorListed {
+			list = append(list, "...")
+			break
+		}
+
+		list = append(list, fmt.Sprintf("element %d: %v", n, e.Errors[n]))
+	}
+
+	return fmt.Sprintf("dbhelper: %d elements failed: %s", len(indexes), strings.Join(list, "; "))
+}
+
+// Indexes returns indexes of failed elements in ascending order.
+func (e *MultiError) Indexes() []int {
+	indexes := make([]int, 0, len(e.Errors))
+	for n := range e.Errors {
+		indexes = append(indexes, n)
+	}
+
+	sort.Ints(indexes)
+	return indexes
+}
+
+// Unwrap returns errors of failed elements in order of their indexes, so
+// that errors.Is and errors.As check each of them.
+func (e *MultiError) Unwrap() []error {
+	indexes := e.Indexes()
+	errs := make([]error, len(indexes))
+	for n, index := range indexes {
+		errs[n] = e.Errors[index]
+	}
+
+	return errs
+}
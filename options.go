@@ -0,0 +1,274 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Name of parameter used to pass tenant to queries generated for tables.
+const tenantParam = "dbhelper_tenant"
+
+// ErrNoTenant is returned when table has tenant column, but DbHelper is not
+// bound to a tenant using WithTenant.
+var ErrNoTenant = errors.New("dbhelper: tenant is not set")
+
+// TableOptions are options of table registration used by AddTableWith.
+type TableOptions struct {
+	// Name of the table.
+	Name string
+
+	// Integer column storing timestamp of deletion. If set, Delete and
+	// DeleteWhere set the column to current timestamp instead of deleting
+	// records, and records with non-zero value are skipped by SelectById,
+	// SelectBy, SelectAll, Select and modifications.
+	SoftDelete string
+
+	// Integer column incremented by every update. Update modifies record
+	// only if its version in database is equal to version of structure,
+	// otherwise no rows are affected, so concurrent modifications can be
+	// detected using UpdateStrict.
+	Version string
+
+	// Column storing tenant of the record. If set, DbHelper must be bound to
+	// a tenant using WithTenant. Inserted records get the tenant, other
+	// queries generated for the table are limited to records of the tenant.
+	Tenant string
+
+	// Column referencing parent record of the same table, which makes the
+	// table a tree used by SelectChildren, SelectAncestors and SubtreeDelete.
+	// Root records store zero value.
+	Parent string
+}
+
+// AddTableWith adds a connection between type of i and table opts.Name
+// like AddTable, applying other options.
+func (dbh *DbHelper) AddTableWith(i interface{}, opts TableOptions) error {
+	err := dbh.addTable(i, opts.Name, false)
+	if err != nil {
+		return err
+	}
+
+	t, _ := typeOf(i)
+	err = dbh.tables[t].setOptions(opts)
+	if err != nil {
+		delete(dbh.tables, t)
+		return err
+	}
+
+	return nil
+}
+
+// AddTables adds connections between types and table names of tables in one
+// call. If any of connections cannot be added, none of them are added.
+func (dbh *DbHelper) AddTables(tables map[interface{}]string) error {
+	opts := make(map[interface{}]TableOptions, len(tables))
+	for i, name := range tables {
+		opts[i] = TableOptions{Name: name}
+	}
+
+	return dbh.AddTablesWith(opts)
+}
+
+// AddTablesWith adds connections between types and tables with options in
+// one call like AddTables.
+func (dbh *DbHelper) AddTablesWith(tables map[interface{}]TableOptions) error {
+	added := make([]interface{}, 0, len(tables))
+	for i, opts := range tables {
+		err := dbh.AddTableWith(i, opts)
+		if err != nil {
+			// remove added tables
+			for _, i := range added {
+				dbh.RemoveTable(i)
+			}
+
+			return err
+		}
+
+		added = append(added, i)
+	}
+
+	return nil
+}
+
+// WithTenant returns DbHelper sharing registered tables and transaction with
+// dbh, which queries of tables with tenant column are limited to tenant.
+func (dbh *DbHelper) WithTenant(tenant interface{}) *DbHelper {
+	// modified tables must be shared to invalidate cache on commit
+	if dbh.tx != nil && dbh.txTables == nil {
+		dbh.txTables = make(map[string]bool)
+	}
+
+	th := *dbh
+	th.tenant = tenant
+	return &th
+}
+
+// Returns field of option column checking that it can store option value.
+func (tbl *dbTable) optionField(option string, column string, integer bool) (*dbField, error) {
+	f, ok := tbl.fields[column]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("dbhelper: %s column '%s' is not mapped to a field of structure type '%v'",
+			option, column, tbl.structType))
+	}
+
+	if f.id || f.auto || f.created || f.modified || f.readonly || f.tsvector {
+		return nil, errors.New(fmt.Sprintf("dbhelper: column '%s' of table '%s' cannot be %s column",
+			column, tbl.name, option))
+	}
+
+	if integer {
+		switch f.typ.Kind() {
+		case reflect.Int, reflect.Int32, reflect.Int64:
+		default:
+			return nil, errors.New(fmt.Sprintf("dbhelper: %s column '%s' of table '%s' must be integer",
+				option, column, tbl.name))
+		}
+	}
+
+	return f, nil
+}
+
+// Applies options of table registration.
+func (tbl *dbTable) setOptions(opts TableOptions) error {
+	var err error
+	if opts.SoftDelete != "" {
+		tbl.softDeleteField, err = tbl.optionField("soft delete", opts.SoftDelete, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.Version != "" {
+		tbl.versionField, err = tbl.optionField("version", opts.Version, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.Tenant != "" {
+		tbl.tenantField, err = tbl.optionField("tenant", opts.Tenant, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.Parent != "" {
+		tbl.parentField, err = tbl.optionField("parent", opts.Parent, false)
+		if err != nil {
+			return err
+		}
+
+		if tbl.idField == nil || !compatibleIdTypes(tbl.parentField.typ, tbl.idField.typ) {
+			return errors.New(fmt.Sprintf("dbhelper: parent column '%s' of table '%s' must have type of id",
+				opts.Parent, tbl.name))
+		}
+	}
+
+	return nil
+}
+
+// Returns conditions limiting queries to records which are not deleted and
+// belong to the tenant passed as parameter tenant.
+func (tbl *dbTable) scopeConds(tenant string) []string {
+	var conds []string
+	if tbl.softDeleteField != nil {
+		conds = append(conds, fmt.Sprintf("%s = 0", tbl.dbHelper.quote(tbl.softDeleteField.column)))
+	}
+
+	if tbl.tenantField != nil {
+		conds = append(conds, fmt.Sprintf("%s = %s", tbl.dbHelper.quote(tbl.tenantField.column),
+			getNamedPlaceholder(tenant)))
+	}
+
+	return conds
+}
+
+// Returns conditions of scopeConds as part of WHERE clause following other conditions.
+func (tbl *dbTable) scopeSQL(tenant string) string {
+	sql := ""
+	for _, cond := range tbl.scopeConds(tenant) {
+		sql += " AND " + cond
+	}
+
+	return sql
+}
+
+// Returns scope conditions for condition API.
+func (dbh *DbHelper) scopeCond(tbl *dbTable) ([]Cond, error) {
+	var conds []Cond
+	if tbl.softDeleteField != nil {
+		conds = append(conds, Eq(tbl.softDeleteField.column, 0))
+	}
+
+	if tbl.tenantField != nil {
+		if dbh.tenant == nil {
+			return nil, ErrNoTenant
+		}
+
+		conds = append(conds, Eq(tbl.tenantField.column, dbh.tenant))
+	}
+
+	return conds, nil
+}
+
+// Adds tenant to parameters of query with scope conditions.
+func (dbh *DbHelper) scopeParams(tbl *dbTable, params map[string]interface{}) error {
+	if tbl.tenantField == nil {
+		return nil
+	}
+
+	if dbh.tenant == nil {
+		return ErrNoTenant
+	}
+
+	params[tenantParam] = dbh.tenant
+	return nil
+}
+
+// Returns cache key of results of the tenant.
+func (dbh *DbHelper) scopeKey(tbl *dbTable, key string) string {
+	key = dbh.maskKey(tbl, key)
+	if tbl.tenantField == nil {
+		return key
+	}
+
+	return fmt.Sprintf("tenant:%v:%s", dbh.tenant, key)
+}
+
+// Assigns tenant of DbHelper to structure v.
+func (dbh *DbHelper) setTenant(tbl *dbTable, v reflect.Value) error {
+	if tbl.tenantField == nil {
+		return nil
+	}
+
+	if dbh.tenant == nil {
+		return ErrNoTenant
+	}
+
+	if !v.CanSet() {
+		return errors.New(fmt.Sprintf("dbhelper: pointer to structure type '%v' is required", tbl.structType))
+	}
+
+	return setFieldValue(tbl.tenantField.value(v), dbh.tenant)
+}
+
+// Returns name of parameter passing value of field f, or empty string if f is nil.
+func columnParam(f *dbField) string {
+	if f == nil {
+		return ""
+	}
+
+	return f.column
+}
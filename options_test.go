@@ -0,0 +1,164 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testOptionsStruct struct {
+	Id      int64  `db:"id" dbopt:"id,auto"`
+	Name    string `db:"name"`
+	Deleted int64  `db:"deleted"`
+	Version int64  `db:"version"`
+	Tenant  string `db:"tenant"`
+}
+
+func TestAddTables(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTables(map[interface{}]string{
+		testStruct{}:        "test",
+		testOptionsStruct{}: "test_options",
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(dbh.tables) != 2 {
+		t.Errorf("unexpected number of tables: %d", len(dbh.tables))
+		return
+	}
+
+	// none of tables are added if one fails
+	dbh = New(nil, Postgresql{})
+	err = dbh.AddTablesWith(map[interface{}]TableOptions{
+		testStruct{}:        {Name: "test"},
+		testOptionsStruct{}: {Name: "test_options", Version: "name"},
+	})
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	if len(dbh.tables) != 0 {
+		t.Errorf("unexpected number of tables: %d", len(dbh.tables))
+		return
+	}
+
+	// unknown column
+	err = dbh.AddTableWith(testOptionsStruct{}, TableOptions{Name: "test_options", Tenant: "owner"})
+	if err == nil || len(dbh.tables) != 0 {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestTableOptions(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTableWith(testOptionsStruct{}, TableOptions{
+		Name:       "test_options",
+		SoftDelete: "deleted",
+		Version:    "version",
+		Tenant:     "tenant",
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// tenant is required
+	_, _, err = dbh.Select(testOptionsStruct{}).SQL()
+	if err != ErrNoTenant {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	query, params, err := dbh.WithTenant("acme").Select(testOptionsStruct{}).Where(Eq("name", "a")).SQL()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if query != "SELECT * FROM test_options WHERE (name = :c0 AND deleted = :c1 AND tenant = :c2)" {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+
+	if params["c2"] != "acme" {
+		t.Errorf("unexpected parameters: %v", params)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testOptionsStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if tbl.scopeSQL(tenantParam) != " AND deleted = 0 AND tenant = :dbhelper_tenant" {
+		t.Errorf("unexpected scope: %s", tbl.scopeSQL(tenantParam))
+		return
+	}
+
+	// version is incremented, tenant cannot be updated
+	set, err := dbh.buildSet(tbl, map[string]interface{}{"name": "b"}, make(map[string]interface{}), 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if set != "name = :u_name, version = version + 1" {
+		t.Errorf("unexpected assignments: %s", set)
+		return
+	}
+
+	_, err = dbh.buildSet(tbl, map[string]interface{}{"tenant": "other"}, make(map[string]interface{}), 0)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestTables(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTableWith(testOptionsStruct{}, TableOptions{Name: "test_options", Version: "version"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = dbh.AddView(testStruct{}, "test_view")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tables := dbh.Tables()
+	if len(tables) != 2 || tables[0].Name != "test_options" || !tables[1].View {
+		t.Errorf("unexpected tables: %v", tables)
+		return
+	}
+
+	info := tables[0]
+	if info.Type != reflect.TypeOf(testOptionsStruct{}) || info.Options.Version != "version" ||
+		info.Id.Field != "Id" || len(info.Columns) != 5 {
+		t.Errorf("unexpected table: %v", info)
+		return
+	}
+
+	if len(info.Id.Options) != 2 || info.Id.Options[0] != "id" || info.Id.Options[1] != "auto" {
+		t.Errorf("unexpected options: %v", info.Id.Options)
+		return
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ParallelQuery describes prepared query executed by QueryParallel.
+type ParallelQuery struct {
+	// Prepared query.
+	Pstmt *Pstmt
+
+	// Destination of query results, see Pstmt.Query.
+	Result interface{}
+
+	// Query parameters, see Pstmt.Query.
+	Params interface{}
+
+	// Number of processed rows, set after execution.
+	Num int64
+
+	// Execution error, set after execution.
+	Err error
+}
+
+// QueryParallel executes independent prepared queries concurrently using
+// up to limit goroutines (no limit if limit is not positive). Results and
+// errors are stored in queries. Returns the first error in the order of
+// queries or nil if all queries succeeded. Queries are executed sequentially
+// if DbHelper is bound to a transaction. No query is executed if any of
+// queries has no prepared statement.
+func (dbh *DbHelper) QueryParallel(limit int, queries ...*ParallelQuery) error {
+	for n, q := range queries {
+		if q == nil || q.Pstmt == nil {
+			return errors.New(fmt.Sprintf("dbhelper: parallel query %d has no prepared statement", n))
+		}
+	}
+
+	if dbh.tx != nil {
+		limit = 1
+	}
+
+	if limit <= 0 || limit > len(queries) {
+		limit = len(queries)
+	}
+
+	// run workers
+	ch := make(chan *ParallelQuery)
+	var wg sync.WaitGroup
+	for n := 0; n < limit; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for q := range ch {
+				q.Num, q.Err = q.Pstmt.in(dbh).Query(q.Result, q.Params)
+			}
+		}()
+	}
+
+	for _, q := range queries {
+		ch <- q
+	}
+
+	close(ch)
+	wg.Wait()
+
+	// return the first error
+	for _, q := range queries {
+		if q.Err != nil {
+			return q.Err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestQueryParallel(t *testing.T) {
+	dbh := newTestDriverDb("SELECT id FROM test_parallel WHERE id > $1", &testResult{
+		columns: []string{"id"},
+		rows:    [][]driver.Value{{int64(1)}, {int64(2)}},
+	})
+
+	q, err := dbh.Prepare("SELECT id FROM test_parallel WHERE id > :id")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	queries := make([]*ParallelQuery, 5)
+	results := make([][]*int64, len(queries))
+	for n := range queries {
+		queries[n] = &ParallelQuery{Pstmt: q, Result: &results[n], Params: map[string]interface{}{"id": n}}
+	}
+
+	err = dbh.QueryParallel(2, queries...)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for n, q := range queries {
+		if q.Num != 2 || len(results[n]) != 2 || *results[n][1] != 2 {
+			t.Errorf("unexpected result of query %d: %d, %v", n, q.Num, results[n])
+			return
+		}
+	}
+
+	// the first error is returned
+	queries[3].Result = nil
+	err = dbh.QueryParallel(0, queries...)
+	if err != errorNil || queries[4].Err != nil {
+		t.Errorf("error of query 3 expected: %v, %v", err, queries[4].Err)
+		return
+	}
+
+	// queries without statements are rejected
+	err = dbh.QueryParallel(0, queries[0], &ParallelQuery{Result: &results[1]})
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	err = dbh.QueryParallel(0, nil)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParamStyle defines how parameters are written in queries passed to Prepare.
+type ParamStyle int
+
+const (
+	// NamedParams are written as :name. Values are passed as a map or, if
+	// query has only one parameter, as a single value.
+	NamedParams ParamStyle = iota
+
+	// OrdinalParams are written as $1, $2, etc. Values are passed as a slice
+	// []interface{}, where n-th element is the value of parameter $n.
+	// String literals and quoted identifiers are not changed.
+	OrdinalParams
+
+	// QuestionParams are written as ?. Values are passed as a slice
+	// []interface{} in the order of parameters in the query. String literals
+	// and quoted identifiers are not changed, so they may contain ?.
+	QuestionParams
+)
+
+var (
+	ordinalParamRegexp  = regexp.MustCompile(`\$[0-9]+`)
+	questionParamRegexp = regexp.MustCompile(`\?`)
+
+	// string literals with doubled quotes and quoted identifiers
+	quotedRegexp = regexp.MustCompile(`'(?:[^']|'')*'|"[^"]*"|` + "`[^`]*`")
+)
+
+// Replaces matches of re in query, which are not within quoted sections,
+// with the result of repl.
+func replaceUnquoted(query string, re *regexp.Regexp, repl func(string) string) string {
+	var buf strings.Builder
+	last := 0
+	for _, loc := range quotedRegexp.FindAllStringIndex(query, -1) {
+		buf.WriteString(re.ReplaceAllStringFunc(query[last:loc[0]], repl))
+		buf.WriteString(query[loc[0]:loc[1]])
+		last = loc[1]
+	}
+
+	buf.WriteString(re.ReplaceAllStringFunc(query[last:], repl))
+	return buf.String()
+}
+
+// Replaces parameters in query with placeholders of SQL dialect. Returns
+// query and names of parameters in the order of placeholders. Names of
+// positional parameters are their numbers.
+func (dbh *DbHelper) parseParams(query string, style ParamStyle) (string, []string, error) {
+	ph := dbh.sqlDialect.Placeholder()
+
+	switch style {
+	case NamedParams:
+		params := paramRegexp.FindAllString(query, -1)
+		for i, p := range params {
+			if len(p) < 2 {
+				return "", nil, errors.New(fmt.Sprintf("dbhelper: wrong parameter placeholder: '%s'", p))
+			}
+
+			// replaced named parameter with placeholder
+			query = strings.Replace(query, p, ph.Next(), 1)
+
+			// store named parameter
+			params[i] = p[1:]
+		}
+
+		return query, params, nil
+	case OrdinalParams:
+		var params []string
+		query = replaceUnquoted(query, ordinalParamRegexp, func(p string) string {
+			params = append(params, p[1:])
+			return ph.Next()
+		})
+
+		return query, params, nil
+	case QuestionParams:
+		var params []string
+		query = replaceUnquoted(query, questionParamRegexp, func(p string) string {
+			params = append(params, strconv.Itoa(len(params)+1))
+			return ph.Next()
+		})
+
+		return query, params, nil
+	}
+
+	return "", nil, errors.New(fmt.Sprintf("dbhelper: unknown parameter style %d", style))
+}
+
+// Returns a list of values for positional query parameters.
+func (pstmt *Pstmt) getPositionalValues(list []interface{}) ([]interface{}, error) {
+	values := make([]interface{}, len(pstmt.params))
+	used := make([]bool, len(list))
+	for i, p := range pstmt.params {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 {
+			return nil, errors.New(fmt.Sprintf("dbhelper: wrong parameter number '%s'", p))
+		}
+
+		if n > len(list) {
+			return nil, errors.New(fmt.Sprintf("dbhelper: value for parameter %d is missing", n))
+		}
+
+		values[i] = paramValue(list[n-1])
+		used[n-1] = true
+	}
+
+	// check that all values are used
+	if !pstmt.dbHelper.AllowUnknownParams {
+		for n, ok := range used {
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("dbhelper: query has no parameter %d", n+1))
+			}
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,223 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Partitioning methods of partitioned tables.
+const (
+	PartitionByRange = "RANGE"
+	PartitionByList  = "LIST"
+)
+
+// Returns SQL literal for value used in partition bounds, which cannot
+// be passed as query parameters.
+func sqlLiteral(value interface{}) (string, error) {
+	if t, ok := value.(time.Time); ok {
+		return fmt.Sprintf("'%s'", t.Format("2006-01-02 15:04:05.999999999-07:00")), nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Bool:
+		return fmt.Sprintf("%v", v.Interface()), nil
+	case reflect.String:
+		return fmt.Sprintf("'%s'", strings.Replace(v.String(), "'", "''", -1)), nil
+	}
+
+	return "", errors.New(fmt.Sprintf("dbhelper: value of type '%T' cannot be used as partition bound", value))
+}
+
+// Returns partition bound for range from (inclusive) to (exclusive).
+func rangeBound(from, to interface{}) (string, error) {
+	f, err := sqlLiteral(from)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := sqlLiteral(to)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("FOR VALUES FROM (%s) TO (%s)", f, t), nil
+}
+
+// Returns partition bound for list of values.
+func listBound(values []interface{}) (string, error) {
+	if len(values) == 0 {
+		return "", errors.New("dbhelper: list partition must have at least one value")
+	}
+
+	literals := make([]string, len(values))
+	for i, value := range values {
+		l, err := sqlLiteral(value)
+		if err != nil {
+			return "", err
+		}
+
+		literals[i] = l
+	}
+
+	return fmt.Sprintf("FOR VALUES IN (%s)", strings.Join(literals, ", ")), nil
+}
+
+// Returns table assigned to type of i, if SQL dialect supports partitions.
+func (dbh *DbHelper) getPartitionedTable(i interface{}) (*dbTable, HasPartitions, error) {
+	sqld, ok := dbh.sqlDialect.(HasPartitions)
+	if !ok {
+		return nil, nil, errors.New("dbhelper: SQL dialect does not support partitions")
+	}
+
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tbl.view {
+		return nil, nil, errors.New(fmt.Sprintf("dbhelper: read-only view '%s' cannot be partitioned", tbl.name))
+	}
+
+	return tbl, sqld, nil
+}
+
+// CreatePartitionedTable creates database table assigned to type of i, if it
+// does not exist, partitioned by column using method PartitionByRange or
+// PartitionByList. Column is added to the primary key, as required by database.
+// Records are inserted to partitioned table as usual and database routes them
+// to partitions. Only Postgresql supports partitions.
+func (dbh *DbHelper) CreatePartitionedTable(i interface{}, method string, column string) error {
+	tbl, sqld, err := dbh.getPartitionedTable(i)
+	if err != nil {
+		return err
+	}
+
+	if method != PartitionByRange && method != PartitionByList {
+		return errors.New(fmt.Sprintf("dbhelper: unknown partitioning method '%s'", method))
+	}
+
+	if _, ok := tbl.fields[column]; !ok {
+		return errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field assigned to column '%s' of table '%s'",
+			tbl.structType, column, tbl.name))
+	}
+
+	_, err = dbh.execRaw(tbl.createPartitionedTableQuery(sqld, method, column))
+	return err
+}
+
+// Returns SQL query creating partitioned table.
+func (tbl *dbTable) createPartitionedTableQuery(sqld HasPartitions, method string, column string) string {
+	columns := make([]string, len(tbl.orderedFields), len(tbl.orderedFields)+1)
+	for i, f := range tbl.orderedFields {
+		columns[i] = tbl.columnDefinition(f, column == tbl.idField.column)
+	}
+
+	// primary key must include partition column
+	if column != tbl.idField.column {
+		columns = append(columns, fmt.Sprintf("PRIMARY KEY (%s, %s)", tbl.dbHelper.quote(tbl.idField.column),
+			tbl.dbHelper.quote(column)))
+	}
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s) %s",
+		tbl.dbHelper.quote(tbl.name), strings.Join(columns, ", "), sqld.PartitionBy(method, tbl.dbHelper.quote(column)))
+}
+
+// CreatePartition creates partition name of range partitioned table assigned
+// to type of i, if it does not exist. Partition contains records with values
+// of partition column from (inclusive) to (exclusive).
+func (dbh *DbHelper) CreatePartition(i interface{}, name string, from, to interface{}) error {
+	bound, err := rangeBound(from, to)
+	if err != nil {
+		return err
+	}
+
+	return dbh.createPartition(i, name, bound)
+}
+
+// CreateListPartition creates partition name of list partitioned table
+// assigned to type of i, if it does not exist. Partition contains records with
+// listed values of partition column.
+func (dbh *DbHelper) CreateListPartition(i interface{}, name string, values ...interface{}) error {
+	bound, err := listBound(values)
+	if err != nil {
+		return err
+	}
+
+	return dbh.createPartition(i, name, bound)
+}
+
+func (dbh *DbHelper) createPartition(i interface{}, name string, bound string) error {
+	tbl, sqld, err := dbh.getPartitionedTable(i)
+	if err != nil {
+		return err
+	}
+
+	if !identifierRegexp.MatchString(name) {
+		return errors.New(fmt.Sprintf("dbhelper: wrong partition name '%s'", name))
+	}
+
+	_, err = dbh.execRaw(sqld.CreatePartition(dbh.quote(tbl.name), dbh.quote(name), bound))
+	return err
+}
+
+// AttachPartition attaches existing table name as a partition of range
+// partitioned table assigned to type of i, containing values of partition
+// column from (inclusive) to (exclusive).
+func (dbh *DbHelper) AttachPartition(i interface{}, name string, from, to interface{}) error {
+	bound, err := rangeBound(from, to)
+	if err != nil {
+		return err
+	}
+
+	tbl, sqld, err := dbh.getPartitionedTable(i)
+	if err != nil {
+		return err
+	}
+
+	if !identifierRegexp.MatchString(name) {
+		return errors.New(fmt.Sprintf("dbhelper: wrong partition name '%s'", name))
+	}
+
+	_, err = dbh.execRaw(sqld.AttachPartition(dbh.quote(tbl.name), dbh.quote(name), bound))
+	return err
+}
+
+// DetachPartition detaches partition name from partitioned table assigned
+// to type of i. Detached partition remains a standalone table.
+func (dbh *DbHelper) DetachPartition(i interface{}, name string) error {
+	tbl, sqld, err := dbh.getPartitionedTable(i)
+	if err != nil {
+		return err
+	}
+
+	if !identifierRegexp.MatchString(name) {
+		return errors.New(fmt.Sprintf("dbhelper: wrong partition name '%s'", name))
+	}
+
+	_, err = dbh.execRaw(sqld.DetachPartition(dbh.quote(tbl.name), dbh.quote(name)))
+	return err
+}
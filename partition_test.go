@@ -0,0 +1,69 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPartitionQueries(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, sqld, err := dbh.getPartitionedTable(testStruct{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	query := tbl.createPartitionedTableQuery(sqld, PartitionByRange, "c")
+	if !strings.HasSuffix(query, ", PRIMARY KEY (id, c)) PARTITION BY RANGE (c)") || strings.Contains(query, "bigserial PRIMARY KEY") {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+
+	bound, err := rangeBound(time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2015, 2, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if bound != "FOR VALUES FROM ('2015-01-01 00:00:00+00:00') TO ('2015-02-01 00:00:00+00:00')" {
+		t.Errorf("unexpected bound: %s", bound)
+		return
+	}
+
+	bound, err = listBound([]interface{}{1, "it's"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if bound != "FOR VALUES IN (1, 'it''s')" {
+		t.Errorf("unexpected bound: %s", bound)
+		return
+	}
+
+	// partitions are not supported
+	dbh = New(nil, MySql{})
+	err = dbh.CreatePartition(testStruct{}, "test_2015", 2015, 2016)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+// DefaultMaxPreparedQueries is the limit of prepared queries generated for
+// each table, which is used if DbHelper.MaxPreparedQueries is zero.
+const DefaultMaxPreparedQueries = 1000
+
+// Shape of request generating a query for the table: operation and its
+// options, e.g. updated columns. Requests of the same shape generate the
+// same query, so it is generated and prepared once.
+type planKey struct {
+	op      string
+	options string
+}
+
+// Returns true if another generated query can be prepared and cached.
+// Must be called with locked mutex.
+func (tbl *dbTable) canPrepare() bool {
+	limit := tbl.dbHelper.MaxPreparedQueries
+	if limit == 0 {
+		limit = DefaultMaxPreparedQueries
+	}
+
+	return limit < 0 || len(tbl.queries) < limit
+}
+
+// Returns prepared query generated for the table. Queries are prepared on
+// first use and cached by their text. When MaxPreparedQueries is reached,
+// returned query is not cached and is executed as text.
+func (tbl *dbTable) getQuery(query string) (*Pstmt, error) {
+	tbl.mutex.Lock()
+	defer tbl.mutex.Unlock()
+
+	if q, ok := tbl.queries[query]; ok {
+		return q, nil
+	}
+
+	if !tbl.canPrepare() {
+		return tbl.dbHelper.direct(query)
+	}
+
+	q, err := tbl.dbHelper.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	tbl.queries[query] = q
+	return q, nil
+}
+
+// Returns prepared query for request of operation op with options, which
+// is generated by build only for new shapes of requests. Options must
+// define the query completely, e.g. list updated columns in stable order.
+func (tbl *dbTable) getPlan(op string, options string, build func() (string, error)) (*Pstmt, error) {
+	key := planKey{op, options}
+
+	tbl.mutex.Lock()
+	q, ok := tbl.plans[key]
+	tbl.mutex.Unlock()
+	if ok {
+		return q, nil
+	}
+
+	// generate query
+	query, err := build()
+	if err != nil {
+		return nil, err
+	}
+
+	q, err = tbl.getQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// queries executed as text are not cached
+	if q.stmt != nil {
+		tbl.mutex.Lock()
+		tbl.plans[key] = q
+		tbl.mutex.Unlock()
+	}
+
+	return q, nil
+}
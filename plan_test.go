@@ -0,0 +1,85 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+func TestQueryPlans(t *testing.T) {
+	result := &testResult{
+		columns: []string{"id"},
+		rows:    [][]driver.Value{{int64(1)}, {int64(2)}},
+	}
+
+	dbh := newTestDriverDb("SELECT * FROM test_plan WHERE id IN ($1, $2)", result)
+	newTestDriverDb("SELECT * FROM test_plan WHERE id IN ($1, $2, $3)", result)
+
+	err := dbh.AddTable(testStruct{}, "test_plan")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, _ := dbh.getTable(reflect.TypeOf(testStruct{}))
+
+	// queries of the same shape are prepared once
+	for _, ids := range [][]interface{}{{1, 2}, {3, 4}} {
+		var list []*testStruct
+		num, err := dbh.Select(testStruct{}).Where(In("id", ids...)).Query(&list)
+		if err != nil || num != 2 {
+			t.Errorf("unexpected result: %d, %v", num, err)
+			return
+		}
+	}
+
+	if len(tbl.queries) != 1 {
+		t.Errorf("one prepared query expected: %d", len(tbl.queries))
+		return
+	}
+
+	// queries above the limit are executed as text
+	dbh.MaxPreparedQueries = 1
+
+	var list []*testStruct
+	num, err := dbh.Select(testStruct{}).Where(In("id", 1, 2, 3)).Query(&list)
+	if err != nil || num != 2 {
+		t.Errorf("unexpected result: %d, %v", num, err)
+		return
+	}
+
+	if len(tbl.queries) != 1 {
+		t.Errorf("query above the limit is cached: %d", len(tbl.queries))
+		return
+	}
+
+	// plans are generated once for each shape
+	dbh.MaxPreparedQueries = 0
+	built := 0
+	for n := 0; n < 2; n++ {
+		_, err = tbl.getPlan("test", "ids=2", func() (string, error) {
+			built++
+			return "SELECT * FROM test_plan WHERE id IN (:a, :b)", nil
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if built != 1 || len(tbl.plans) != 1 || len(tbl.queries) != 2 {
+		t.Errorf("query must be generated once: %d, %d, %d", built, len(tbl.plans), len(tbl.queries))
+		return
+	}
+}
@@ -12,17 +12,204 @@
 package dbhelper
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 )
 
+// Parameter values in the order of parameters of prepared statement.
+type orderedParams []interface{}
+
+// Pool of slices used to scan rows and to pass parameter values.
+var valueBuffers = sync.Pool{
+	New: func() interface{} {
+		return new([]interface{})
+	},
+}
+
+// Returns slice of length n from the pool.
+func getValueBuffer(n int) *[]interface{} {
+	buf := valueBuffers.Get().(*[]interface{})
+	if cap(*buf) < n {
+		*buf = make([]interface{}, n)
+	}
+
+	*buf = (*buf)[:n]
+	return buf
+}
+
+// Returns slice to the pool.
+func putValueBuffer(buf *[]interface{}) {
+	// do not hold references to values
+	for i := range *buf {
+		(*buf)[i] = nil
+	}
+
+	valueBuffers.Put(buf)
+}
+
+// NullPolicy defines how NULL values are scanned to fields which cannot
+// store NULL, e.g. int64 or string.
+type NullPolicy int
+
+const (
+	// NULL value is an error naming the column and the field.
+	NullError NullPolicy = iota
+
+	// Field gets zero value.
+	NullZeroValue
+)
+
+// QueryError is returned when database fails to execute prepared statement
+// or to return its results. It contains the query and names of its
+// parameters, values of parameters are not included.
+type QueryError struct {
+	// Query as it was passed to Prepare.
+	Query string
+
+	// Names of parameters of the query.
+	Params []string
+
+	// Error returned by database/sql.
+	Err error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("dbhelper: %v (query: %s; parameters: %s)", e.Err, e.Query, strings.Join(e.Params, ", "))
+}
+
+// Unwrap returns error returned by database/sql.
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
 // Contains prepared statement ready for execution.
 type Pstmt struct {
 	dbHelper *DbHelper
 	params   []string
-	stmt     *sql.Stmt
+
+	// Prepared statement, nil if query is executed as text.
+	stmt *sql.Stmt
+
+	// Transaction in which statement was prepared, nil if it was prepared
+	// on database.
+	tx *sql.Tx
+
+	// Parameters are positional, their names are numbers starting from 1.
+	positional bool
+
+	// Query as it was passed to Prepare.
+	query string
+
+	// Query with placeholders of SQL dialect, as it was prepared.
+	prepared string
+
+	// Columns of query result mapped to other fields, set by WithAliases.
+	aliases map[string]string
+}
+
+// Returns prepared statement that will be executed using dbh.
+// Used to execute statements prepared for tables within a transaction.
+func (pstmt *Pstmt) in(dbh *DbHelper) *Pstmt {
+	if pstmt.dbHelper == dbh {
+		return pstmt
+	}
+
+	return &Pstmt{
+		dbHelper:   dbh,
+		params:     pstmt.params,
+		stmt:       pstmt.stmt,
+		tx:         pstmt.tx,
+		positional: pstmt.positional,
+		query:      pstmt.query,
+		prepared:   pstmt.prepared,
+		aliases:    pstmt.aliases,
+	}
+}
+
+// WithAliases returns prepared statement which maps columns of query result
+// to fields of structures using aliases: keys are column names and values
+// are mapped column names or Go field names. Used for queries which column
+// names do not match tags, e.g. "SELECT COUNT(*) AS n", instead of failing
+// on unknown columns. Prepared statement is shared, so it must be closed once.
+func (pstmt *Pstmt) WithAliases(aliases map[string]string) *Pstmt {
+	p := *pstmt
+	p.aliases = aliases
+	return &p
+}
+
+// Params returns names of parameters in the order of placeholders. Names of
+// positional parameters are numbers starting from 1.
+func (pstmt *Pstmt) Params() []string {
+	return append([]string(nil), pstmt.params...)
+}
+
+// SQL returns query as it was prepared, with placeholders of SQL dialect
+// instead of parameters.
+func (pstmt *Pstmt) SQL() string {
+	return pstmt.prepared
+}
+
+// Returns statement for execution. If DbHelper is bound to a transaction,
+// returned statement is transaction-specific. Returns nil if statement was
+// prepared in another transaction, which may be already finished, so query
+// must be executed as text.
+func (pstmt *Pstmt) getStmt() *sql.Stmt {
+	if pstmt.stmt == nil {
+		return nil
+	}
+
+	if pstmt.tx != nil {
+		if pstmt.tx != pstmt.dbHelper.tx {
+			return nil
+		}
+
+		return pstmt.stmt
+	}
+
+	if pstmt.dbHelper.tx != nil {
+		return pstmt.dbHelper.tx.Stmt(pstmt.stmt)
+	}
+
+	return pstmt.stmt
+}
+
+// Executes statement with values. Queries with tags and queries which are
+// not prepared are executed as text.
+func (pstmt *Pstmt) execValues(ctx context.Context, values []interface{}) (sql.Result, error) {
+	comment := pstmt.dbHelper.tagComment(ctx)
+	if comment == "" {
+		if stmt := pstmt.getStmt(); stmt != nil {
+			return stmt.ExecContext(ctx, values...)
+		}
+	}
+
+	if pstmt.dbHelper.tx != nil {
+		return pstmt.dbHelper.tx.ExecContext(ctx, comment+pstmt.prepared, values...)
+	}
+
+	return pstmt.dbHelper.Db.ExecContext(ctx, comment+pstmt.prepared, values...)
+}
+
+// Performs query with values. Queries with tags and queries which are not
+// prepared are executed as text.
+func (pstmt *Pstmt) queryValues(ctx context.Context, values []interface{}) (*sql.Rows, error) {
+	comment := pstmt.dbHelper.tagComment(ctx)
+	if comment == "" {
+		if stmt := pstmt.getStmt(); stmt != nil {
+			return stmt.QueryContext(ctx, values...)
+		}
+	}
+
+	if pstmt.dbHelper.tx != nil {
+		return pstmt.dbHelper.tx.QueryContext(ctx, comment+pstmt.prepared, values...)
+	}
+
+	return pstmt.dbHelper.Db.QueryContext(ctx, comment+pstmt.prepared, values...)
 }
 
 // Returns a list of values for query parameters
@@ -42,6 +229,20 @@ func (pstmt *Pstmt) getValues(params interface{}) ([]interface{}, error) {
 		}
 	}
 
+	// values are already in correct order
+	if ordered, ok := params.(orderedParams); ok {
+		if len(ordered) != num {
+			return nil, errors.New(fmt.Sprintf("dbhelper: query has %d parameters, %d values provided", num, len(ordered)))
+		}
+
+		return ordered, nil
+	}
+
+	// positional values
+	if list, ok := params.([]interface{}); ok && pstmt.positional {
+		return pstmt.getPositionalValues(list)
+	}
+
 	// slice containing values
 	values := make([]interface{}, num, num)
 
@@ -60,7 +261,15 @@ func (pstmt *Pstmt) getValues(params interface{}) ([]interface{}, error) {
 				return nil, errors.New(fmt.Sprintf("dbhelper: value for parameter '%s' is missing", p))
 			}
 
-			values[i] = v.Interface()
+			values[i] = paramValue(v.Interface())
+		}
+
+		// check that all values are used
+		if !pstmt.dbHelper.AllowUnknownParams && paramsValue.Len() > 0 {
+			err := pstmt.checkUnknownParams(paramsValue)
+			if err != nil {
+				return nil, err
+			}
 		}
 	} else {
 		if num > 1 {
@@ -71,29 +280,49 @@ func (pstmt *Pstmt) getValues(params interface{}) ([]interface{}, error) {
 			return nil, errors.New(fmt.Sprintf("dbhelper: wrong parameter type '%v'", paramsType))
 		}
 
-		values[0] = paramsValue.Interface()
+		values[0] = paramValue(paramsValue.Interface())
 	}
 
 	return values, nil
 }
 
-func (pstmt *Pstmt) exec(params interface{}) (sql.Result, error) {
+// Returns error if params map contains keys that are not parameters of the query.
+func (pstmt *Pstmt) checkUnknownParams(paramsValue reflect.Value) error {
+	known := make(map[string]bool, len(pstmt.params))
+	for _, p := range pstmt.params {
+		known[p] = true
+	}
+
+	for _, key := range paramsValue.MapKeys() {
+		if key.Kind() != reflect.String || !known[key.String()] {
+			return errors.New(fmt.Sprintf("dbhelper: query has no parameter '%v'", key.Interface()))
+		}
+	}
+
+	return nil
+}
+
+func (pstmt *Pstmt) exec(ctx context.Context, params interface{}) (sql.Result, error) {
+	// refuse to modify data
+	err := pstmt.checkReadOnly(true)
+	if err != nil {
+		return nil, err
+	}
+
 	// get parameter values for query
 	values, err := pstmt.getValues(params)
 	if err != nil {
 		return nil, err
 	}
 
-	// execute query
-	var res sql.Result
-	if values != nil {
-		res, err = pstmt.stmt.Exec(values...)
-	} else {
-		res, err = pstmt.stmt.Exec()
-	}
+	// apply default timeout
+	ctx, cancel := pstmt.dbHelper.withTimeout(ctx)
+	defer cancel()
 
+	// execute query
+	res, err := pstmt.execValues(ctx, values)
 	if err != nil {
-		return nil, wrapError(err)
+		return nil, pstmt.queryError(err)
 	}
 
 	return res, nil
@@ -104,8 +333,13 @@ func (pstmt *Pstmt) exec(params interface{}) (sql.Result, error) {
 // If query has more than one parameter, params must be a map[string]interface{}.
 // Returns number of affected rows or -1 if this number cannot be obtained.
 func (pstmt *Pstmt) Exec(params interface{}) (int64, error) {
+	return pstmt.ExecContext(pstmt.dbHelper.context(), params)
+}
+
+// ExecContext is like Exec, but uses context ctx for execution.
+func (pstmt *Pstmt) ExecContext(ctx context.Context, params interface{}) (int64, error) {
 	// execute query
-	res, err := pstmt.exec(params)
+	res, err := pstmt.exec(ctx, params)
 	if err != nil {
 		return 0, err
 	}
@@ -127,66 +361,148 @@ func (pstmt *Pstmt) Exec(params interface{}) (int64, error) {
 // If query has only one parameter, params can be the value of that parameter.
 // If query has more than one parameter, params must be a map[string]interface{}.
 func (pstmt *Pstmt) Query(i interface{}, params interface{}) (int64, error) {
+	return pstmt.QueryContext(pstmt.dbHelper.context(), i, params)
+}
+
+// QueryContext is like Query, but uses context ctx for execution.
+func (pstmt *Pstmt) QueryContext(ctx context.Context, i interface{}, params interface{}) (int64, error) {
+	return pstmt.queryTo(ctx, i, params, nil)
+}
+
+// QueryMeta describes result of query returned by Pstmt.QueryMeta.
+type QueryMeta struct {
+	// Columns of query result.
+	Columns []string
+
+	// Number of mapped rows.
+	Rows int64
+
+	// Number of rows which were not mapped, because only the first row is
+	// mapped to a structure or value.
+	Skipped int64
+
+	// Result has more rows than MaxRows allows, in which case ErrMaxRows is
+	// returned together with QueryMeta.
+	Truncated bool
+}
+
+// QueryMeta is like Query, but returns description of query result. Unlike
+// Query, it reads all rows of result to count skipped rows. Description is
+// also returned with ErrMaxRows and ErrMultipleRows.
+func (pstmt *Pstmt) QueryMeta(i interface{}, params interface{}) (*QueryMeta, error) {
+	return pstmt.QueryMetaContext(pstmt.dbHelper.context(), i, params)
+}
+
+// QueryMetaContext is like QueryMeta, but uses context ctx for execution.
+func (pstmt *Pstmt) QueryMetaContext(ctx context.Context, i interface{}, params interface{}) (*QueryMeta, error) {
+	meta := &QueryMeta{}
+	_, err := pstmt.queryTo(ctx, i, params, meta)
+	if err != nil && err != ErrMaxRows && err != ErrMultipleRows {
+		return nil, err
+	}
+
+	return meta, err
+}
+
+// Performs query, filling meta if it is not nil.
+func (pstmt *Pstmt) queryTo(ctx context.Context, i interface{}, params interface{}, meta *QueryMeta) (int64, error) {
 	if i == nil {
 		return 0, errorNil
 	}
 
-	var err error
-	returnSlice := false
-	returnStruct := false
-
 	// get pointer to slice value
 	slicePtrValue := reflect.ValueOf(i)
-	slicePtrType := slicePtrValue.Type()
+	if slicePtrValue.Kind() == reflect.Ptr && slicePtrValue.IsNil() {
+		return 0, errors.New("dbhelper: cannot use pointer to nil")
+	}
 
-	if slicePtrType.Kind() != reflect.Ptr {
-		return 0, errors.New("dbhelper: pointer expected")
+	shape, err := pstmt.dbHelper.resultShapeOf(slicePtrValue.Type())
+	if err != nil {
+		return 0, err
 	}
 
-	// get slice value
-	sliceValue := slicePtrValue.Elem()
-	if !sliceValue.IsValid() {
-		return 0, errors.New("dbhelper: cannot use pointer to nil")
+	return pstmt.queryShape(ctx, shape, slicePtrValue, params, meta)
+}
+
+// Destination of query results: pointer to a slice of pointers or pointer
+// to a single structure or value.
+type resultShape struct {
+	// Rows are appended to slice.
+	slice bool
+
+	// Type of slice.
+	sliceType reflect.Type
+
+	// Type of value of a row, structure or scalar.
+	rowType reflect.Type
+
+	// Table or mapping of structure, nil for scalars.
+	tbl *dbTable
+
+	// Optional function called for each row of a single structure
+	// destination instead of stopping after the first row. The same
+	// structure is reused for all rows.
+	each func(v reflect.Value) error
+}
+
+// Returns shape of destination of query results of type ptrType.
+func (dbh *DbHelper) resultShapeOf(ptrType reflect.Type) (*resultShape, error) {
+	if ptrType.Kind() != reflect.Ptr {
+		return nil, errors.New("dbhelper: pointer expected")
 	}
 
 	// get slice type
-	sliceType := sliceValue.Type()
+	sliceType := ptrType.Elem()
 	if sliceType.Kind() == reflect.Ptr {
-		return 0, errors.New("dbhelper: cannot use pointer to pointer")
+		return nil, errors.New("dbhelper: cannot use pointer to pointer")
 	}
 
 	if sliceType.Kind() == reflect.Interface {
-		return 0, errors.New("dbhelper: wrong type of i")
+		return nil, errors.New("dbhelper: wrong type of i")
 	}
 
 	// get return pointer type
-	var returnPtrType reflect.Type
+	shape := &resultShape{}
+	returnPtrType := ptrType
 	if sliceType.Kind() == reflect.Slice {
 		// return slice of pointers to structs
-		returnSlice = true
+		shape.slice = true
+		shape.sliceType = sliceType
 		returnPtrType = sliceType.Elem()
 
 		if returnPtrType.Kind() != reflect.Ptr {
-			return 0, errors.New("dbhelper: pointer to a slice of pointers expected")
+			return nil, errors.New("dbhelper: pointer to a slice of pointers expected")
 		}
-	} else {
-		// return pointer
-		returnPtrType = slicePtrType
 	}
 
-	// get return type
-	returnType := returnPtrType.Elem()
-	if returnType.Kind() == reflect.Struct {
-		returnStruct = true
-	}
-
-	// get table
-	var tbl *dbTable
-	if returnStruct {
-		tbl, err = pstmt.dbHelper.getTable(returnType)
+	// get table or mapping of structure that is not registered
+	shape.rowType = returnPtrType.Elem()
+	if shape.rowType.Kind() == reflect.Struct {
+		tbl, err := dbh.getMapping(shape.rowType)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
+
+		shape.tbl = tbl
+	}
+
+	return shape, nil
+}
+
+// Performs query, storing results to destination of shape pointed by
+// slicePtrValue and filling meta if it is not nil.
+func (pstmt *Pstmt) queryShape(ctx context.Context, shape *resultShape, slicePtrValue reflect.Value,
+	params interface{}, meta *QueryMeta) (int64, error) {
+	returnSlice := shape.slice
+	returnStruct := shape.tbl != nil
+	returnType := shape.rowType
+	sliceValue := slicePtrValue.Elem()
+	tbl := shape.tbl
+
+	// refuse to modify data
+	err := pstmt.checkReadOnly(false)
+	if err != nil {
+		return 0, err
 	}
 
 	// get parameter values for query
@@ -195,16 +511,14 @@ func (pstmt *Pstmt) Query(i interface{}, params interface{}) (int64, error) {
 		return 0, err
 	}
 
-	// perform query
-	var rows *sql.Rows
-	if values != nil {
-		rows, err = pstmt.stmt.Query(values...)
-	} else {
-		rows, err = pstmt.stmt.Query()
-	}
+	// apply default timeout
+	ctx, cancel := pstmt.dbHelper.withTimeout(ctx)
+	defer cancel()
 
+	// perform query
+	rows, err := pstmt.queryValues(ctx, values)
 	if err != nil {
-		return 0, wrapError(err)
+		return 0, pstmt.queryError(err)
 	}
 
 	// close rows on exit
@@ -212,18 +526,51 @@ func (pstmt *Pstmt) Query(i interface{}, params interface{}) (int64, error) {
 
 	// create slice
 	if returnSlice {
-		sliceValue.Set(reflect.MakeSlice(sliceType, 0, 10))
+		sliceValue.Set(reflect.MakeSlice(shape.sliceType, 0, 10))
 	}
 
 	// get column names
 	columns, err := rows.Columns()
 	if err != nil {
-		return 0, wrapError(err)
+		return 0, pstmt.queryError(err)
+	}
+
+	if meta != nil {
+		meta.Columns = columns
+	}
+
+	// resolve fields of the structure corresponding to columns once per query
+	var plan []*dbField
+	if returnStruct {
+		plan, err = tbl.scanPlan(columns, pstmt.aliases)
+		if err != nil {
+			return 0, err
+		}
 	}
 
+	// fields masked for the reader
+	masked := maskedFields(ctx, plan)
+
+	// slice containing pointers to corresponding fields of the structure
+	buf := getValueBuffer(len(columns))
+	defer putValueBuffer(buf)
+	fields := *buf
+
 	// read rows data to structures
 	num := int64(0)
+	skipped := int64(0)
 	for rows.Next() {
+		// limit number of rows
+		if returnSlice && pstmt.dbHelper.MaxRows > 0 && num == pstmt.dbHelper.MaxRows {
+			if meta != nil {
+				meta.Truncated = true
+				meta.Rows = num
+			}
+
+			// slice keeps rows read so far
+			return num, ErrMaxRows
+		}
+
 		// create new structure and get a pointer to it
 		var returnPtrValue reflect.Value
 		if returnSlice {
@@ -235,21 +582,30 @@ func (pstmt *Pstmt) Query(i interface{}, params interface{}) (int64, error) {
 		// get new structure value
 		returnValue := returnPtrValue.Elem()
 
-		if returnStruct {
-			// slice containing pointers to corresponding fields of the structure
-			fields := make([]interface{}, tbl.numField, tbl.numField)
+		// reused structure must not keep values of the previous row
+		if shape.each != nil {
+			returnValue.Set(reflect.Zero(returnType))
+		}
 
+		if returnStruct {
 			// fill slice with pointers
-			for i, col := range columns {
-				// get field in new structure
-				v := returnValue.FieldByIndex(tbl.fields[col].index)
-
-				// append pointer to field to slice
-				fields[i] = v.Addr().Interface()
+			for i, f := range plan {
+				fields[i] = f.dest(returnValue)
 			}
 
 			// scan row and assign values to struct fields
 			err = rows.Scan(fields...)
+			if err != nil {
+				// columns with NULL values
+				err = pstmt.scanNulls(rows, err, plan, returnValue)
+				if err != nil {
+					return 0, err
+				}
+			}
+
+			for _, f := range masked {
+				f.setMasked(returnValue)
+			}
 		} else {
 			// scan row and assign return value
 			err = rows.Scan(returnValue.Addr().Interface())
@@ -257,7 +613,7 @@ func (pstmt *Pstmt) Query(i interface{}, params interface{}) (int64, error) {
 
 		// check scan error
 		if err != nil {
-			return 0, wrapError(err)
+			return 0, pstmt.queryError(err)
 		}
 
 		num++
@@ -265,10 +621,107 @@ func (pstmt *Pstmt) Query(i interface{}, params interface{}) (int64, error) {
 		if returnSlice {
 			// append pointer to slice
 			sliceValue.Set(reflect.Append(sliceValue, returnPtrValue))
+		} else if shape.each != nil {
+			// row is processed before the next one is scanned
+			err = shape.each(returnValue)
+			if err != nil {
+				return num, err
+			}
 		} else {
+			// other rows are only counted
+			if meta != nil || pstmt.dbHelper.StrictSingleRow {
+				for rows.Next() {
+					skipped++
+				}
+			}
+
 			break
 		}
 	}
 
+	// error which stopped iteration
+	err = rows.Err()
+	if err != nil {
+		return 0, pstmt.queryError(err)
+	}
+
+	if meta != nil {
+		meta.Rows = num
+		meta.Skipped = skipped
+	}
+
+	// query matched more rows than expected
+	if skipped > 0 && pstmt.dbHelper.StrictSingleRow {
+		return num + skipped, ErrMultipleRows
+	}
+
 	return num, nil
 }
+
+// Scans current row again after scan error err to find NULL values of
+// columns mapped to fields which cannot store NULL, and handles them
+// according to NullPolicy. Returns err if there are no such columns.
+func (pstmt *Pstmt) scanNulls(rows *sql.Rows, err error, plan []*dbField, v reflect.Value) error {
+	// fields which cannot store NULL are scanned to pointers
+	ptrs := make([]reflect.Value, len(plan))
+	fields := make([]interface{}, len(plan))
+	for i, f := range plan {
+		if f.nullable() {
+			fields[i] = f.dest(v)
+			continue
+		}
+
+		ptrs[i] = reflect.New(reflect.PtrTo(f.typ))
+		fields[i] = ptrs[i].Interface()
+	}
+
+	if rows.Scan(fields...) != nil {
+		return pstmt.queryError(err)
+	}
+
+	found := false
+	for i, ptr := range ptrs {
+		if !ptr.IsValid() {
+			continue
+		}
+
+		f := plan[i]
+		if !ptr.Elem().IsNil() {
+			f.value(v).Set(ptr.Elem().Elem())
+			continue
+		}
+
+		if pstmt.dbHelper.NullPolicy != NullZeroValue {
+			return errors.New(fmt.Sprintf("dbhelper: column '%s' is NULL, field '%s' of structure type '%v' cannot store NULL",
+				f.column, f.name, v.Type()))
+		}
+
+		f.value(v).Set(reflect.Zero(f.typ))
+		found = true
+	}
+
+	if !found {
+		return pstmt.queryError(err)
+	}
+
+	return nil
+}
+
+// Returns QueryError for error err of the statement.
+func (pstmt *Pstmt) queryError(err error) error {
+	return &QueryError{
+		Query:  pstmt.query,
+		Params: pstmt.params,
+		Err:    err,
+	}
+}
+
+// Closes prepared statement.
+func (pstmt *Pstmt) Close() error {
+	err := pstmt.stmt.Close()
+	if err != nil {
+		return wrapError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,561 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetValuesUnknownParams(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	pstmt := &Pstmt{
+		dbHelper: dbh,
+		params:   []string{"id", "text"},
+	}
+
+	values, err := pstmt.getValues(map[string]interface{}{
+		"text": "abc",
+		"id":   1,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(values) != 2 || values[0] != 1 || values[1] != "abc" {
+		t.Errorf("unexpected values: %v", values)
+		return
+	}
+
+	// misspelled parameter name
+	params := map[string]interface{}{
+		"id":   1,
+		"text": "abc",
+		"txet": "abc",
+	}
+
+	_, err = pstmt.getValues(params)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	dbh.AllowUnknownParams = true
+	_, err = pstmt.getValues(params)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+
+	query, params, err := dbh.parseParams("SELECT * FROM test WHERE c > $2 AND text = $1", OrdinalParams)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if query != "SELECT * FROM test WHERE c > $1 AND text = $2" {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+
+	pstmt := &Pstmt{
+		dbHelper:   dbh,
+		params:     params,
+		positional: true,
+	}
+
+	values, err := pstmt.getValues([]interface{}{"abc", 123})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if values[0] != 123 || values[1] != "abc" {
+		t.Errorf("unexpected values: %v", values)
+		return
+	}
+
+	query, params, err = dbh.parseParams("SELECT * FROM test WHERE c > ? AND text = ?", QuestionParams)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if query != "SELECT * FROM test WHERE c > $1 AND text = $2" || len(params) != 2 {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+
+	// quoted sections are not changed
+	query, params, err = dbh.parseParams(`SELECT '?', 'it''s ?' AS "a?" FROM test WHERE c > ? AND text = ?`, QuestionParams)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if query != `SELECT '?', 'it''s ?' AS "a?" FROM test WHERE c > $1 AND text = $2` || len(params) != 2 {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+
+	query, params, err = dbh.parseParams("SELECT '$1' FROM test WHERE text = $1", OrdinalParams)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if query != "SELECT '$1' FROM test WHERE text = $1" || len(params) != 1 {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+}
+
+func TestWithAliases(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	tbl, err := dbh.getMapping(reflect.TypeOf(testStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	columns := []string{"record_id", "flag", "m"}
+	_, err = tbl.scanPlan(columns, nil)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	// aliases are column or field names
+	pstmt := (&Pstmt{dbHelper: dbh}).WithAliases(map[string]string{"record_id": "id", "flag": "Bool"})
+	plan, err := tbl.scanPlan(columns, pstmt.in(New(nil, Postgresql{})).aliases)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if plan[0].column != "id" || plan[1].column != "b" || plan[2].column != "m" {
+		t.Errorf("unexpected columns: %s, %s, %s", plan[0].column, plan[1].column, plan[2].column)
+		return
+	}
+
+	// unknown alias
+	_, err = tbl.scanPlan(columns, map[string]string{"record_id": "Missing", "flag": "b"})
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestIgnoreColumnCase(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	tbl, err := dbh.getMapping(reflect.TypeOf(testStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	columns := []string{"ID", "Text"}
+	_, err = tbl.scanPlan(columns, nil)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	dbh.IgnoreColumnCase = true
+	plan, err := tbl.scanPlan(columns, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if plan[0].column != "id" || plan[1].column != "text" {
+		t.Errorf("unexpected columns: %s, %s", plan[0].column, plan[1].column)
+		return
+	}
+}
+
+func TestNullPolicy(t *testing.T) {
+	query := "SELECT id, text FROM test_null"
+	dbh := newTestDriverDb(query, &testResult{
+		columns: []string{"id", "text"},
+		rows:    [][]driver.Value{{int64(1), "a"}, {int64(2), nil}},
+	})
+
+	q, err := dbh.Prepare(query)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	var list []*testStruct
+	_, err = q.Query(&list, nil)
+	if err == nil || !strings.Contains(err.Error(), "'text'") {
+		t.Errorf("error naming the column expected: %v", err)
+		return
+	}
+
+	dbh.NullPolicy = NullZeroValue
+	num, err := q.Query(&list, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if num != 2 || list[0].Text != "a" || list[1].Id != 2 || list[1].Text != "" {
+		t.Errorf("unexpected records: %v, %v", list[0], list[1])
+		return
+	}
+}
+
+func TestQueryRowsError(t *testing.T) {
+	query := "SELECT id, text FROM test_rows"
+	result := &testResult{
+		columns: []string{"id", "text"},
+		rows:    [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}, {int64(3), "c"}},
+		err:     errors.New("connection reset"),
+	}
+
+	dbh := newTestDriverDb(query, result)
+	q, err := dbh.Prepare(query)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	// error after the last row is not swallowed
+	var list []*testStruct
+	_, err = q.Query(&list, nil)
+	if err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("error of iteration expected: %v", err)
+		return
+	}
+
+	result.err = nil
+	dbh.MaxRows = 2
+	list = nil
+	num, err := q.Query(&list, nil)
+	if err != ErrMaxRows || num != 2 || len(list) != 2 {
+		t.Errorf("ErrMaxRows expected: %d, %d, %v", num, len(list), err)
+		return
+	}
+
+	// limit does not apply to single records
+	var record testStruct
+	num, err = q.Query(&record, nil)
+	if err != nil || num != 1 || record.Id != 1 {
+		t.Errorf("unexpected result: %d, %v, %v", num, record, err)
+		return
+	}
+
+	dbh.MaxRows = 3
+	num, err = q.Query(&list, nil)
+	if err != nil || num != 3 {
+		t.Errorf("unexpected result: %d, %v", num, err)
+		return
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	query := "SELECT id, text FROM test_error WHERE id = :id"
+	dbh := newTestDriverDb("SELECT id, text FROM test_error WHERE id = $1", &testResult{
+		columns: []string{"id", "text"},
+		rows:    [][]driver.Value{{int64(1), "a"}},
+		err:     errors.New("connection reset"),
+	})
+
+	q, err := dbh.Prepare(query)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	var list []*testStruct
+	_, err = q.Query(&list, "secret")
+
+	var qe *QueryError
+	if !errors.As(err, &qe) {
+		t.Errorf("QueryError expected: %v", err)
+		return
+	}
+
+	if qe.Query != query || len(qe.Params) != 1 || qe.Params[0] != "id" || qe.Err.Error() != "connection reset" {
+		t.Errorf("unexpected error: %#v", qe)
+		return
+	}
+
+	// values of parameters are not included
+	if strings.Contains(err.Error(), "secret") || !strings.Contains(err.Error(), query) {
+		t.Errorf("unexpected message: %v", err)
+		return
+	}
+}
+
+func TestBeginOptions(t *testing.T) {
+	dbh := newTestDriverDb("SELECT 1", &testResult{})
+	dbh.IgnoreColumnCase = true
+	dbh.NullPolicy = NullZeroValue
+	dbh.MaxRows = 10
+	dbh.SecretHasher = testHasher{}
+
+	txh, err := dbh.Begin()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer txh.Rollback()
+
+	if !txh.IgnoreColumnCase || txh.NullPolicy != NullZeroValue || txh.MaxRows != 10 || txh.SecretHasher == nil {
+		t.Error("options are not copied to transaction")
+		return
+	}
+}
+
+func TestBeginWith(t *testing.T) {
+	dbh := newTestDriverDb("SET LOCAL statement_timeout = 1500", &testResult{})
+	newTestDriverDb("SET LOCAL lock_timeout = 200", &testResult{})
+
+	txh, err := dbh.BeginWith(TxOptions{StatementTimeout: 1500 * time.Millisecond, LockTimeout: 200 * time.Millisecond})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = txh.Commit()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// DefaultTimeout is not set on the server
+	dbh.DefaultTimeout = 2 * time.Second
+	txh, err = dbh.BeginWith(TxOptions{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	txh.Rollback()
+
+	// unknown timeout statement fails
+	_, err = dbh.BeginWith(TxOptions{LockTimeout: time.Second})
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	// not supported by dialect
+	_, err = New(nil, Sqlite{}).BeginWith(TxOptions{LockTimeout: time.Second})
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestExecQuery(t *testing.T) {
+	dbh := newTestDriverDb("SELECT text FROM test_once WHERE id = $1", &testResult{
+		columns: []string{"text"},
+		rows:    [][]driver.Value{{"a"}},
+	})
+	newTestDriverDb("DELETE FROM test_once WHERE id = $1", &testResult{
+		rows: [][]driver.Value{{}, {}},
+	})
+
+	var text string
+	num, err := dbh.Query(&text, "SELECT text FROM test_once WHERE id = :id", 1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if num != 1 || text != "a" {
+		t.Errorf("unexpected result: %d, %s", num, text)
+		return
+	}
+
+	num, err = dbh.Exec("DELETE FROM test_once WHERE id = :id", 1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if num != 2 {
+		t.Errorf("unexpected number of affected rows: %d", num)
+		return
+	}
+}
+
+func TestExecDirect(t *testing.T) {
+	dbh := newTestDriverDb("SELECT text FROM test_direct WHERE id = $1", &testResult{
+		columns: []string{"text"},
+		rows:    [][]driver.Value{{"a"}},
+	})
+	newTestDriverDb("DELETE FROM test_direct WHERE id = $1", &testResult{
+		rows: [][]driver.Value{{}},
+	})
+
+	var text string
+	num, err := dbh.QueryDirect(&text, "SELECT text FROM test_direct WHERE id = :id", 1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if num != 1 || text != "a" {
+		t.Errorf("unexpected result: %d, %s", num, text)
+		return
+	}
+
+	num, err = dbh.ExecDirect("DELETE FROM test_direct WHERE id = :id", 1)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if num != 1 {
+		t.Errorf("unexpected number of affected rows: %d", num)
+		return
+	}
+
+	// parameters are checked before execution
+	_, err = dbh.ExecDirect("DELETE FROM test_direct WHERE id = :id", nil)
+	if err == nil {
+		t.Error("error expected for missing parameter")
+		return
+	}
+}
+
+func TestPstmtParams(t *testing.T) {
+	dbh := newTestDriverDb("SELECT text FROM test_params WHERE id = $1 OR parent = $2 OR id = $3", &testResult{})
+	q, err := dbh.Prepare("SELECT text FROM test_params WHERE id = :id OR parent = :parent OR id = :id")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	if q.SQL() != "SELECT text FROM test_params WHERE id = $1 OR parent = $2 OR id = $3" {
+		t.Errorf("unexpected SQL: %s", q.SQL())
+		return
+	}
+
+	params := q.Params()
+	if !reflect.DeepEqual(params, []string{"id", "parent", "id"}) {
+		t.Errorf("unexpected parameters: %v", params)
+		return
+	}
+
+	// returned slice is a copy
+	params[0] = "changed"
+	if q.Params()[0] != "id" {
+		t.Error("parameters of statement are changed")
+		return
+	}
+}
+
+func TestQueryMeta(t *testing.T) {
+	query := "SELECT id, text FROM test_meta"
+	dbh := newTestDriverDb(query, &testResult{
+		columns: []string{"id", "text"},
+		rows:    [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}, {int64(3), "c"}},
+	})
+
+	q, err := dbh.Prepare(query)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	// rows after the first one are skipped
+	var record testStruct
+	meta, err := q.QueryMeta(&record, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !reflect.DeepEqual(meta.Columns, []string{"id", "text"}) || meta.Rows != 1 || meta.Skipped != 2 || meta.Truncated {
+		t.Errorf("unexpected meta: %+v", meta)
+		return
+	}
+
+	// result is truncated by MaxRows
+	dbh.MaxRows = 2
+	var list []*testStruct
+	meta, err = q.QueryMeta(&list, nil)
+	if err != ErrMaxRows {
+		t.Errorf("ErrMaxRows expected: %v", err)
+		return
+	}
+
+	if meta.Rows != 2 || !meta.Truncated {
+		t.Errorf("unexpected meta: %+v", meta)
+		return
+	}
+}
+
+func TestStrictSingleRow(t *testing.T) {
+	query := "SELECT id, text FROM test_strict"
+	dbh := newTestDriverDb(query, &testResult{
+		columns: []string{"id", "text"},
+		rows:    [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}},
+	})
+
+	q, err := dbh.Prepare(query)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	// the first row is mapped by default
+	var record testStruct
+	num, err := q.Query(&record, nil)
+	if err != nil || num != 1 || record.Id != 1 {
+		t.Errorf("the first row expected: %d, %v", num, err)
+		return
+	}
+
+	dbh.StrictSingleRow = true
+	num, err = q.Query(&record, nil)
+	if err != ErrMultipleRows || num != 2 {
+		t.Errorf("ErrMultipleRows expected: %d, %v", num, err)
+		return
+	}
+
+	// slices are not affected
+	var list []*testStruct
+	num, err = q.Query(&list, nil)
+	if err != nil || num != 2 {
+		t.Errorf("all rows expected: %d, %v", num, err)
+		return
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReadOnlyError is returned when DbHelper returned by ReadOnly is used to
+// execute a statement which can modify data.
+type ReadOnlyError struct {
+	// Query as it was passed to Prepare, or raw statement.
+	Query string
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("dbhelper: DbHelper is read-only, statement refused (query: %s)", e.Query)
+}
+
+// Statements that only read data.
+var readStatements = map[string]bool{
+	"SELECT":  true,
+	"WITH":    true,
+	"VALUES":  true,
+	"TABLE":   true,
+	"SHOW":    true,
+	"EXPLAIN": true,
+}
+
+// ReadOnly returns DbHelper sharing registered tables and transaction with
+// dbh, which refuses to insert, update and delete records and to execute
+// statements, returning *ReadOnlyError. Queries are performed only if they
+// start with SELECT, WITH, VALUES, TABLE, SHOW or EXPLAIN. Used in request
+// paths that must never write, e.g. handlers of GET requests or readers of
+// replicas. Statements prepared by dbh itself are not restricted. The guard
+// checks kind of statements, so it does not replace permissions of database
+// users: data-modifying common table expressions and functions are not
+// detected.
+func (dbh *DbHelper) ReadOnly() *DbHelper {
+	// modified tables must be shared to invalidate cache on commit
+	if dbh.tx != nil && dbh.txTables == nil {
+		dbh.txTables = make(map[string]bool)
+	}
+
+	ch := *dbh
+	ch.readOnly = true
+	return &ch
+}
+
+// IsReadOnly reports whether dbh was returned by ReadOnly.
+func (dbh *DbHelper) IsReadOnly() bool {
+	return dbh.readOnly
+}
+
+// Returns true if query only reads data.
+func isReadStatement(query string) bool {
+	fields := strings.Fields(strings.TrimLeft(query, "( \t\r\n"))
+	if len(fields) == 0 {
+		return false
+	}
+
+	return readStatements[strings.ToUpper(fields[0])]
+}
+
+// Returns error if statement is executed by read-only DbHelper. Queries
+// which only read data are allowed unless exec is true.
+func (pstmt *Pstmt) checkReadOnly(exec bool) error {
+	if !pstmt.dbHelper.readOnly {
+		return nil
+	}
+
+	if !exec && isReadStatement(pstmt.prepared) {
+		return nil
+	}
+
+	return &ReadOnlyError{Query: pstmt.query}
+}
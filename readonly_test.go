@@ -0,0 +1,98 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	dbh := newTestDriverDb("SELECT id FROM test_readonly", &testResult{
+		columns: []string{"id"},
+		rows:    [][]driver.Value{{int64(1)}},
+	})
+	newTestDriverDb("DELETE FROM test_readonly", &testResult{
+		rows: [][]driver.Value{{}},
+	})
+	newTestDriverDb("INSERT INTO test_readonly (id) VALUES (1) RETURNING id", &testResult{
+		columns: []string{"id"},
+		rows:    [][]driver.Value{{int64(1)}},
+	})
+
+	ro := dbh.ReadOnly()
+	if !ro.IsReadOnly() || dbh.IsReadOnly() {
+		t.Error("only returned DbHelper must be read-only")
+		return
+	}
+
+	// queries are allowed
+	var id int64
+	_, err := ro.Query(&id, "SELECT id FROM test_readonly", nil)
+	if err != nil || id != 1 {
+		t.Errorf("query expected: %d, %v", id, err)
+		return
+	}
+
+	// statements are refused
+	var roErr *ReadOnlyError
+	_, err = ro.Exec("DELETE FROM test_readonly", nil)
+	if !errors.As(err, &roErr) || roErr.Query != "DELETE FROM test_readonly" {
+		t.Errorf("ReadOnlyError expected: %v", err)
+		return
+	}
+
+	_, err = ro.Query(&id, "INSERT INTO test_readonly (id) VALUES (1) RETURNING id", nil)
+	if !errors.As(err, &roErr) {
+		t.Errorf("ReadOnlyError expected: %v", err)
+		return
+	}
+
+	// transactions are read-only too
+	txh, err := ro.Begin()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer txh.Rollback()
+
+	_, err = txh.Exec("DELETE FROM test_readonly", nil)
+	if !errors.As(err, &roErr) {
+		t.Errorf("ReadOnlyError expected: %v", err)
+		return
+	}
+
+	// original DbHelper is not restricted
+	num, err := dbh.Exec("DELETE FROM test_readonly", nil)
+	if err != nil || num != 1 {
+		t.Errorf("statement expected: %d, %v", num, err)
+		return
+	}
+}
+
+func TestIsReadStatement(t *testing.T) {
+	for query, read := range map[string]bool{
+		"SELECT 1":                             true,
+		"  select * FROM t":                    true,
+		"(SELECT 1) UNION (SELECT 2)":          true,
+		"WITH a AS (SELECT 1) SELECT * FROM a": true,
+		"UPDATE t SET a = 1":                   false,
+		"DROP TABLE t":                         false,
+		"":                                     false,
+	} {
+		if isReadStatement(query) != read {
+			t.Errorf("wrong kind of statement: %s", query)
+		}
+	}
+}
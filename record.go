@@ -0,0 +1,250 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Record is a row of a table which is not known at compile time, e.g. in
+// tools, admin consoles and migration scripts.
+type Record struct {
+	// Name of the table.
+	Table string
+
+	// Names of columns in order of assignment or of query result.
+	Columns []string
+
+	// Values of columns by name.
+	Values map[string]interface{}
+}
+
+// NewRecord returns empty record of table.
+func NewRecord(table string) *Record {
+	return &Record{
+		Table:  table,
+		Values: make(map[string]interface{}),
+	}
+}
+
+// Set assigns value to column, adding column to the record if needed.
+func (r *Record) Set(column string, value interface{}) *Record {
+	if r.Values == nil {
+		r.Values = make(map[string]interface{})
+	}
+
+	if _, ok := r.Values[column]; !ok {
+		r.Columns = append(r.Columns, column)
+	}
+
+	r.Values[column] = value
+	return r
+}
+
+// Get returns value of column or nil if record has no such column.
+func (r *Record) Get(column string) interface{} {
+	return r.Values[column]
+}
+
+// Returns error if record has no columns or names are not valid.
+func (r *Record) check() error {
+	if !identifierRegexp.MatchString(r.Table) {
+		return errors.New(fmt.Sprintf("dbhelper: wrong table name '%s'", r.Table))
+	}
+
+	if len(r.Columns) == 0 {
+		return errors.New(fmt.Sprintf("dbhelper: record of table '%s' has no columns", r.Table))
+	}
+
+	for _, col := range r.Columns {
+		if !identifierRegexp.MatchString(col) {
+			return errors.New(fmt.Sprintf("dbhelper: wrong column name '%s'", col))
+		}
+
+		if _, ok := r.Values[col]; !ok {
+			return errors.New(fmt.Sprintf("dbhelper: record of table '%s' has no value of column '%s'", r.Table, col))
+		}
+	}
+
+	return nil
+}
+
+// Returns quoted names of columns and parameters passing their values.
+func (dbh *DbHelper) recordParams(r *Record, params map[string]interface{}) ([]string, []string) {
+	columns := make([]string, len(r.Columns))
+	holders := make([]string, len(r.Columns))
+	for n, col := range r.Columns {
+		name := fmt.Sprintf("v%d", n)
+		params[name] = r.Values[col]
+		columns[n] = dbh.quote(col)
+		holders[n] = getNamedPlaceholder(name)
+	}
+
+	return columns, holders
+}
+
+// InsertRecord inserts record to its table. Query is prepared and closed
+// for every record, values of generated columns are not returned.
+func (dbh *DbHelper) InsertRecord(r *Record) error {
+	err := r.check()
+	if err != nil {
+		return err
+	}
+
+	params := make(map[string]interface{}, len(r.Columns))
+	columns, holders := dbh.recordParams(r, params)
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		dbh.quote(r.Table), strings.Join(columns, ", "), strings.Join(holders, ", "))
+
+	_, err = dbh.Exec(query, params)
+	return err
+}
+
+// UpdateRecord sets columns of record in rows of its table matching
+// condition and returns number of affected rows.
+func (dbh *DbHelper) UpdateRecord(r *Record, where Cond) (int64, error) {
+	err := r.check()
+	if err != nil {
+		return 0, err
+	}
+
+	if where == nil {
+		return 0, errors.New("dbhelper: condition of updated rows is required")
+	}
+
+	cond, params, err := BuildCond(where)
+	if err != nil {
+		return 0, err
+	}
+
+	columns, holders := dbh.recordParams(r, params)
+	set := make([]string, len(columns))
+	for n := range columns {
+		set[n] = fmt.Sprintf("%s = %s", columns[n], holders[n])
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", dbh.quote(r.Table), strings.Join(set, ", "), cond)
+	return dbh.Exec(query, params)
+}
+
+// QueryRecords selects rows of table matching condition, or all rows if
+// where is nil. Values are returned as provided by driver.
+func (dbh *DbHelper) QueryRecords(table string, where Cond) ([]*Record, error) {
+	if !identifierRegexp.MatchString(table) {
+		return nil, errors.New(fmt.Sprintf("dbhelper: wrong table name '%s'", table))
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s", dbh.quote(table))
+	params := make(map[string]interface{})
+	if where != nil {
+		cond, condParams, err := BuildCond(where)
+		if err != nil {
+			return nil, err
+		}
+
+		query += " WHERE " + cond
+		params = condParams
+	}
+
+	q, err := dbh.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer q.Close()
+
+	records, err := q.QueryRecords(params)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range records {
+		r.Table = table
+	}
+
+	return records, nil
+}
+
+// QueryRecords executes prepared query and returns rows of result as
+// records without table name. Values are returned as provided by driver.
+// Parameters are handled in the same way as by Query.
+func (pstmt *Pstmt) QueryRecords(params interface{}) ([]*Record, error) {
+	// refuse to modify data
+	err := pstmt.checkReadOnly(false)
+	if err != nil {
+		return nil, err
+	}
+
+	// get parameter values for query
+	values, err := pstmt.getValues(params)
+	if err != nil {
+		return nil, err
+	}
+
+	// apply default timeout
+	ctx, cancel := pstmt.dbHelper.withTimeout(pstmt.dbHelper.context())
+	defer cancel()
+
+	// perform query
+	rows, err := pstmt.queryValues(ctx, values)
+	if err != nil {
+		return nil, pstmt.queryError(err)
+	}
+
+	// close rows on exit
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, pstmt.queryError(err)
+	}
+
+	var records []*Record
+	for rows.Next() {
+		// limit number of rows
+		if pstmt.dbHelper.MaxRows > 0 && int64(len(records)) == pstmt.dbHelper.MaxRows {
+			return nil, ErrMaxRows
+		}
+
+		row := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for n := range row {
+			dest[n] = &row[n]
+		}
+
+		err = rows.Scan(dest...)
+		if err != nil {
+			return nil, pstmt.queryError(err)
+		}
+
+		r := &Record{
+			Columns: append([]string(nil), columns...),
+			Values:  make(map[string]interface{}, len(columns)),
+		}
+
+		for n, col := range columns {
+			r.Values[col] = row[n]
+		}
+
+		records = append(records, r)
+	}
+
+	// error which stopped iteration
+	err = rows.Err()
+	if err != nil {
+		return nil, pstmt.queryError(err)
+	}
+
+	return records, nil
+}
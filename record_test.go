@@ -0,0 +1,70 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestRecords(t *testing.T) {
+	dbh := newTestDriverDb("INSERT INTO test_records (id, name) VALUES ($1, $2)", &testResult{})
+	newTestDriverDb("UPDATE test_records SET name = $1 WHERE id = $2", &testResult{
+		rows: [][]driver.Value{{}},
+	})
+	newTestDriverDb("SELECT * FROM test_records WHERE id > $1", &testResult{
+		columns: []string{"id", "name"},
+		rows:    [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}},
+	})
+
+	r := NewRecord("test_records").Set("id", 1).Set("name", "a")
+	err := dbh.InsertRecord(r)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	num, err := dbh.UpdateRecord(NewRecord("test_records").Set("name", "b"), Eq("id", 1))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if num != 1 {
+		t.Errorf("unexpected number of updated rows: %d", num)
+		return
+	}
+
+	records, err := dbh.QueryRecords("test_records", Gt("id", 0))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if len(records) != 2 || records[1].Table != "test_records" || records[1].Get("name") != "b" || records[1].Columns[0] != "id" {
+		t.Errorf("unexpected records: %v", records)
+		return
+	}
+
+	// names are not a part of query text unless they are valid
+	err = dbh.InsertRecord(NewRecord("test_records").Set("id; DROP TABLE test", 1))
+	if err == nil {
+		t.Error("error expected for wrong column name")
+		return
+	}
+
+	_, err = dbh.UpdateRecord(r, nil)
+	if err == nil {
+		t.Error("error expected without condition")
+		return
+	}
+}
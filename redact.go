@@ -0,0 +1,147 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Redactor returns value which replaces value of sensitive column in logs
+// and error messages.
+type Redactor func(value interface{}) interface{}
+
+// RedactAll is a Redactor which replaces any value with "***".
+func RedactAll(value interface{}) interface{} {
+	return "***"
+}
+
+// RedactKeepLast returns Redactor keeping last n characters of string
+// representation of value and replacing others with '*'.
+func RedactKeepLast(n int) Redactor {
+	return func(value interface{}) interface{} {
+		s := []rune(fmt.Sprint(value))
+		for k := 0; k < len(s)-n; k++ {
+			s[k] = '*'
+		}
+
+		return string(s)
+	}
+}
+
+// SetRedactor sets redactor r for columns of table assigned to type of i,
+// or for all its columns if no columns are given. Redacted values are
+// returned by Redacted and RedactParams and included in error messages.
+func (dbh *DbHelper) SetRedactor(i interface{}, r Redactor, columns ...string) error {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return err
+	}
+
+	// all columns
+	if len(columns) == 0 {
+		for _, f := range tbl.orderedFields {
+			f.redactor = r
+		}
+
+		return nil
+	}
+
+	// check all columns before setting redactor
+	fields := make([]*dbField, len(columns))
+	for n, col := range columns {
+		f, ok := tbl.fields[col]
+		if !ok {
+			return errors.New(fmt.Sprintf("dbhelper: table '%s' has no mapped column '%s'", tbl.name, col))
+		}
+
+		fields[n] = f
+	}
+
+	for _, f := range fields {
+		f.redactor = r
+	}
+
+	return nil
+}
+
+// Returns value of the field to be included in logs and error messages.
+func (f *dbField) redact(value interface{}) interface{} {
+	if f.redactor == nil {
+		return value
+	}
+
+	return f.redactor(value)
+}
+
+// Redacted returns values of columns of structure i with values of
+// sensitive columns replaced by their redactors, for logging records.
+func (dbh *DbHelper) Redacted(i interface{}) (map[string]interface{}, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return nil, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := tbl.structValue(i)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{}, len(tbl.orderedFields))
+	for _, f := range tbl.orderedFields {
+		values[f.column] = f.redact(f.value(v).Interface())
+	}
+
+	return values, nil
+}
+
+// RedactParams returns copy of parameters of query for table assigned to
+// type of i, with values of parameters named after sensitive columns
+// replaced by their redactors, for logging queries.
+func (dbh *DbHelper) RedactParams(i interface{}, params map[string]interface{}) (map[string]interface{}, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return nil, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return nil, err
+	}
+
+	redacted := make(map[string]interface{}, len(params))
+	for name, value := range params {
+		if f, ok := tbl.fields[name]; ok {
+			value = f.redact(value)
+		}
+
+		redacted[name] = value
+	}
+
+	return redacted, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testSecretStruct struct {
+	Id       int64  `db:"id" dbopt:"id,auto"`
+	Login    string `db:"login"`
+	Password string `db:"password"`
+	Card     string `db:"card"`
+	Status   string `db:"status" dbopt:"enum=active|blocked"`
+}
+
+func TestRedactor(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testSecretStruct{}, "test_secrets")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = dbh.SetRedactor(testSecretStruct{}, RedactAll, "password", "unknown")
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	err = dbh.SetRedactor(testSecretStruct{}, RedactAll, "password")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = dbh.SetRedactor(testSecretStruct{}, RedactKeepLast(4), "card", "status")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	s := &testSecretStruct{Id: 1, Login: "user", Password: "secret", Card: "4111111111111111", Status: "deleted"}
+	values, err := dbh.Redacted(s)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if values["login"] != "user" || values["password"] != "***" || values["card"] != "************1111" {
+		t.Errorf("unexpected values: %v", values)
+		return
+	}
+
+	params, err := dbh.RedactParams(s, map[string]interface{}{"password": "secret", "n": 1})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if params["password"] != "***" || params["n"] != 1 {
+		t.Errorf("unexpected parameters: %v", params)
+		return
+	}
+
+	// error messages contain redacted values
+	tbl, err := dbh.getTable(reflect.TypeOf(testSecretStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = tbl.checkEnums(reflect.ValueOf(s).Elem())
+	if err == nil || strings.Contains(err.Error(), "deleted") || !strings.Contains(err.Error(), "***eted") {
+		t.Errorf("error with redacted value expected: %v", err)
+		return
+	}
+}
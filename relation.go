@@ -0,0 +1,722 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Kinds of relations between tables.
+const (
+	// Records of related table reference the record by foreign key column
+	// of related table.
+	HasMany = "has_many"
+
+	// Like HasMany, but there is at most one related record.
+	HasOne = "has_one"
+
+	// The record references related record by foreign key column of its table.
+	BelongsTo = "belongs_to"
+
+	// Records are linked by join table, which has foreign key columns
+	// referencing both tables.
+	ManyToMany = "many_to_many"
+)
+
+// Relation declares relation of a registered table to another registered table.
+type Relation struct {
+	// Name of the relation, which is also the name of structure field
+	// storing related records. Field must not be mapped to a column (use tag
+	// db:"-"). It must be a slice of structures or pointers to structures of
+	// Target type for HasMany and ManyToMany, and a structure or a pointer to
+	// structure otherwise.
+	Name string
+
+	// Kind of the relation: HasMany, HasOne, BelongsTo or ManyToMany.
+	Kind string
+
+	// Structure mapped to related table.
+	Target interface{}
+
+	// Foreign key column of related table for HasMany and HasOne, of the
+	// table for BelongsTo, and of join table referencing the table for
+	// ManyToMany.
+	ForeignKey string
+
+	// Join table and its foreign key column referencing related table,
+	// for ManyToMany only.
+	JoinTable string
+	JoinKey   string
+}
+
+// Stores information about declared relation.
+type dbRelation struct {
+	name       string
+	kind       string
+	target     *dbTable
+	foreignKey string
+	joinTable  string
+	joinKey    string
+
+	// Structure field storing related records.
+	field reflect.StructField
+
+	// Field is a slice.
+	many bool
+}
+
+// AddRelation declares relation of table assigned to type of i. Both tables
+// must be registered and have fields with option 'id'.
+func (dbh *DbHelper) AddRelation(i interface{}, rel Relation) error {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return err
+	}
+
+	// get tables
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return err
+	}
+
+	tt, err := typeOf(rel.Target)
+	if err != nil {
+		return err
+	}
+
+	target, err := dbh.getTable(tt)
+	if err != nil {
+		return err
+	}
+
+	if tbl.idField == nil || target.idField == nil {
+		return errors.New(fmt.Sprintf("dbhelper: relation '%s' can be declared only for tables with ids", rel.Name))
+	}
+
+	if _, ok := tbl.relations[rel.Name]; ok {
+		return errors.New(fmt.Sprintf("dbhelper: relation '%s' of table '%s' is already declared", rel.Name, tbl.name))
+	}
+
+	r := &dbRelation{
+		name:       rel.Name,
+		kind:       rel.Kind,
+		target:     target,
+		foreignKey: rel.ForeignKey,
+		joinTable:  rel.JoinTable,
+		joinKey:    rel.JoinKey,
+		many:       rel.Kind == HasMany || rel.Kind == ManyToMany,
+	}
+
+	// check foreign keys
+	switch rel.Kind {
+	case HasMany, HasOne:
+		if _, ok := target.fields[rel.ForeignKey]; !ok {
+			return errors.New(fmt.Sprintf("dbhelper: table '%s' has no foreign key column '%s'", target.name, rel.ForeignKey))
+		}
+	case BelongsTo:
+		if _, ok := tbl.fields[rel.ForeignKey]; !ok {
+			return errors.New(fmt.Sprintf("dbhelper: table '%s' has no foreign key column '%s'", tbl.name, rel.ForeignKey))
+		}
+	case ManyToMany:
+		for _, name := range []string{rel.ForeignKey, rel.JoinTable, rel.JoinKey} {
+			if !identifierRegexp.MatchString(name) {
+				return errors.New(fmt.Sprintf("dbhelper: wrong join table or column name '%s' of relation '%s'", name, rel.Name))
+			}
+		}
+	default:
+		return errors.New(fmt.Sprintf("dbhelper: unknown kind '%s' of relation '%s'", rel.Kind, rel.Name))
+	}
+
+	// check field storing related records
+	field, ok := t.FieldByName(rel.Name)
+	if !ok {
+		return errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field '%s'", t, rel.Name))
+	}
+
+	ft := field.Type
+	if r.many {
+		if ft.Kind() != reflect.Slice {
+			return errors.New(fmt.Sprintf("dbhelper: field '%s' of structure type '%v' must be a slice", rel.Name, t))
+		}
+
+		ft = ft.Elem()
+	}
+
+	if ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+
+	if ft != tt {
+		return errors.New(fmt.Sprintf("dbhelper: field '%s' of structure type '%v' cannot store records of type '%v'",
+			rel.Name, t, tt))
+	}
+
+	r.field = field
+
+	if tbl.relations == nil {
+		tbl.relations = make(map[string]*dbRelation)
+	}
+
+	tbl.relations[rel.Name] = r
+	tbl.relationNames = append(tbl.relationNames, rel.Name)
+	return nil
+}
+
+// Returns declared relation of the table.
+func (tbl *dbTable) getRelation(name string) (*dbRelation, error) {
+	r, ok := tbl.relations[name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("dbhelper: table '%s' has no relation '%s'", tbl.name, name))
+	}
+
+	return r, nil
+}
+
+// DeleteCascade deletes record i like Delete together with records related
+// to it by HasMany and HasOne relations, and links of ManyToMany relations.
+// Related records are deleted recursively before the records referencing
+// them within a transaction, so it can be used with databases without
+// ON DELETE CASCADE. Returns number of deleted root records.
+func (dbh *DbHelper) DeleteCascade(i interface{}) (int64, error) {
+	if dbh.tx == nil {
+		txh, err := dbh.Begin()
+		if err != nil {
+			return 0, err
+		}
+
+		num, err := txh.DeleteCascade(i)
+		if err != nil {
+			txh.Rollback()
+			return 0, err
+		}
+
+		return num, txh.Commit()
+	}
+
+	// get table
+	tbl, v, err := dbh.prepareParams(i)
+	if err != nil {
+		return 0, err
+	}
+
+	err = dbh.deleteRelated(tbl, []interface{}{tbl.idField.value(v).Interface()})
+	if err != nil {
+		return 0, err
+	}
+
+	return dbh.Delete(i)
+}
+
+// Deletes records related to records of the table with ids.
+func (dbh *DbHelper) deleteRelated(tbl *dbTable, ids []interface{}) error {
+	for _, name := range tbl.relationNames {
+		r := tbl.relations[name]
+		switch r.kind {
+		case HasMany, HasOne:
+			// records related to related records
+			if len(r.target.relations) > 0 {
+				var childIds []interface{}
+				err := dbh.inChunks(r.target, ids, func(chunk []interface{}) error {
+					query, params, err := dbh.Select(reflect.New(r.target.structType).Interface()).
+						Columns(r.target.idField.column).Where(In(r.foreignKey, chunk...)).SQL()
+					if err != nil {
+						return err
+					}
+
+					chunkIds, err := dbh.queryIds(r.target, query, params)
+					childIds = append(childIds, chunkIds...)
+					return err
+				})
+				if err != nil {
+					return err
+				}
+
+				if len(childIds) == 0 {
+					continue
+				}
+
+				err = dbh.deleteRelated(r.target, childIds)
+				if err != nil {
+					return err
+				}
+			}
+
+			err := dbh.inChunks(r.target, ids, func(chunk []interface{}) error {
+				_, err := dbh.DeleteWhere(reflect.New(r.target.structType).Interface(), In(r.foreignKey, chunk...))
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		case ManyToMany:
+			err := dbh.inChunks(nil, ids, func(chunk []interface{}) error {
+				query, params, err := BuildCond(In(r.foreignKey, chunk...))
+				if err != nil {
+					return err
+				}
+
+				_, err = dbh.execQuery(tbl, fmt.Sprintf("DELETE FROM %s WHERE %s", dbh.quote(r.joinTable), query), params)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// SelectByIdWith performs a select by id query like SelectById and loads
+// named declared relations of the selected record. Related records of each
+// relation are selected by one follow-up query.
+func (dbh *DbHelper) SelectByIdWith(i interface{}, id int64, relations ...string) (int64, error) {
+	num, err := dbh.SelectById(i, id)
+	if err != nil || num == 0 {
+		return num, err
+	}
+
+	return num, dbh.loadRelations(i, relations)
+}
+
+// LoadRelation selects records related to record i by declared relation
+// name and stores them in the field of the relation, so that relations can
+// be loaded on demand after i was selected. If i is a pointer to slice of
+// pointers to structures, relation of all records is loaded by one query.
+func (dbh *DbHelper) LoadRelation(i interface{}, name string) error {
+	return dbh.loadRelations(i, []string{name})
+}
+
+// Loads named relations of structure or slice of structures i.
+func (dbh *DbHelper) loadRelations(i interface{}, names []string) error {
+	// get table
+	t, err := typeOf(i)
+	if err != nil {
+		return err
+	}
+
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return err
+	}
+
+	// get relations before performing any queries
+	relations := make([]*dbRelation, len(names))
+	for n, name := range names {
+		relations[n], err = tbl.getRelation(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	// structures to fill
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("dbhelper: pointer expected")
+	}
+
+	var parents []reflect.Value
+	v = v.Elem()
+	if v.Kind() == reflect.Slice {
+		parents = make([]reflect.Value, 0, v.Len())
+		for n := 0; n < v.Len(); n++ {
+			p := v.Index(n)
+			if p.Kind() == reflect.Ptr {
+				if p.IsNil() {
+					continue
+				}
+
+				p = p.Elem()
+			}
+
+			parents = append(parents, p)
+		}
+	} else {
+		parents = []reflect.Value{v}
+	}
+
+	for _, r := range relations {
+		err = dbh.loadRelation(tbl, r, parents)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns distinct values of field f of structures.
+func distinctValues(f *dbField, structs []reflect.Value) []interface{} {
+	seen := make(map[interface{}]bool, len(structs))
+	values := make([]interface{}, 0, len(structs))
+	for _, v := range structs {
+		value := f.value(v).Interface()
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// Returns key used to match values of foreign keys and ids, which can
+// have different types.
+func relationKey(value interface{}) string {
+	return fmt.Sprint(value)
+}
+
+// Selects related records of parents and stores them in field of relation.
+func (dbh *DbHelper) loadRelation(tbl *dbTable, r *dbRelation, parents []reflect.Value) error {
+	// parents and ids of related records matching them
+	var keyField *dbField
+	links := make(map[string][]string)
+
+	// related records are selected by values of column
+	var column string
+	var values []interface{}
+	switch r.kind {
+	case HasMany, HasOne:
+		keyField = tbl.idField
+		column = r.foreignKey
+		values = distinctValues(tbl.idField, parents)
+	case BelongsTo:
+		keyField = tbl.fields[r.foreignKey]
+		column = r.target.idField.column
+		values = distinctValues(keyField, parents)
+	case ManyToMany:
+		keyField = tbl.idField
+		targetIds, err := dbh.queryLinks(tbl, r, distinctValues(tbl.idField, parents), links)
+		if err != nil {
+			return err
+		}
+
+		column = r.target.idField.column
+		values = targetIds
+	}
+
+	// select related records
+	list := reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(r.target.structType)), 0, len(values))
+	err := dbh.inChunks(r.target, values, func(chunk []interface{}) error {
+		rows := reflect.New(list.Type())
+		_, err := dbh.Select(reflect.New(r.target.structType).Interface()).Where(In(column, chunk...)).Query(rows.Interface())
+		if err != nil {
+			return err
+		}
+
+		list = reflect.AppendSlice(list, rows.Elem())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// group related records by keys of parents
+	groups := make(map[string][]reflect.Value)
+	for n := 0; n < list.Len(); n++ {
+		ptr := list.Index(n)
+		switch r.kind {
+		case HasMany, HasOne:
+			key := relationKey(r.target.fields[r.foreignKey].value(ptr.Elem()).Interface())
+			groups[key] = append(groups[key], ptr)
+		case BelongsTo:
+			key := relationKey(r.target.idField.value(ptr.Elem()).Interface())
+			groups[key] = append(groups[key], ptr)
+		case ManyToMany:
+			id := relationKey(r.target.idField.value(ptr.Elem()).Interface())
+			for _, key := range links[id] {
+				groups[key] = append(groups[key], ptr)
+			}
+		}
+	}
+
+	// store related records in parents
+	for _, p := range parents {
+		r.assign(p, groups[relationKey(keyField.value(p).Interface())])
+	}
+
+	return nil
+}
+
+// Selects rows of join table of ManyToMany relation r linking records with
+// ids. Stores keys of linked records by keys of related records in links and
+// returns distinct ids of related records.
+func (dbh *DbHelper) queryLinks(tbl *dbTable, r *dbRelation, ids []interface{}, links map[string][]string) ([]interface{}, error) {
+	// rows are mapped to structure with types of ids of both tables
+	linkType := reflect.StructOf([]reflect.StructField{
+		{Name: "Owner", Type: tbl.idField.typ, Tag: `db:"owner"`},
+		{Name: "Target", Type: r.target.idField.typ, Tag: `db:"target"`},
+	})
+
+	// join table has no scope
+	list := reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(linkType)), 0, len(ids))
+	err := dbh.inChunks(nil, ids, func(chunk []interface{}) error {
+		where, params, err := BuildCond(In(dbh.quote(r.foreignKey), chunk...))
+		if err != nil {
+			return err
+		}
+
+		query := fmt.Sprintf("SELECT %s AS owner, %s AS target FROM %s WHERE %s", dbh.quote(r.foreignKey),
+			dbh.quote(r.joinKey), dbh.quote(r.joinTable), where)
+
+		q, err := dbh.Prepare(query)
+		if err != nil {
+			return err
+		}
+
+		defer q.Close()
+
+		rows := reflect.New(list.Type())
+		_, err = q.Query(rows.Interface(), params)
+		if err != nil {
+			return err
+		}
+
+		list = reflect.AppendSlice(list, rows.Elem())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	targetIds := make([]interface{}, 0, list.Len())
+	for n := 0; n < list.Len(); n++ {
+		row := list.Index(n).Elem()
+		owner := relationKey(row.Field(0).Interface())
+		target := row.Field(1).Interface()
+
+		id := relationKey(target)
+		if _, ok := links[id]; !ok {
+			targetIds = append(targetIds, target)
+		}
+
+		links[id] = append(links[id], owner)
+	}
+
+	return targetIds, nil
+}
+
+// Stores pointers to related records in field of relation of structure v.
+func (r *dbRelation) assign(v reflect.Value, related []reflect.Value) {
+	field := v.FieldByIndex(r.field.Index)
+	ft := r.field.Type
+
+	if r.many {
+		list := reflect.MakeSlice(ft, 0, len(related))
+		for _, ptr := range related {
+			if ft.Elem().Kind() == reflect.Ptr {
+				list = reflect.Append(list, ptr)
+			} else {
+				list = reflect.Append(list, ptr.Elem())
+			}
+		}
+
+		field.Set(list)
+		return
+	}
+
+	// no related record
+	if len(related) == 0 {
+		field.Set(reflect.Zero(ft))
+		return
+	}
+
+	if ft.Kind() == reflect.Ptr {
+		field.Set(related[0])
+	} else {
+		field.Set(related[0].Elem())
+	}
+}
+
+// Changes of links of ManyToMany relation.
+const (
+	linkAppend = iota
+	linkRemove
+	linkReplace
+)
+
+// AppendRelation links record i to related records by join table of
+// declared ManyToMany relation name. Links which already exist are skipped.
+// Returns number of added links.
+func (dbh *DbHelper) AppendRelation(i interface{}, name string, related ...interface{}) (int64, error) {
+	return dbh.changeLinks(i, name, related, linkAppend)
+}
+
+// RemoveRelation deletes links of record i to related records from join
+// table of declared ManyToMany relation name. Returns number of removed links.
+func (dbh *DbHelper) RemoveRelation(i interface{}, name string, related ...interface{}) (int64, error) {
+	return dbh.changeLinks(i, name, related, linkRemove)
+}
+
+// ReplaceRelation links record i to related records only, removing other
+// links of declared ManyToMany relation name. Returns number of added and
+// removed links.
+func (dbh *DbHelper) ReplaceRelation(i interface{}, name string, related ...interface{}) (int64, error) {
+	return dbh.changeLinks(i, name, related, linkReplace)
+}
+
+// Adds and removes links of record i.
+func (dbh *DbHelper) changeLinks(i interface{}, name string, related []interface{}, change int) (int64, error) {
+	// get table and relation
+	t, err := typeOf(i)
+	if err != nil {
+		return 0, err
+	}
+
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return 0, err
+	}
+
+	r, err := tbl.getRelation(name)
+	if err != nil {
+		return 0, err
+	}
+
+	if r.kind != ManyToMany {
+		return 0, errors.New(fmt.Sprintf("dbhelper: relation '%s' of table '%s' has no join table", name, tbl.name))
+	}
+
+	v, err := tbl.structValue(i)
+	if err != nil {
+		return 0, err
+	}
+
+	id := tbl.idField.value(v).Interface()
+
+	// ids of related records
+	ids := make([]interface{}, 0, len(related))
+	for _, rec := range related {
+		if rec == nil {
+			return 0, errorNil
+		}
+
+		rv, err := r.target.structValue(rec)
+		if err != nil {
+			return 0, err
+		}
+
+		ids = append(ids, r.target.idField.value(rv).Interface())
+	}
+
+	return dbh.updateLinks(tbl, r, id, ids, change)
+}
+
+// Adds and removes links of record with id within a transaction.
+func (dbh *DbHelper) updateLinks(tbl *dbTable, r *dbRelation, id interface{}, ids []interface{}, change int) (int64, error) {
+	if dbh.tx == nil {
+		txh, err := dbh.Begin()
+		if err != nil {
+			return 0, err
+		}
+
+		num, err := txh.updateLinks(tbl, r, id, ids, change)
+		if err != nil {
+			txh.Rollback()
+			return 0, err
+		}
+
+		return num, txh.Commit()
+	}
+
+	// keys of related records
+	keys := make(map[string]bool, len(ids))
+	for _, targetId := range ids {
+		keys[relationKey(targetId)] = true
+	}
+
+	// ids of linked records
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = :owner", dbh.quote(r.joinKey), dbh.quote(r.joinTable),
+		dbh.quote(r.foreignKey))
+
+	q, err := tbl.getQuery(query)
+	if err != nil {
+		return 0, err
+	}
+
+	ptr := reflect.New(reflect.SliceOf(reflect.PtrTo(r.target.idField.typ)))
+	_, err = q.in(dbh).Query(ptr.Interface(), id)
+	if err != nil {
+		return 0, err
+	}
+
+	linked := make(map[string]bool)
+	list := ptr.Elem()
+	for n := 0; n < list.Len(); n++ {
+		linked[relationKey(list.Index(n).Elem().Interface())] = true
+	}
+
+	// links to remove
+	var remove []interface{}
+	if change == linkRemove {
+		for _, targetId := range ids {
+			key := relationKey(targetId)
+			if linked[key] {
+				remove = append(remove, targetId)
+				linked[key] = false
+			}
+		}
+	}
+
+	if change == linkReplace {
+		for n := 0; n < list.Len(); n++ {
+			targetId := list.Index(n).Elem().Interface()
+			if !keys[relationKey(targetId)] {
+				remove = append(remove, targetId)
+			}
+		}
+	}
+
+	num := int64(0)
+	if len(remove) > 0 {
+		where, params, err := BuildCond(And(Eq(dbh.quote(r.foreignKey), id), In(dbh.quote(r.joinKey), remove...)))
+		if err != nil {
+			return 0, err
+		}
+
+		n, err := dbh.execQuery(tbl, fmt.Sprintf("DELETE FROM %s WHERE %s", dbh.quote(r.joinTable), where), params)
+		if err != nil {
+			return 0, err
+		}
+
+		num += n
+	}
+
+	// links to add
+	if change == linkRemove {
+		return num, nil
+	}
+
+	query = fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (:owner, :target)", dbh.quote(r.joinTable),
+		dbh.quote(r.foreignKey), dbh.quote(r.joinKey))
+
+	for _, targetId := range ids {
+		key := relationKey(targetId)
+		if linked[key] {
+			continue
+		}
+
+		linked[key] = true
+
+		_, err = dbh.execQuery(tbl, query, map[string]interface{}{"owner": id, "target": targetId})
+		if err != nil {
+			return 0, err
+		}
+
+		num++
+	}
+
+	return num, nil
+}
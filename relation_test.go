@@ -0,0 +1,222 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testAuthor struct {
+	Id    int64         `db:"id" dbopt:"id,auto"`
+	Name  string        `db:"name"`
+	Books []*testBook   `db:"-"`
+	Tags  []testTag     `db:"-"`
+	Bio   *testBio      `db:"-"`
+	Other []*testAuthor `db:"-"`
+}
+
+type testBook struct {
+	Id       int64       `db:"id" dbopt:"id,auto"`
+	AuthorId int64       `db:"author_id"`
+	Title    string      `db:"title"`
+	Author   *testAuthor `db:"-"`
+}
+
+type testTag struct {
+	Id   int64  `db:"id" dbopt:"id,auto"`
+	Name string `db:"name"`
+}
+
+type testBio struct {
+	Id       int64  `db:"id" dbopt:"id,auto"`
+	AuthorId string `db:"author_id"`
+	Text     string `db:"text"`
+}
+
+// Returns DbHelper with registered tables and relations of authors.
+func newTestRelations(t *testing.T) *DbHelper {
+	dbh := New(nil, Postgresql{})
+	tables := []struct {
+		i    interface{}
+		name string
+	}{
+		{testAuthor{}, "authors"},
+		{testBook{}, "books"},
+		{testTag{}, "tags"},
+		{testBio{}, "bios"},
+	}
+
+	for _, tbl := range tables {
+		err := dbh.AddTable(tbl.i, tbl.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	relations := []struct {
+		i   interface{}
+		rel Relation
+	}{
+		{testAuthor{}, Relation{Name: "Books", Kind: HasMany, Target: testBook{}, ForeignKey: "author_id"}},
+		{testAuthor{}, Relation{Name: "Bio", Kind: HasOne, Target: testBio{}, ForeignKey: "author_id"}},
+		{testAuthor{}, Relation{Name: "Tags", Kind: ManyToMany, Target: testTag{}, ForeignKey: "author_id",
+			JoinTable: "author_tags", JoinKey: "tag_id"}},
+		{testBook{}, Relation{Name: "Author", Kind: BelongsTo, Target: testAuthor{}, ForeignKey: "author_id"}},
+	}
+
+	for _, r := range relations {
+		err := dbh.AddRelation(r.i, r.rel)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dbh
+}
+
+func TestAddRelation(t *testing.T) {
+	dbh := newTestRelations(t)
+
+	// wrong declarations
+	wrong := []Relation{
+		{Name: "Books", Kind: HasMany, Target: testBook{}, ForeignKey: "author_id"},
+		{Name: "Other", Kind: HasMany, Target: testBook{}, ForeignKey: "author_id"},
+		{Name: "Bio", Kind: HasMany, Target: testBio{}, ForeignKey: "author_id"},
+		{Name: "Name", Kind: HasOne, Target: testBio{}, ForeignKey: "author_id"},
+		{Name: "Missing", Kind: HasMany, Target: testBook{}, ForeignKey: "author_id"},
+		{Name: "Other", Kind: HasMany, Target: testAuthor{}, ForeignKey: "parent_id"},
+		{Name: "Other", Kind: "owns", Target: testAuthor{}, ForeignKey: "id"},
+		{Name: "Other", Kind: ManyToMany, Target: testAuthor{}, ForeignKey: "author_id", JoinTable: "friends"},
+		{Name: "Other", Kind: HasMany, Target: testStruct{}, ForeignKey: "id"},
+	}
+
+	for _, rel := range wrong {
+		err := dbh.AddRelation(testAuthor{}, rel)
+		if err == nil {
+			t.Errorf("error expected for relation %v", rel)
+			return
+		}
+	}
+
+	// type of foreign key is not compatible with id
+	report := dbh.Validate()
+	if len(report.Problems) != 1 || report.Problems[0].Table != "authors" || report.Problems[0].Column != "author_id" {
+		t.Errorf("unexpected problems: %v", report.Problems)
+		return
+	}
+}
+
+func TestRelationAssign(t *testing.T) {
+	dbh := newTestRelations(t)
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testAuthor{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	books := []reflect.Value{
+		reflect.ValueOf(&testBook{Id: 1, AuthorId: 5}),
+		reflect.ValueOf(&testBook{Id: 2, AuthorId: 5}),
+	}
+
+	a := &testAuthor{Id: 5}
+	v := reflect.ValueOf(a).Elem()
+
+	// slice of pointers
+	tbl.relations["Books"].assign(v, books)
+	if len(a.Books) != 2 || a.Books[1].Id != 2 {
+		t.Errorf("wrong books: %v", a.Books)
+		return
+	}
+
+	// no related records
+	tbl.relations["Books"].assign(v, nil)
+	if a.Books == nil || len(a.Books) != 0 {
+		t.Errorf("empty slice expected: %v", a.Books)
+		return
+	}
+
+	// single record
+	bio := &testBio{Id: 3, AuthorId: "5"}
+	tbl.relations["Bio"].assign(v, []reflect.Value{reflect.ValueOf(bio)})
+	if a.Bio != bio {
+		t.Errorf("wrong bio: %v", a.Bio)
+		return
+	}
+
+	tbl.relations["Bio"].assign(v, nil)
+	if a.Bio != nil {
+		t.Errorf("nil expected: %v", a.Bio)
+		return
+	}
+
+	// slice of structures
+	tags := []reflect.Value{reflect.ValueOf(&testTag{Id: 7, Name: "go"})}
+	tbl.relations["Tags"].assign(v, tags)
+	if len(a.Tags) != 1 || a.Tags[0].Name != "go" {
+		t.Errorf("wrong tags: %v", a.Tags)
+		return
+	}
+
+	// foreign keys and ids of different types are matched
+	if relationKey(int64(5)) != relationKey(bio.AuthorId) {
+		t.Error("keys do not match")
+		return
+	}
+}
+
+func TestLoadRelation(t *testing.T) {
+	dbh := newTestRelations(t)
+
+	a := &testAuthor{Id: 1}
+	errs := []error{
+		dbh.LoadRelation(a, "Name"),
+		dbh.LoadRelation(testAuthor{}, "Books"),
+		dbh.LoadRelation(&testTag{}, "Books"),
+		dbh.LoadRelation(&[]*testAuthor{a}, "Missing"),
+	}
+
+	for n, err := range errs {
+		if err == nil {
+			t.Errorf("error expected in case %d", n)
+			return
+		}
+	}
+}
+
+func TestAppendRelation(t *testing.T) {
+	dbh := newTestRelations(t)
+
+	a := &testAuthor{Id: 1}
+	errs := []error{}
+
+	_, err := dbh.AppendRelation(a, "Books", &testBook{Id: 1})
+	errs = append(errs, err)
+
+	_, err = dbh.RemoveRelation(a, "Tags", &testBook{Id: 1})
+	errs = append(errs, err)
+
+	_, err = dbh.ReplaceRelation(a, "Tags", nil)
+	errs = append(errs, err)
+
+	_, err = dbh.AppendRelation(&testTag{}, "Tags", &testTag{Id: 1})
+	errs = append(errs, err)
+
+	for n, err := range errs {
+		if err == nil {
+			t.Errorf("error expected in case %d", n)
+			return
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Returns queries creating registered tables in the order of their names.
+// Tables assigned to several structure types are created once, views are
+// not created. Sequences of SequenceGenerator are created before their
+// tables, triggers maintaining columns with option 'modified' are created
+// after them.
+func (dbh *DbHelper) schemaQueries() []string {
+	sqldSeq, hasSeq := dbh.sqlDialect.(HasSequences)
+
+	var queries []string
+	created := make(map[string]bool)
+	for _, tbl := range dbh.sortedTables() {
+		if tbl.view || created[tbl.name] {
+			continue
+		}
+
+		created[tbl.name] = true
+
+		// sequence used to generate ids
+		gen, useSeq := tbl.idGenerator.(*SequenceGenerator)
+		useSeq = useSeq && hasSeq && identifierRegexp.MatchString(gen.Sequence)
+		if useSeq {
+			queries = append(queries, sqldSeq.CreateSequence(gen.Sequence))
+		}
+
+		queries = append(queries, tbl.createTableQuery())
+
+		if useSeq {
+			queries = append(queries, sqldSeq.SetSequenceOwner(gen.Sequence, dbh.quote(tbl.name),
+				dbh.quote(tbl.idField.column)))
+		}
+
+		// history table follows its table
+		if tbl.history {
+			queries = append(queries, tbl.createHistoryTableQuery())
+		}
+
+		// trigger maintaining modification timestamp
+		if tbl.modifiedField != nil {
+			column := tbl.modifiedField.column
+			queries = append(queries, dbh.sqlDialect.ModifiedTrigger(modifiedTriggerName(tbl, column),
+				dbh.quote(tbl.name), dbh.quote(column), dbh.quote(tbl.idField.column))...)
+		}
+	}
+
+	return queries
+}
+
+// ExportSchema writes to w queries creating all registered tables in SQL
+// dialect of dbh, as CreateTable does, together with sequences and
+// modification timestamp triggers (see CreateSequence and
+// CreateModifiedTrigger). Constraints derived from field options (primary
+// key, not null columns, valid values of enums) are defined within tables.
+// Each query is terminated by semicolon at the end of line, so schema can
+// be applied by ImportSchema or used as a baseline of migrations.
+func (dbh *DbHelper) ExportSchema(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "-- schema of tables registered in dbhelper\n")
+	if err != nil {
+		return err
+	}
+
+	for _, query := range dbh.schemaQueries() {
+		_, err = fmt.Fprintf(w, "%s;\n", query)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ImportSchema executes queries read from r, e.g. written by ExportSchema.
+// Queries are terminated by semicolon at the end of line and may span
+// several lines, which are passed to database unchanged. Empty lines and
+// lines starting with "--" between queries are skipped.
+// Queries are executed within transaction of dbh, if it has one. Otherwise
+// a transaction is started if SQL dialect supports transactional DDL
+// (Postgresql, Sqlite), so that schema is not applied partially. Other
+// dialects (MySql) commit each query, so queries executed before a
+// failed one remain applied.
+func (dbh *DbHelper) ImportSchema(r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// start transaction if needed
+	txh := dbh
+	if dbh.tx == nil && dbh.Capabilities().TransactionalDDL {
+		txh, err = dbh.Begin()
+		if err != nil {
+			return err
+		}
+	}
+
+	err = txh.importSchema(string(data))
+	if err != nil {
+		if txh != dbh {
+			txh.Rollback()
+		}
+
+		return err
+	}
+
+	if txh != dbh {
+		return txh.Commit()
+	}
+
+	return nil
+}
+
+func (dbh *DbHelper) importSchema(data string) error {
+	var query []string
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		trimmed := strings.TrimSpace(line)
+
+		// skip empty lines and comments between queries
+		if len(query) == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+
+		if !strings.HasSuffix(trimmed, ";") {
+			query = append(query, line)
+			continue
+		}
+
+		query = append(query, strings.TrimSuffix(strings.TrimRight(line, " \t"), ";"))
+		_, err := dbh.execRaw(strings.Join(query, "\n"))
+		if err != nil {
+			return err
+		}
+
+		query = query[:0]
+	}
+
+	// last query may be not terminated
+	if len(query) > 0 && strings.TrimSpace(strings.Join(query, "")) != "" {
+		_, err := dbh.execRaw(strings.Join(query, "\n"))
+		return err
+	}
+
+	return nil
+}
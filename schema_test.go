@@ -0,0 +1,102 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExportSchema(t *testing.T) {
+	dbh := newTestDriverDb("", &testResult{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = dbh.EnableHistory(testStruct{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	err = dbh.ExportSchema(&buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 6 || !strings.HasPrefix(lines[0], "--") ||
+		!strings.HasPrefix(lines[1], "CREATE TABLE IF NOT EXISTS test (id bigserial PRIMARY KEY") ||
+		!strings.HasPrefix(lines[2], "CREATE TABLE IF NOT EXISTS test_history (") ||
+		!strings.HasPrefix(lines[3], "CREATE OR REPLACE FUNCTION test_m_modified()") ||
+		lines[5] != "CREATE TRIGGER test_m_modified BEFORE UPDATE ON test FOR EACH ROW EXECUTE PROCEDURE test_m_modified();" {
+		t.Errorf("unexpected schema: %s", buf.String())
+		return
+	}
+
+	// all queries are executed
+	for _, line := range lines[1:] {
+		newTestDriverDb(strings.TrimSuffix(line, ";"), &testResult{})
+	}
+
+	err = dbh.ImportSchema(&buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// unknown query fails
+	err = dbh.ImportSchema(strings.NewReader("-- comment\n\nDROP TABLE\n  test;\n"))
+	if err == nil || !strings.Contains(err.Error(), "unknown query: DROP TABLE\n  test") {
+		t.Errorf("unknown query error expected: %v", err)
+		return
+	}
+}
+
+func TestExportSchemaSequence(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testCSVStruct{}, "test_seq")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = dbh.SetIdGenerator(testCSVStruct{}, &SequenceGenerator{Sequence: "test_seq_ids"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	queries := dbh.schemaQueries()
+	if len(queries) != 3 || queries[0] != "CREATE SEQUENCE IF NOT EXISTS test_seq_ids" ||
+		queries[2] != "ALTER SEQUENCE test_seq_ids OWNED BY test_seq.id" {
+		t.Errorf("unexpected queries: %v", queries)
+		return
+	}
+}
+
+func TestImportSchemaLiteral(t *testing.T) {
+	query := "INSERT INTO test_schema (text) VALUES ('a\n  -- b\n\nc')"
+	dbh := newTestDriverDb(query, &testResult{})
+
+	// lines of query are not changed
+	err := dbh.ImportSchema(strings.NewReader("-- comment\n" + query + ";\n"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Escapes special characters of LIKE patterns (%, _ and \) in s.
+func EscapeLike(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `%`, `\%`, -1)
+	s = strings.Replace(s, `_`, `\_`, -1)
+	return s
+}
+
+// Returns case insensitive search condition.
+func searchCondition(sqld SqlDialect, column string, param string) string {
+	if sqld, ok := sqld.(HasSearch); ok {
+		return sqld.Search(column, param)
+	}
+
+	return fmt.Sprintf(`LOWER(%s) LIKE LOWER(%s) ESCAPE '\'`, column, param)
+}
+
+// Search selects records of the table assigned to type of i, in which
+// column contains substring. Search is case insensitive. Special characters
+// in substring are escaped. Query is prepared when Search is called first
+// time for the column. Soft-deleted records and records of other tenants
+// are skipped.
+func (dbh *DbHelper) Search(i interface{}, column string, substring string) (int64, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return 0, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return 0, err
+	}
+
+	// parameters of query limited to tenant
+	params := map[string]interface{}{"substring": "%" + EscapeLike(substring) + "%"}
+	err = dbh.scopeParams(tbl, params)
+	if err != nil {
+		return 0, err
+	}
+
+	// check if query was already prepared
+	tbl.mutex.Lock()
+	q, ok := tbl.searchQueries[column]
+	if !ok {
+		// check column name
+		_, ok := tbl.fields[column]
+		if !ok {
+			tbl.mutex.Unlock()
+			return 0, errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field assigned to column '%s' of table '%s'",
+				t, column, tbl.name))
+		}
+
+		// search query
+		query := fmt.Sprintf("SELECT * FROM %s WHERE %s%s", dbh.quote(tbl.name),
+			searchCondition(dbh.sqlDialect, dbh.quote(column), getNamedPlaceholder("substring")),
+			tbl.scopeSQL(tenantParam))
+
+		// prepare query, it is cached for all transactions
+		q, err = tbl.dbHelper.Prepare(query)
+		if err != nil {
+			tbl.mutex.Unlock()
+			return 0, err
+		}
+
+		// store prepared query
+		tbl.searchQueries[column] = q
+	}
+	tbl.mutex.Unlock()
+
+	// perform query
+	return q.in(dbh).Query(i, params)
+}
+
+// SearchFullText selects records of the table assigned to type of i, which
+// match full-text query. Field with option 'tsvector' is used as search
+// document. On Postgresql query is converted using to_tsquery and results are
+// sorted by rank, on other dialects search document must contain query as
+// a substring. At most rankLimit records are returned, if rankLimit is positive.
+// Soft-deleted records and records of other tenants are skipped.
+func (dbh *DbHelper) SearchFullText(i interface{}, query string, rankLimit int) (int64, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return 0, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return 0, err
+	}
+
+	if tbl.tsvectorField == nil {
+		return 0, errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field with option 'tsvector'", t))
+	}
+
+	column := dbh.quote(tbl.tsvectorField.column)
+	param := getNamedPlaceholder("query")
+	scope := tbl.scopeSQL(tenantParam)
+
+	// build query
+	sql := fmt.Sprintf("SELECT * FROM %s WHERE ", dbh.quote(tbl.name))
+	if sqld, ok := dbh.sqlDialect.(HasFullTextSearch); ok {
+		cond, rank := sqld.FullTextSearch(column, param)
+		sql += fmt.Sprintf("%s%s ORDER BY %s DESC", cond, scope, rank)
+	} else {
+		sql += searchCondition(dbh.sqlDialect, column, param) + scope
+		query = "%" + EscapeLike(query) + "%"
+	}
+
+	if rankLimit > 0 {
+		sql += fmt.Sprintf(" LIMIT %d", rankLimit)
+	}
+
+	// parameters of query limited to tenant
+	params := map[string]interface{}{"query": query}
+	err = dbh.scopeParams(tbl, params)
+	if err != nil {
+		return 0, err
+	}
+
+	// reuse query prepared for the same limit
+	q, err := tbl.getQuery(sql)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.in(dbh).Query(i, params)
+}
@@ -0,0 +1,202 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+type testSearchStruct struct {
+	Id   int64  `db:"id" dbopt:"id,auto"`
+	Name string `db:"name"`
+}
+
+func TestEscapeLike(t *testing.T) {
+	if s := EscapeLike(`50%_a\b`); s != `50\%\_a\\b` {
+		t.Errorf("unexpected escaped string: %s", s)
+		return
+	}
+}
+
+func TestSearchCondition(t *testing.T) {
+	if s := searchCondition(Postgresql{}, "name", "$1"); s != "name ILIKE $1" {
+		t.Errorf("unexpected condition: %s", s)
+		return
+	}
+
+	if s := searchCondition(Sqlite{}, "name", "?"); s != `LOWER(name) LIKE LOWER(?) ESCAPE '\'` {
+		t.Errorf("unexpected condition: %s", s)
+		return
+	}
+}
+
+func TestSearch(t *testing.T) {
+	dbh := newTestDriverDb("SELECT * FROM test_search WHERE name ILIKE $1", &testResult{
+		columns: []string{"id", "name"},
+		rows:    [][]driver.Value{{int64(1), "Abc"}, {int64(2), "xabc"}},
+	})
+
+	err := dbh.AddTable(testSearchStruct{}, "test_search")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var list []*testSearchStruct
+	num, err := dbh.Search(&list, "name", "abc")
+	if err != nil || num != 2 || list[1].Name != "xabc" {
+		t.Errorf("unexpected result: %d, %v", num, err)
+		return
+	}
+
+	// prepared query is reused
+	list = nil
+	num, err = dbh.Search(&list, "name", "50%")
+	if err != nil || num != 2 {
+		t.Errorf("unexpected result: %d, %v", num, err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testSearchStruct{}))
+	if err != nil || len(tbl.searchQueries) != 1 {
+		t.Errorf("one prepared query expected: %v", err)
+		return
+	}
+
+	// unknown column
+	_, err = dbh.Search(&list, "unknown", "abc")
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+type testFullTextStruct struct {
+	Id       int64  `db:"id" dbopt:"id,auto"`
+	Document string `db:"document" dbopt:"tsvector"`
+}
+
+func TestSearchFullText(t *testing.T) {
+	dbh := newTestDriverDb("SELECT * FROM test_fts WHERE document @@ to_tsquery($1) ORDER BY ts_rank(document, to_tsquery($2)) DESC LIMIT 10", &testResult{
+		columns: []string{"id", "document"},
+		rows:    [][]driver.Value{{int64(1), "'abc':1"}},
+	})
+
+	err := dbh.AddTable(testFullTextStruct{}, "test_fts")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var list []*testFullTextStruct
+	num, err := dbh.SearchFullText(&list, "abc", 10)
+	if err != nil || num != 1 || list[0].Id != 1 {
+		t.Errorf("unexpected result: %d, %v", num, err)
+		return
+	}
+
+	// table without search document
+	err = dbh.AddTable(testSearchStruct{}, "test_search")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = dbh.SearchFullText(&[]*testSearchStruct{}, "abc", 0)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestSearchScope(t *testing.T) {
+	dbh := newTestScopedDb(t, "SELECT * FROM test_options WHERE name ILIKE $1 AND deleted = 0 AND tenant = $2")
+
+	var list []*testOptionsStruct
+	_, err := dbh.Search(&list, "name", "a")
+	if err != ErrNoTenant {
+		t.Errorf("ErrNoTenant expected: %v", err)
+		return
+	}
+
+	// deleted records and records of other tenants are skipped
+	num, err := dbh.WithTenant("acme").Search(&list, "name", "a")
+	if err != nil || num != 1 || list[0].Id != 1 {
+		t.Errorf("unexpected result: %d, %v", num, err)
+		return
+	}
+}
+
+type testScopedFullTextStruct struct {
+	Id       int64  `db:"id" dbopt:"id,auto"`
+	Document string `db:"document" dbopt:"tsvector"`
+	Deleted  int64  `db:"deleted"`
+	Tenant   string `db:"tenant"`
+}
+
+func TestSearchFullTextScope(t *testing.T) {
+	query := "SELECT * FROM test_fts_scoped WHERE document @@ to_tsquery($1) AND deleted = 0 AND tenant = $2 " +
+		"ORDER BY ts_rank(document, to_tsquery($3)) DESC"
+	dbh := newTestDriverDb(query, &testResult{
+		columns: []string{"id", "document", "deleted", "tenant"},
+		queryRows: func(args []driver.Value) [][]driver.Value {
+			var rows [][]driver.Value
+			for _, row := range [][]driver.Value{
+				{int64(1), "'a':1", int64(0), "acme"},
+				{int64(2), "'a':1", int64(123), "acme"},
+				{int64(3), "'a':1", int64(0), "other"},
+			} {
+				if row[2] == int64(0) && row[3] == args[1] {
+					rows = append(rows, row)
+				}
+			}
+
+			return rows
+		},
+	})
+
+	err := dbh.AddTableWith(testScopedFullTextStruct{}, TableOptions{
+		Name:       "test_fts_scoped",
+		SoftDelete: "deleted",
+		Tenant:     "tenant",
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var list []*testScopedFullTextStruct
+	_, err = dbh.SearchFullText(&list, "a", 0)
+	if err != ErrNoTenant {
+		t.Errorf("ErrNoTenant expected: %v", err)
+		return
+	}
+
+	// deleted records and records of other tenants are skipped
+	for n := 0; n < 2; n++ {
+		list = nil
+		num, err := dbh.WithTenant("acme").SearchFullText(&list, "a", 0)
+		if err != nil || num != 1 || list[0].Id != 1 {
+			t.Errorf("unexpected result: %d, %v", num, err)
+			return
+		}
+	}
+
+	// query is prepared once
+	tbl, err := dbh.getTable(reflect.TypeOf(testScopedFullTextStruct{}))
+	if err != nil || len(tbl.queries) != 1 {
+		t.Errorf("prepared query expected: %v", err)
+		return
+	}
+}
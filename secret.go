@@ -0,0 +1,103 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// SecretHasher hashes values of fields with option 'bcrypt', e.g. passwords,
+// before they are stored, so that only hashes are stored in database.
+// Implementation is usually a wrapper of golang.org/x/crypto/bcrypt.
+type SecretHasher interface {
+	// Hash returns hash of secret.
+	Hash(secret string) (string, error)
+
+	// Verify returns true if hash is a hash of secret.
+	Verify(hash string, secret string) (bool, error)
+
+	// IsHash returns true if value is already a hash, so that hashes of
+	// selected records are not hashed again on update.
+	IsHash(value string) bool
+}
+
+var errorNoHasher = errors.New("dbhelper: SecretHasher is not set")
+
+// Returns hash of secret s, or s if it is empty or already a hash.
+// SecretHasher of dbh is used, as in VerifySecret.
+func (dbh *DbHelper) hashSecret(s string) (string, error) {
+	hasher := dbh.SecretHasher
+	if hasher == nil {
+		return "", errorNoHasher
+	}
+
+	if s == "" || hasher.IsHash(s) {
+		return s, nil
+	}
+
+	return hasher.Hash(s)
+}
+
+// Replaces secrets stored in fields of structure v of table tbl with their hashes.
+func (dbh *DbHelper) hashSecrets(tbl *dbTable, v reflect.Value) error {
+	for _, f := range tbl.secretFields {
+		fv := f.value(v)
+		hash, err := dbh.hashSecret(fv.String())
+		if err != nil {
+			return err
+		}
+
+		fv.SetString(hash)
+	}
+
+	return nil
+}
+
+// VerifySecret returns true if candidate matches the secret which hash is
+// stored in field with option 'bcrypt' assigned to column of structure i,
+// e.g. selected user record and a password entered by user.
+func (dbh *DbHelper) VerifySecret(i interface{}, column string, candidate string) (bool, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return false, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return false, err
+	}
+
+	f, ok := tbl.fields[column]
+	if !ok || !f.secret {
+		return false, errors.New(fmt.Sprintf("dbhelper: column '%s' of table '%s' has no option 'bcrypt'", column, tbl.name))
+	}
+
+	if dbh.SecretHasher == nil {
+		return false, errorNoHasher
+	}
+
+	v, err := tbl.structValue(i)
+	if err != nil {
+		return false, err
+	}
+
+	hash := f.value(v).String()
+	if hash == "" {
+		return false, nil
+	}
+
+	return dbh.SecretHasher.Verify(hash, candidate)
+}
@@ -0,0 +1,173 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Hasher which is not secure, used in tests.
+type testHasher struct{}
+
+func (h testHasher) Hash(secret string) (string, error) {
+	return "hash:" + secret, nil
+}
+
+func (h testHasher) Verify(hash string, secret string) (bool, error) {
+	return hash == "hash:"+secret, nil
+}
+
+func (h testHasher) IsHash(value string) bool {
+	return strings.HasPrefix(value, "hash:")
+}
+
+type testUser struct {
+	Id       int64  `db:"id" dbopt:"id,auto"`
+	Login    string `db:"login"`
+	Password string `db:"password" dbopt:"bcrypt"`
+}
+
+func TestSecret(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testUser{}, "test_users")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testUser{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	u := &testUser{Login: "user", Password: "secret"}
+	v := reflect.ValueOf(u).Elem()
+
+	// hasher is required
+	err = dbh.hashSecrets(tbl, v)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	dbh.SecretHasher = testHasher{}
+	err = dbh.hashSecrets(tbl, v)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// hashes are not hashed again
+	err = dbh.hashSecrets(tbl, v)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if u.Password != "hash:secret" {
+		t.Errorf("unexpected hash: %s", u.Password)
+		return
+	}
+
+	for candidate, expected := range map[string]bool{"secret": true, "wrong": false} {
+		ok, err := dbh.VerifySecret(u, "password", candidate)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if ok != expected {
+			t.Errorf("unexpected result of verification of '%s'", candidate)
+			return
+		}
+	}
+
+	_, err = dbh.VerifySecret(u, "login", "user")
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	// values of updates are hashed
+	params := make(map[string]interface{})
+	_, err = dbh.buildSet(tbl, map[string]interface{}{"password": "new"}, params, 0)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if params["u_password"] != "hash:new" {
+		t.Errorf("unexpected parameters: %v", params)
+		return
+	}
+
+	// secrets must be strings
+	type wrongUser struct {
+		Id  int64 `db:"id" dbopt:"id,auto"`
+		Pin int64 `db:"pin" dbopt:"bcrypt"`
+	}
+
+	err = dbh.AddTable(wrongUser{}, "wrong_users")
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestSecretHasherOfHelper(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testUser{}, "test_users")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testUser{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// hasher set on derived helper is used for hashing and verification
+	ch := dbh.WithContext(context.Background())
+	ch.SecretHasher = testHasher{}
+
+	u := &testUser{Login: "user", Password: "secret"}
+	err = ch.hashSecrets(tbl, reflect.ValueOf(u).Elem())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ok, err := ch.VerifySecret(u, "password", "secret")
+	if err != nil || !ok {
+		t.Errorf("unexpected result of verification: %v, %v", ok, err)
+		return
+	}
+
+	// helper without hasher neither hashes nor verifies
+	err = dbh.hashSecrets(tbl, reflect.ValueOf(&testUser{Password: "secret"}).Elem())
+	if err != errorNoHasher {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	_, err = dbh.VerifySecret(u, "password", "secret")
+	if err != errorNoHasher {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+}
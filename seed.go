@@ -0,0 +1,157 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SeedFunc inserts reference data using dbh, which is bound to a transaction.
+type SeedFunc func(dbh *DbHelper) error
+
+// Seeder applies named seeds to database exactly once. Names of applied seeds
+// are recorded in a table, so seeds can be applied on every startup.
+type Seeder struct {
+	// Name of the table storing names of applied seeds.
+	Table string
+
+	dbHelper *DbHelper
+	names    []string
+	seeds    map[string]SeedFunc
+}
+
+// NewSeeder returns new Seeder recording applied seeds in table "dbhelper_seeds".
+func (dbh *DbHelper) NewSeeder() *Seeder {
+	return &Seeder{
+		Table:    "dbhelper_seeds",
+		dbHelper: dbh,
+		seeds:    make(map[string]SeedFunc),
+	}
+}
+
+// Add registers seed f with name. Seeds are applied in the order they were added.
+func (s *Seeder) Add(name string, f SeedFunc) error {
+	if name == "" {
+		return errors.New("dbhelper: seed name cannot be an empty string")
+	}
+
+	if f == nil {
+		return errors.New(fmt.Sprintf("dbhelper: seed '%s' has no function", name))
+	}
+
+	if _, ok := s.seeds[name]; ok {
+		return errors.New(fmt.Sprintf("dbhelper: seed '%s' is already added", name))
+	}
+
+	s.names = append(s.names, name)
+	s.seeds[name] = f
+
+	return nil
+}
+
+// Apply applies seeds that were not applied yet and returns their names.
+// Every seed is applied in a separate transaction together with recording
+// its name, so a failed seed is retried on the next call.
+func (s *Seeder) Apply() ([]string, error) {
+	dbh := s.dbHelper
+
+	if !identifierRegexp.MatchString(s.Table) {
+		return nil, errors.New(fmt.Sprintf("dbhelper: wrong table name '%s'", s.Table))
+	}
+
+	// create table for applied seeds
+	_, err := dbh.execRaw(fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (name VARCHAR(255) PRIMARY KEY, applied BIGINT NOT NULL)", s.Table))
+	if err != nil {
+		return nil, err
+	}
+
+	// get applied seeds
+	applied, err := s.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(s.names))
+	for _, name := range s.names {
+		if applied[name] {
+			continue
+		}
+
+		err = s.apply(name)
+		if err != nil {
+			return names, err
+		}
+
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// Returns names of applied seeds.
+func (s *Seeder) applied() (map[string]bool, error) {
+	pstmt, err := s.dbHelper.Prepare(fmt.Sprintf("SELECT name FROM %s", s.Table))
+	if err != nil {
+		return nil, err
+	}
+
+	defer pstmt.Close()
+
+	var names []*string
+	_, err = pstmt.Query(&names, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]bool, len(names))
+	for _, name := range names {
+		applied[*name] = true
+	}
+
+	return applied, nil
+}
+
+// Applies seed and records its name within one transaction.
+func (s *Seeder) apply(name string) error {
+	txh, err := s.dbHelper.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = s.seeds[name](txh)
+	if err != nil {
+		txh.Rollback()
+		return errors.New(fmt.Sprintf("dbhelper: seed '%s': %v", name, err))
+	}
+
+	pstmt, err := txh.Prepare(fmt.Sprintf("INSERT INTO %s (name, applied) VALUES (:name, :applied)", s.Table))
+	if err != nil {
+		txh.Rollback()
+		return err
+	}
+
+	defer pstmt.Close()
+
+	_, err = pstmt.Exec(map[string]interface{}{
+		"name":    name,
+		"applied": time.Now().UTC().Unix(),
+	})
+	if err != nil {
+		txh.Rollback()
+		return err
+	}
+
+	return txh.Commit()
+}
@@ -0,0 +1,47 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"testing"
+)
+
+func TestSeederAdd(t *testing.T) {
+	s := New(nil, Postgresql{}).NewSeeder()
+
+	f := func(dbh *DbHelper) error {
+		return nil
+	}
+
+	err := s.Add("colors", f)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.Add("colors", f)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	err = s.Add("sizes", nil)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	if len(s.names) != 1 || s.names[0] != "colors" {
+		t.Errorf("unexpected seeds: %v", s.names)
+		return
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sync"
+)
+
+// Sharded routes operations to one of several DbHelpers (shards) by hash of
+// shard key. Shard key is the value of field with option 'shardkey' or a
+// value passed to Shard. Records are routed by number of shards, so it must
+// not change once records are stored.
+type Sharded struct {
+	shards []*DbHelper
+}
+
+// NewSharded returns Sharded routing operations to shards.
+func NewSharded(shards ...*DbHelper) (*Sharded, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("dbhelper: at least one shard is required")
+	}
+
+	return &Sharded{shards: shards}, nil
+}
+
+// Shards returns all shards in the order they were passed to NewSharded.
+func (s *Sharded) Shards() []*DbHelper {
+	return s.shards
+}
+
+// AddTable assigns table name to type of i on all shards.
+func (s *Sharded) AddTable(i interface{}, name string) error {
+	for _, dbh := range s.shards {
+		err := dbh.AddTable(i, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Shard returns shard for shard key. Keys with the same string
+// representation, e.g. int(5) and int64(5), are routed to the same shard.
+func (s *Sharded) Shard(key interface{}) *DbHelper {
+	h := fnv.New32a()
+	h.Write([]byte(fmt.Sprint(key)))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// ShardOf returns shard for structure i by value of its field with option
+// 'shardkey'.
+func (s *Sharded) ShardOf(i interface{}) (*DbHelper, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return nil, err
+	}
+
+	// get table
+	tbl, err := s.shards[0].getTable(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if tbl.shardKeyField == nil {
+		return nil, errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field with option 'shardkey'", t))
+	}
+
+	v, err := tbl.structValue(i)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Shard(tbl.shardKeyField.value(v).Interface()), nil
+}
+
+// Insert inserts record to shard of structure i. See DbHelper.Insert.
+func (s *Sharded) Insert(i interface{}) error {
+	dbh, err := s.ShardOf(i)
+	if err != nil {
+		return err
+	}
+
+	return dbh.Insert(i)
+}
+
+// Update updates record in shard of structure i. See DbHelper.Update.
+func (s *Sharded) Update(i interface{}) (int64, error) {
+	dbh, err := s.ShardOf(i)
+	if err != nil {
+		return 0, err
+	}
+
+	return dbh.Update(i)
+}
+
+// Delete deletes record from shard of structure i. See DbHelper.Delete.
+func (s *Sharded) Delete(i interface{}) (int64, error) {
+	dbh, err := s.ShardOf(i)
+	if err != nil {
+		return 0, err
+	}
+
+	return dbh.Delete(i)
+}
+
+// SelectAll selects all records from all shards concurrently to i, which
+// must be a pointer to a slice of pointers. Records are ordered by shards.
+// Returns the first error in the order of shards.
+func (s *Sharded) SelectAll(i interface{}) (int64, error) {
+	if i == nil {
+		return 0, errorNil
+	}
+
+	ptr := reflect.ValueOf(i)
+	if ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Slice {
+		return 0, errors.New("dbhelper: pointer to a slice of pointers expected")
+	}
+
+	// select records of every shard to a separate slice
+	results := make([]reflect.Value, len(s.shards))
+	errs := make([]error, len(s.shards))
+	var wg sync.WaitGroup
+	for n, dbh := range s.shards {
+		results[n] = reflect.New(ptr.Elem().Type())
+
+		wg.Add(1)
+		go func(n int, dbh *DbHelper) {
+			defer wg.Done()
+			_, errs[n] = dbh.SelectAll(results[n].Interface())
+		}(n, dbh)
+	}
+
+	wg.Wait()
+
+	// join results
+	list := reflect.MakeSlice(ptr.Elem().Type(), 0, 0)
+	for n, result := range results {
+		if errs[n] != nil {
+			return 0, errs[n]
+		}
+
+		list = reflect.AppendSlice(list, result.Elem())
+	}
+
+	ptr.Elem().Set(list)
+	return int64(list.Len()), nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"testing"
+)
+
+type testShardStruct struct {
+	Id       int64 `db:"id" dbopt:"id,auto"`
+	TenantId int64 `db:"tenant_id" dbopt:"shardkey"`
+}
+
+func TestSharded(t *testing.T) {
+	_, err := NewSharded()
+	if err == nil {
+		t.Error("error expected without shards")
+		return
+	}
+
+	s, err := NewSharded(New(nil, Postgresql{}), New(nil, Postgresql{}), New(nil, Postgresql{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = s.AddTable(testShardStruct{}, "test_shards")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// records are routed by shard key
+	seen := make(map[*DbHelper]bool)
+	for tenant := int64(1); tenant <= 20; tenant++ {
+		dbh, err := s.ShardOf(&testShardStruct{TenantId: tenant})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		if dbh != s.Shard(tenant) || dbh != s.Shard(int(tenant)) {
+			t.Errorf("tenant %d is routed to different shards", tenant)
+			return
+		}
+
+		seen[dbh] = true
+	}
+
+	if len(seen) != 3 {
+		t.Errorf("records are routed to %d of 3 shards", len(seen))
+		return
+	}
+
+	// structure without shard key
+	err = s.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = s.ShardOf(&testStruct{})
+	if err == nil {
+		t.Error("error expected for structure without shard key")
+		return
+	}
+}
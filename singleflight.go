@@ -0,0 +1,177 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// Error returned to callers waiting for shared query that panicked.
+var errFlightPanic = errors.New("dbhelper: shared query panicked")
+
+// In-flight query shared by concurrent callers.
+type flightCall struct {
+	wg    sync.WaitGroup
+	value reflect.Value
+	num   int64
+	err   error
+}
+
+// Collapses identical concurrent queries into one.
+type flightGroup struct {
+	mutex sync.Mutex
+	calls map[string]*flightCall
+}
+
+// Executes fn once for all concurrent callers using the same key. If fn
+// panics, the panic is propagated to the caller running it and waiting
+// callers get an error.
+func (g *flightGroup) do(key string, fn func() (reflect.Value, int64, error)) (reflect.Value, int64, error) {
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+
+	// wait for query that is already running
+	if c, ok := g.calls[key]; ok {
+		g.mutex.Unlock()
+		c.wg.Wait()
+		return c.value, c.num, c.err
+	}
+
+	c := &flightCall{err: errFlightPanic}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mutex.Unlock()
+
+	// release waiting callers even if fn panics
+	defer func() {
+		g.mutex.Lock()
+		delete(g.calls, key)
+		g.mutex.Unlock()
+		c.wg.Done()
+	}()
+
+	c.value, c.num, c.err = fn()
+	return c.value, c.num, c.err
+}
+
+// Assigns deep copy of src to dst, so that they do not share slices and maps.
+// Values referenced by pointers and unexported fields are still shared.
+func deepCopy(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Slice:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+
+		slice := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for n := 0; n < src.Len(); n++ {
+			deepCopy(slice.Index(n), src.Index(n))
+		}
+
+		dst.Set(slice)
+	case reflect.Map:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			value := reflect.New(src.Type().Elem()).Elem()
+			deepCopy(value, iter.Value())
+			m.SetMapIndex(iter.Key(), value)
+		}
+
+		dst.Set(m)
+	case reflect.Array:
+		for n := 0; n < src.Len(); n++ {
+			deepCopy(dst.Index(n), src.Index(n))
+		}
+	case reflect.Struct:
+		dst.Set(src)
+		for n := 0; n < src.NumField(); n++ {
+			if dst.Field(n).CanSet() {
+				deepCopy(dst.Field(n), src.Field(n))
+			}
+		}
+	default:
+		dst.Set(src)
+	}
+}
+
+// Copies shared query result src to i. Structures referenced by slice
+// elements and their slices and maps are copied, so that callers do not
+// share them.
+func copyResult(i interface{}, src reflect.Value) {
+	dst := reflect.ValueOf(i).Elem()
+	if dst.Kind() != reflect.Slice {
+		deepCopy(dst, src)
+		return
+	}
+
+	slice := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+	elemType := dst.Type().Elem().Elem()
+	for n := 0; n < src.Len(); n++ {
+		p := reflect.New(elemType)
+		deepCopy(p.Elem(), src.Index(n).Elem())
+		slice.Index(n).Set(p)
+	}
+
+	dst.Set(slice)
+}
+
+// Performs select query. Identical concurrent queries performed outside of
+// transactions are executed only once and their result is shared. The query
+// runs with context of the first caller, callers whose context is still
+// active run the query again if it was canceled.
+func (dbh *DbHelper) selectShared(tbl *dbTable, key string, q *Pstmt, i interface{}, params interface{}) (int64, error) {
+	if dbh.tx != nil || dbh.flights == nil {
+		return q.in(dbh).Query(i, params)
+	}
+
+	resultType := reflect.TypeOf(i)
+	if resultType.Kind() != reflect.Ptr {
+		// let Query report the error
+		return q.in(dbh).Query(i, params)
+	}
+
+	value, num, err := dbh.flights.do(tbl.name+":"+cacheKey(i, key)+":"+resultType.String(), func() (reflect.Value, int64, error) {
+		// query to a new value, which is not used by any of callers
+		ptr := reflect.New(resultType.Elem())
+		num, err := q.in(dbh).Query(ptr.Interface(), params)
+		return ptr.Elem(), num, err
+	})
+
+	// context of the first caller was canceled
+	if err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) &&
+		dbh.context().Err() == nil {
+		return q.in(dbh).Query(i, params)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	// single row result is not changed if nothing was found
+	if num > 0 || value.Kind() == reflect.Slice {
+		copyResult(i, value)
+	}
+
+	return num, nil
+}
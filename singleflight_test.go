@@ -0,0 +1,98 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package dbhelper
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testSharedStruct struct {
+	Name  string
+	Data  []byte
+	Attrs map[string]string
+}
+
+func TestFlightPanic(t *testing.T) {
+	g := &flightGroup{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	go func() {
+		defer func() {
+			recover()
+		}()
+
+		g.do("key", func() (reflect.Value, int64, error) {
+			close(started)
+			<-release
+			panic("test")
+		})
+	}()
+
+	<-started
+	done := make(chan error)
+	go func() {
+		_, _, err := g.do("key", func() (reflect.Value, int64, error) {
+			return reflect.Value{}, 0, nil
+		})
+		done <- err
+	}()
+
+	// let second caller wait for the first one
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	select {
+	case err := <-done:
+		if err != errFlightPanic {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+	case <-time.After(time.Second):
+		t.Error("waiting caller is blocked")
+		return
+	}
+
+	// key is released
+	_, num, err := g.do("key", func() (reflect.Value, int64, error) {
+		return reflect.Value{}, 1, nil
+	})
+	if err != nil || num != 1 {
+		t.Errorf("unexpected result: %d, %v", num, err)
+		return
+	}
+}
+
+func TestCopyResult(t *testing.T) {
+	src := testSharedStruct{
+		Name:  "a",
+		Data:  []byte("abc"),
+		Attrs: map[string]string{"k": "v"},
+	}
+
+	var dst testSharedStruct
+	copyResult(&dst, reflect.ValueOf(src))
+	if !reflect.DeepEqual(dst, src) {
+		t.Errorf("unexpected copy: %v", dst)
+		return
+	}
+
+	dst.Data[0] = 'x'
+	dst.Attrs["k"] = "x"
+	if string(src.Data) != "abc" || src.Attrs["k"] != "v" {
+		t.Errorf("shared result is changed: %v", src)
+		return
+	}
+
+	var slice []*testSharedStruct
+	copyResult(&slice, reflect.ValueOf([]*testSharedStruct{&src}))
+	slice[0].Data[0] = 'x'
+	if len(slice) != 1 || slice[0] == &src || string(src.Data) != "abc" {
+		t.Errorf("shared result is changed: %v", src)
+		return
+	}
+}
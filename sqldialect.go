@@ -12,30 +12,294 @@
 package dbhelper
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
+	"time"
 )
 
-// Holds information specific for different database dialects.
+// SqlDialect holds information specific for different database dialects.
+// Besides built-in dialects, other databases can be supported by implementing
+// this interface. Optional features are enabled by implementing interfaces
+// Has*, e.g. HasInsertPostfix, which are checked at run time.
 type SqlDialect interface {
 	// Placeholders are different for different database dialects.
-	placeholder() placeholder
+	Placeholder() Placeholder
+
+	// Column types are different for different database dialects.
+	ColumnType(c ColumnInfo) string
+
+	// Expression returning current unix timestamp.
+	UnixTimestamp() string
+
+	// Statements creating trigger that sets column to current timestamp on
+	// update. Nil if triggers are not supported.
+	ModifiedTrigger(name string, table string, column string, id string) []string
+
+	// Statements dropping trigger created by ModifiedTrigger.
+	DropModifiedTrigger(name string, table string) []string
+
+	// Capabilities returns features supported by database.
+	Capabilities() Capabilities
+}
+
+// ColumnInfo describes column of registered table. It is passed to SQL
+// dialects and returned by Tables.
+type ColumnInfo struct {
+	// Name of the column.
+	Name string
+
+	// Name of structure field.
+	Field string
+
+	// Type of structure field.
+	Type reflect.Type
+
+	// Kind of the column, if it is not defined by field type: "decimal",
+	// "inet", "cidr", "interval" or "geometry".
+	Kind string
+
+	// Column identifies rows of the table.
+	Id bool
+
+	// Values of the column are generated by database.
+	Auto bool
+
+	// Column is a full-text search document maintained by database.
+	TsVector bool
+
+	// Options of the field, as in tag 'dbopt'.
+	Options []string
+
+	// Valid values of enum field.
+	Enum []interface{}
+}
+
+// TableInfo describes registered table. It is passed to SQL dialects and
+// returned by Tables.
+type TableInfo struct {
+	// Name of the table.
+	Name string
+
+	// Structure type mapped to the table.
+	Type reflect.Type
+
+	// Column identifying rows of the table, zero value for views without id.
+	Id ColumnInfo
+
+	// All columns in the order of structure fields.
+	Columns []ColumnInfo
+
+	// Table is a read-only view.
+	View bool
+
+	// Previous states of records are stored in history table.
+	History bool
+
+	// Options of registration.
+	Options TableOptions
+}
+
+// Capabilities describes features supported by database of SQL dialect.
+type Capabilities struct {
+	// Modified rows can be returned by insert, update and delete statements.
+	Returning bool
+
+	// Existing row can be updated by insert statement (Upsert).
+	Upsert bool
+
+	// Columns can store arrays.
+	Arrays bool
+
+	// Transactions can be partially rolled back to savepoints.
+	Savepoints bool
+
+	// Selected rows can be locked until the end of transaction.
+	LockingSelect bool
+
+	// Queries can use recursive common table expressions.
+	RecursiveQueries bool
+
+	// Transactions can be prepared for two-phase commit.
+	TwoPhaseCommit bool
+
+	// Statements changing schema (CREATE TABLE etc.) are rolled back
+	// together with transaction.
+	TransactionalDDL bool
+
+	// Maximum number of parameters of a single statement, 0 if unlimited.
+	MaxParams int
+}
+
+// Capabilities returns features supported by database.
+func (dbh *DbHelper) Capabilities() Capabilities {
+	return dbh.sqlDialect.Capabilities()
+}
+
+// HasAutoIncrement is implemented by dialects marking auto-incremented
+// columns in table definition with a keyword.
+type HasAutoIncrement interface {
+	AutoIncrement() string
+}
+
+// HasInsertPostfix is implemented by dialects adding postfix to insert
+// statement. Sometimes needed to get last inserted id.
+type HasInsertPostfix interface {
+	InsertPostfix(t TableInfo) string
+}
+
+// HasCustomInsert is implemented by dialects executing insert query in a
+// custom way. Sometimes needed to get last inserted id.
+type HasCustomInsert interface {
+	// Executes insert query pstmt, which is bound to transaction if
+	// needed, and returns id of inserted row.
+	Insert(t TableInfo, pstmt *Pstmt, params interface{}) (interface{}, error)
+}
+
+// HasStatementTimeout is implemented by dialects limiting execution time
+// of statements within a transaction.
+type HasStatementTimeout interface {
+	StatementTimeout(d time.Duration) string
+}
+
+// HasLockTimeout is implemented by dialects limiting time of waiting for
+// locks within a transaction.
+type HasLockTimeout interface {
+	LockTimeout(d time.Duration) string
+}
+
+// HasTwoPhaseCommit is implemented by dialects preparing transactions for
+// two-phase commit. Prepared transaction survives end of session and is
+// committed or rolled back by id outside of transaction.
+type HasTwoPhaseCommit interface {
+	PrepareTransaction(id string) string
+	CommitPrepared(id string) string
+	RollbackPrepared(id string) string
 }
 
-// Postfix for insert statement. Sometimes needed to get last inserted id.
-type hasInsertPostfix interface {
-	// Sometimes needed to last inserted id.
-	insertPostfix(tbl *dbTable) string
+// HasFullTextSearch is implemented by dialects supporting full-text search.
+type HasFullTextSearch interface {
+	FullTextSearch(column string, param string) (cond string, rank string)
 }
 
-// Actions after execution of insert query. Sometimes needed to get last inserted id.
-type hasCustomInsert interface {
-	// Sometimes needed to last inserted id.
-	insert(tbl *dbTable, params map[string]interface{}) (int64, error)
+// HasMaterializedViews is implemented by dialects supporting materialized views.
+type HasMaterializedViews interface {
+	RefreshMaterializedView(name string, concurrently bool) string
 }
 
-// Placeholder interface.
-type placeholder interface {
-	next() string
+// HasInterval is implemented by dialects storing time.Duration fields in
+// native interval columns.
+type HasInterval interface {
+	Interval(d time.Duration) string
+}
+
+// HasGeometry is implemented by dialects storing Point fields in geometry
+// columns.
+type HasGeometry interface {
+	GeometryFromText(param string, srid int) string
+}
+
+// HasReturning is implemented by dialects returning columns of modified rows.
+type HasReturning interface {
+	Returning(columns string) string
+}
+
+// HasLockingSelect is implemented by dialects locking selected rows until
+// the end of transaction.
+type HasLockingSelect interface {
+	ForUpdate() string
+}
+
+// HasQuoting is implemented by dialects quoting table and column names.
+type HasQuoting interface {
+	Quote(name string) string
+}
+
+// HasLimit is implemented by dialects limiting number of returned rows
+// differently from LIMIT n OFFSET m.
+type HasLimit interface {
+	Limit(limit int, offset int) string
+}
+
+// HasUpsert is implemented by dialects updating existing row with the same
+// id by insert statement.
+type HasUpsert interface {
+	Upsert(id string, columns []string) string
+}
+
+// HasSequences is implemented by dialects supporting sequences.
+type HasSequences interface {
+	CreateSequence(name string) string
+	SetSequenceOwner(name string, table string, column string) string
+	NextValue(name string) string
+	ResetSequence(table string, column string) string
+}
+
+// HasPartitions is implemented by dialects supporting table partitions.
+type HasPartitions interface {
+	PartitionBy(method string, column string) string
+	CreatePartition(parent string, name string, bound string) string
+	AttachPartition(parent string, name string, bound string) string
+	DetachPartition(parent string, name string) string
+}
+
+// HasTableEngine is implemented by dialects adding table engine clause to
+// table definition. Tables with engine have no primary key and check
+// constraints, rows are ordered by id instead.
+type HasTableEngine interface {
+	TableEngine(id string) string
+}
+
+// HasSearch is implemented by dialects with custom case insensitive search.
+type HasSearch interface {
+	Search(column string, param string) string
+}
+
+// Returns quoted name, if SQL dialect quotes names. Names containing schema
+// are quoted by parts.
+func (dbh *DbHelper) quote(name string) string {
+	sqld, ok := dbh.sqlDialect.(HasQuoting)
+	if !ok {
+		return name
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = sqld.Quote(part)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// Returns clause limiting number of returned rows. Zero limit or offset
+// means that it is not applied.
+func limitClause(sqld SqlDialect, limit int, offset int) string {
+	if limit <= 0 && offset <= 0 {
+		return ""
+	}
+
+	if sqld, ok := sqld.(HasLimit); ok {
+		return " " + sqld.Limit(limit, offset)
+	}
+
+	clause := ""
+	if limit > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	if offset > 0 {
+		clause += fmt.Sprintf(" OFFSET %d", offset)
+	}
+
+	return clause
+}
+
+// Placeholder generates placeholders of query parameters. New generator
+// is used for every query.
+type Placeholder interface {
+	// Returns placeholder of the next parameter.
+	Next() string
 }
 
 // Placeholder format: "?".
@@ -43,7 +307,7 @@ type standardPlaceholder struct {
 }
 
 // Returns next placeholder.
-func (ph *standardPlaceholder) next() string {
+func (ph *standardPlaceholder) Next() string {
 	return "?"
 }
 
@@ -56,24 +320,232 @@ type Postgresql struct {
 }
 
 // Returns placeholder generator.
-func (sqld Postgresql) placeholder() placeholder {
+func (sqld Postgresql) Placeholder() Placeholder {
 	return &pgsqlPlaceholder{0}
 }
 
+// Returns features supported by database.
+func (sqld Postgresql) Capabilities() Capabilities {
+	return Capabilities{
+		Returning:        true,
+		Upsert:           true,
+		Arrays:           true,
+		Savepoints:       true,
+		LockingSelect:    true,
+		RecursiveQueries: true,
+		TwoPhaseCommit:   true,
+		TransactionalDDL: true,
+		MaxParams:        65535,
+	}
+}
+
 // Postfix needed for Postgresql to return last inserted id.
-func (sqld Postgresql) insertPostfix(tbl *dbTable) string {
-	return fmt.Sprintf("RETURNING %s", tbl.idField.column)
+func (sqld Postgresql) InsertPostfix(t TableInfo) string {
+	return fmt.Sprintf("RETURNING %s", t.Id.Name)
 }
 
 // Custom insert query for Postgresql databse is needed to return last inserted record id.
-func (sqld Postgresql) insert(tbl *dbTable, params map[string]interface{}) (int64, error) {
-	var id int64
-	_, err := tbl.insertQuery.Query(&id, params)
+// Returned id has the type of id field, so ids generated by database, like
+// uuid DEFAULT gen_random_uuid(), can be returned.
+func (sqld Postgresql) Insert(t TableInfo, pstmt *Pstmt, params interface{}) (interface{}, error) {
+	id := reflect.New(t.Id.Type)
+	_, err := pstmt.Query(id.Interface(), params)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+
+	return id.Elem().Interface(), nil
+}
+
+// Returns column type for field.
+func (sqld Postgresql) ColumnType(c ColumnInfo) string {
+	if c.TsVector {
+		return "tsvector"
+	}
+
+	switch c.Kind {
+	case kindDecimal:
+		return "numeric"
+	case kindInet:
+		return "inet"
+	case kindCidr:
+		return "cidr"
+	case kindInterval:
+		return "interval"
+	case kindGeometry:
+		return fmt.Sprintf("geometry(Point, %d)", pointSRID)
+	}
+
+	switch c.Type.Kind() {
+	case reflect.String:
+		if c.Auto {
+			return "uuid DEFAULT gen_random_uuid()"
+		}
+	case reflect.Int, reflect.Int64:
+		if c.Auto {
+			return "bigserial"
+		}
+		return "bigint"
+	case reflect.Int32:
+		if c.Auto {
+			return "serial"
+		}
+		return "integer"
+	case reflect.Int8, reflect.Int16:
+		return "smallint"
+	case reflect.Float32:
+		return "real"
+	case reflect.Float64:
+		return "double precision"
+	case reflect.Bool:
+		return "boolean"
+	}
+
+	return "text"
+}
+
+// Returns clause updating existing row on conflict.
+func (sqld Postgresql) Upsert(id string, columns []string) string {
+	return conflictUpsert(id, columns)
+}
+
+// Returns clause updating existing row on conflict of id, which is used by
+// Postgresql and Sqlite.
+func conflictUpsert(id string, columns []string) string {
+	if len(columns) == 0 {
+		return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", id)
+	}
+
+	set := make([]string, len(columns))
+	for i, col := range columns {
+		set[i] = fmt.Sprintf("%s = EXCLUDED.%s", col, col)
+	}
+
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", id, strings.Join(set, ", "))
+}
+
+// Returns clause returning columns of modified rows.
+func (sqld Postgresql) Returning(columns string) string {
+	return fmt.Sprintf("RETURNING %s", columns)
+}
+
+// Returns PostGIS function creating geometry from WKT.
+func (sqld Postgresql) GeometryFromText(param string, srid int) string {
+	return fmt.Sprintf("ST_GeomFromText(%s, %d)", param, srid)
+}
+
+// Returns interval value of duration d.
+func (sqld Postgresql) Interval(d time.Duration) string {
+	return fmt.Sprintf("%d microseconds", d.Nanoseconds()/int64(time.Microsecond))
+}
+
+// Returns statement preparing the current transaction for two-phase commit.
+func (sqld Postgresql) PrepareTransaction(id string) string {
+	return fmt.Sprintf("PREPARE TRANSACTION '%s'", id)
+}
+
+// Returns statement committing prepared transaction.
+func (sqld Postgresql) CommitPrepared(id string) string {
+	return fmt.Sprintf("COMMIT PREPARED '%s'", id)
+}
+
+// Returns statement rolling back prepared transaction.
+func (sqld Postgresql) RollbackPrepared(id string) string {
+	return fmt.Sprintf("ROLLBACK PREPARED '%s'", id)
+}
+
+// Returns statement setting statement timeout for the current transaction.
+func (sqld Postgresql) StatementTimeout(d time.Duration) string {
+	return fmt.Sprintf("SET LOCAL statement_timeout = %d", d.Nanoseconds()/int64(time.Millisecond))
+}
+
+// Returns statement setting lock timeout for the current transaction.
+func (sqld Postgresql) LockTimeout(d time.Duration) string {
+	return fmt.Sprintf("SET LOCAL lock_timeout = %d", d.Nanoseconds()/int64(time.Millisecond))
+}
+
+// Returns case insensitive search condition.
+func (sqld Postgresql) Search(column string, param string) string {
+	return fmt.Sprintf("%s ILIKE %s", column, param)
+}
+
+// Returns full-text search condition and rank expression.
+func (sqld Postgresql) FullTextSearch(column string, param string) (string, string) {
+	return fmt.Sprintf("%s @@ to_tsquery(%s)", column, param),
+		fmt.Sprintf("ts_rank(%s, to_tsquery(%s))", column, param)
+}
+
+// Returns statement refreshing materialized view.
+func (sqld Postgresql) RefreshMaterializedView(name string, concurrently bool) string {
+	if concurrently {
+		return fmt.Sprintf("REFRESH MATERIALIZED VIEW CONCURRENTLY %s", name)
 	}
 
-	return id, nil
+	return fmt.Sprintf("REFRESH MATERIALIZED VIEW %s", name)
+}
+
+// Returns clause of partitioned table definition.
+func (sqld Postgresql) PartitionBy(method string, column string) string {
+	return fmt.Sprintf("PARTITION BY %s (%s)", method, column)
+}
+
+// Returns statement creating partition.
+func (sqld Postgresql) CreatePartition(parent string, name string, bound string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s %s", name, parent, bound)
+}
+
+// Returns statement attaching table as a partition.
+func (sqld Postgresql) AttachPartition(parent string, name string, bound string) string {
+	return fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s %s", parent, name, bound)
+}
+
+// Returns statement detaching partition.
+func (sqld Postgresql) DetachPartition(parent string, name string) string {
+	return fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s", parent, name)
+}
+
+// Returns statement creating sequence.
+func (sqld Postgresql) CreateSequence(name string) string {
+	return fmt.Sprintf("CREATE SEQUENCE IF NOT EXISTS %s", name)
+}
+
+// Returns statement making sequence owned by column.
+func (sqld Postgresql) SetSequenceOwner(name string, table string, column string) string {
+	return fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s.%s", name, table, column)
+}
+
+// Returns query selecting next value of sequence.
+func (sqld Postgresql) NextValue(name string) string {
+	return fmt.Sprintf("SELECT nextval('%s')", name)
+}
+
+// Returns statement setting sequence of serial column to the maximum id.
+func (sqld Postgresql) ResetSequence(table string, column string) string {
+	return fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE(MAX(%s), 1)) FROM %s",
+		table, column, column, table)
+}
+
+// Returns expression returning current unix timestamp.
+func (sqld Postgresql) UnixTimestamp() string {
+	return "EXTRACT(EPOCH FROM NOW())::bigint"
+}
+
+// Returns statements creating trigger function and trigger.
+func (sqld Postgresql) ModifiedTrigger(name string, table string, column string, id string) []string {
+	return []string{
+		fmt.Sprintf("CREATE OR REPLACE FUNCTION %s() RETURNS trigger AS $$ "+
+			"BEGIN NEW.%s := %s; RETURN NEW; END $$ LANGUAGE plpgsql", name, column, sqld.UnixTimestamp()),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", name, table),
+		fmt.Sprintf("CREATE TRIGGER %s BEFORE UPDATE ON %s FOR EACH ROW EXECUTE PROCEDURE %s()", name, table, name),
+	}
+}
+
+// Returns statements dropping trigger and trigger function.
+func (sqld Postgresql) DropModifiedTrigger(name string, table string) []string {
+	return []string{
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", name, table),
+		fmt.Sprintf("DROP FUNCTION IF EXISTS %s()", name),
+	}
 }
 
 // Placeholder format: "$n".
@@ -82,7 +554,7 @@ type pgsqlPlaceholder struct {
 }
 
 // Returns next placeholder.
-func (ph *pgsqlPlaceholder) next() string {
+func (ph *pgsqlPlaceholder) Next() string {
 	ph.n++
 	return fmt.Sprintf("$%d", ph.n)
 }
@@ -96,10 +568,123 @@ type MySql struct {
 }
 
 // Returns placeholder generator.
-func (sqld MySql) placeholder() placeholder {
+func (sqld MySql) Placeholder() Placeholder {
 	return &standardPlaceholder{}
 }
 
+// Returns features supported by database.
+func (sqld MySql) Capabilities() Capabilities {
+	return Capabilities{
+		Upsert:        true,
+		Savepoints:    true,
+		LockingSelect: true,
+		MaxParams:     65535,
+	}
+}
+
+// Returns column type for field.
+func (sqld MySql) ColumnType(c ColumnInfo) string {
+	switch c.Kind {
+	case kindDecimal:
+		return "DECIMAL(65,30)"
+	case kindInet, kindCidr:
+		return "VARCHAR(49)"
+	case kindGeometry:
+		return fmt.Sprintf("POINT SRID %d", pointSRID)
+	}
+
+	switch c.Type.Kind() {
+	case reflect.Int, reflect.Int64:
+		return "BIGINT"
+	case reflect.Int32:
+		return "INT"
+	case reflect.Int16:
+		return "SMALLINT"
+	case reflect.Int8:
+		return "TINYINT"
+	case reflect.Float32:
+		return "FLOAT"
+	case reflect.Float64:
+		return "DOUBLE"
+	case reflect.Bool:
+		return "BOOLEAN"
+	}
+
+	return "VARCHAR(255)"
+}
+
+// Returns keyword marking auto-incremented column.
+func (sqld MySql) AutoIncrement() string {
+	return "AUTO_INCREMENT"
+}
+
+// Returns expression returning current unix timestamp.
+func (sqld MySql) UnixTimestamp() string {
+	return "UNIX_TIMESTAMP()"
+}
+
+// Returns statements creating trigger.
+func (sqld MySql) ModifiedTrigger(name string, table string, column string, id string) []string {
+	return []string{
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s", name),
+		fmt.Sprintf("CREATE TRIGGER %s BEFORE UPDATE ON %s FOR EACH ROW SET NEW.%s = %s",
+			name, table, column, sqld.UnixTimestamp()),
+	}
+}
+
+// Returns statements dropping trigger.
+func (sqld MySql) DropModifiedTrigger(name string, table string) []string {
+	return []string{fmt.Sprintf("DROP TRIGGER IF EXISTS %s", name)}
+}
+
+// Returns name quoted with backticks.
+func (sqld MySql) Quote(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// Returns limit clause. Offset cannot be used without limit.
+func (sqld MySql) Limit(limit int, offset int) string {
+	if limit <= 0 {
+		return fmt.Sprintf("LIMIT %d, 18446744073709551615", offset)
+	}
+
+	if offset > 0 {
+		return fmt.Sprintf("LIMIT %d, %d", offset, limit)
+	}
+
+	return fmt.Sprintf("LIMIT %d", limit)
+}
+
+// Returns clause updating existing row with duplicate key.
+func (sqld MySql) Upsert(id string, columns []string) string {
+	// no-op assignment, if there is nothing to update
+	if len(columns) == 0 {
+		return fmt.Sprintf("ON DUPLICATE KEY UPDATE %s = %s", id, id)
+	}
+
+	set := make([]string, len(columns))
+	for i, col := range columns {
+		set[i] = fmt.Sprintf("%s = VALUES(%s)", col, col)
+	}
+
+	return fmt.Sprintf("ON DUPLICATE KEY UPDATE %s", strings.Join(set, ", "))
+}
+
+// Returns clause locking selected rows.
+func (sqld MySql) ForUpdate() string {
+	return "FOR UPDATE"
+}
+
+// Returns function creating geometry from WKT.
+func (sqld MySql) GeometryFromText(param string, srid int) string {
+	return fmt.Sprintf("ST_GeomFromText(%s, %d)", param, srid)
+}
+
+// Returns case insensitive search condition. Backslash is the default escape character.
+func (sqld MySql) Search(column string, param string) string {
+	return fmt.Sprintf("LOWER(%s) LIKE LOWER(%s)", column, param)
+}
+
 //
 // Sqlite
 //
@@ -109,6 +694,171 @@ type Sqlite struct {
 }
 
 // Returns placeholder generator.
-func (sqld Sqlite) placeholder() placeholder {
+func (sqld Sqlite) Placeholder() Placeholder {
 	return &standardPlaceholder{}
 }
+
+// Returns features supported by database. Maximum number of parameters
+// is the default limit of Sqlite before version 3.32.0.
+func (sqld Sqlite) Capabilities() Capabilities {
+	return Capabilities{
+		Upsert:           true,
+		Savepoints:       true,
+		RecursiveQueries: true,
+		TransactionalDDL: true,
+		MaxParams:        999,
+	}
+}
+
+// Returns column type for field.
+func (sqld Sqlite) ColumnType(c ColumnInfo) string {
+	switch c.Kind {
+	case kindDecimal:
+		return "NUMERIC"
+	case kindGeometry:
+		return "POINT"
+	}
+
+	switch c.Type.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "INTEGER"
+	case reflect.Float32, reflect.Float64:
+		return "REAL"
+	case reflect.Bool:
+		return "BOOLEAN"
+	}
+
+	return "TEXT"
+}
+
+// Returns keyword marking auto-incremented column.
+func (sqld Sqlite) AutoIncrement() string {
+	return "AUTOINCREMENT"
+}
+
+// Returns expression returning current unix timestamp.
+func (sqld Sqlite) UnixTimestamp() string {
+	return "CAST(strftime('%s', 'now') AS INTEGER)"
+}
+
+// Returns statements creating trigger. Sqlite cannot modify new row in
+// BEFORE trigger, so row is updated after update.
+func (sqld Sqlite) ModifiedTrigger(name string, table string, column string, id string) []string {
+	return []string{
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s", name),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER UPDATE ON %s FOR EACH ROW BEGIN "+
+			"UPDATE %s SET %s = %s WHERE %s = NEW.%s; END",
+			name, table, table, column, sqld.UnixTimestamp(), id, id),
+	}
+}
+
+// Returns statements dropping trigger.
+func (sqld Sqlite) DropModifiedTrigger(name string, table string) []string {
+	return []string{fmt.Sprintf("DROP TRIGGER IF EXISTS %s", name)}
+}
+
+// Returns SpatiaLite function creating geometry from WKT.
+func (sqld Sqlite) GeometryFromText(param string, srid int) string {
+	return fmt.Sprintf("GeomFromText(%s, %d)", param, srid)
+}
+
+// Returns limit clause. Offset cannot be used without limit.
+func (sqld Sqlite) Limit(limit int, offset int) string {
+	if limit <= 0 {
+		limit = -1
+	}
+
+	if offset > 0 {
+		return fmt.Sprintf("LIMIT %d OFFSET %d", limit, offset)
+	}
+
+	return fmt.Sprintf("LIMIT %d", limit)
+}
+
+// Returns clause updating existing row on conflict.
+func (sqld Sqlite) Upsert(id string, columns []string) string {
+	return conflictUpsert(id, columns)
+}
+
+//
+// ClickHouse
+//
+
+// ClickHouse SQL dialect for analytical tables. ClickHouse does not generate
+// ids, so structures with option 'auto' require IdGenerator. Rows are
+// inserted in blocks, so InsertBatch should be preferred to Insert.
+// Placeholders are "?", named placeholders are replaced by Prepare as usual.
+// Update and Delete are not supported.
+type ClickHouse struct {
+}
+
+// Returns placeholder generator.
+func (sqld ClickHouse) Placeholder() Placeholder {
+	return &standardPlaceholder{}
+}
+
+// Returns features supported by database.
+func (sqld ClickHouse) Capabilities() Capabilities {
+	return Capabilities{
+		Arrays: true,
+	}
+}
+
+// Returns column type for field.
+func (sqld ClickHouse) ColumnType(c ColumnInfo) string {
+	switch c.Kind {
+	case kindDecimal:
+		return "Decimal(38, 18)"
+	case kindInet, kindCidr, kindGeometry:
+		return "String"
+	}
+
+	switch c.Type.Kind() {
+	case reflect.String:
+		if c.Auto {
+			return "UUID DEFAULT generateUUIDv4()"
+		}
+	case reflect.Int8:
+		return "Int8"
+	case reflect.Int16:
+		return "Int16"
+	case reflect.Int32:
+		return "Int32"
+	case reflect.Int, reflect.Int64:
+		return "Int64"
+	case reflect.Float32:
+		return "Float32"
+	case reflect.Float64:
+		return "Float64"
+	case reflect.Bool:
+		return "Bool"
+	}
+
+	return "String"
+}
+
+// Returns expression returning current unix timestamp.
+func (sqld ClickHouse) UnixTimestamp() string {
+	return "toUnixTimestamp(now())"
+}
+
+// ClickHouse has no triggers.
+func (sqld ClickHouse) ModifiedTrigger(name string, table string, column string, id string) []string {
+	return nil
+}
+
+// ClickHouse has no triggers.
+func (sqld ClickHouse) DropModifiedTrigger(name string, table string) []string {
+	return nil
+}
+
+// Returns table engine clause.
+func (sqld ClickHouse) TableEngine(id string) string {
+	return fmt.Sprintf("ENGINE = MergeTree ORDER BY %s", id)
+}
+
+// Ids generated by database cannot be obtained.
+func (sqld ClickHouse) Insert(t TableInfo, pstmt *Pstmt, params interface{}) (interface{}, error) {
+	return nil, errors.New(fmt.Sprintf("dbhelper: ClickHouse cannot generate ids of table '%s', IdGenerator is required",
+		t.Name))
+}
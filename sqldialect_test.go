@@ -0,0 +1,204 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMySqlDialect(t *testing.T) {
+	dbh := New(nil, MySql{})
+	if dbh.quote("db.test`s") != "`db`.`test``s`" {
+		t.Errorf("unexpected quoted name: %s", dbh.quote("db.test`s"))
+		return
+	}
+
+	limits := map[[2]int]string{
+		{10, 0}:  " LIMIT 10",
+		{10, 20}: " LIMIT 20, 10",
+		{0, 20}:  " LIMIT 20, 18446744073709551615",
+		{0, 0}:   "",
+	}
+
+	for l, expected := range limits {
+		if limitClause(dbh.sqlDialect, l[0], l[1]) != expected {
+			t.Errorf("unexpected limit clause: %s", limitClause(dbh.sqlDialect, l[0], l[1]))
+			return
+		}
+	}
+
+	upsert := MySql{}.Upsert("`id`", []string{"`b`", "`m`"})
+	if upsert != "ON DUPLICATE KEY UPDATE `b` = VALUES(`b`), `m` = VALUES(`m`)" {
+		t.Errorf("unexpected upsert clause: %s", upsert)
+		return
+	}
+
+	upsert = Postgresql{}.Upsert("id", []string{"b"})
+	if upsert != "ON CONFLICT (id) DO UPDATE SET b = EXCLUDED.b" {
+		t.Errorf("unexpected upsert clause: %s", upsert)
+		return
+	}
+
+	if limitClause(Sqlite{}, 0, 5) != " LIMIT -1 OFFSET 5" {
+		t.Errorf("unexpected limit clause: %s", limitClause(Sqlite{}, 0, 5))
+		return
+	}
+}
+
+type testClickHouseStruct struct {
+	Id     string `db:"id" dbopt:"id,auto"`
+	Amount int64  `db:"amount"`
+	Status string `db:"status" dbopt:"enum=new|done"`
+}
+
+func TestClickHouseDialect(t *testing.T) {
+	dbh := New(nil, ClickHouse{})
+	err := dbh.AddTable(testClickHouseStruct{}, "test_events")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testClickHouseStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	query := tbl.createTableQuery()
+	if query != "CREATE TABLE IF NOT EXISTS test_events (id UUID DEFAULT generateUUIDv4(), "+
+		"amount Int64, status String) ENGINE = MergeTree ORDER BY id" {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+
+	// insert of slice requires slice
+	err = dbh.InsertBatch(testClickHouseStruct{})
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	if !New(nil, Postgresql{}).Capabilities().Returning {
+		t.Error("Postgresql supports RETURNING")
+		return
+	}
+
+	if New(nil, MySql{}).Capabilities().Returning {
+		t.Error("MySql does not support RETURNING")
+		return
+	}
+
+	if New(nil, Sqlite{}).Capabilities().MaxParams != 999 {
+		t.Error("unexpected maximum number of parameters of Sqlite")
+		return
+	}
+
+	if New(nil, ClickHouse{}).Capabilities().Savepoints {
+		t.Error("ClickHouse does not support savepoints")
+		return
+	}
+
+	if New(nil, MySql{}).Capabilities().TransactionalDDL {
+		t.Error("MySql commits DDL statements")
+		return
+	}
+}
+
+func TestMaxParams(t *testing.T) {
+	dbh := New(nil, Sqlite{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, _ := dbh.getTable(reflect.TypeOf(testStruct{}))
+
+	// values are split to chunks allowed by dialect
+	values := make([]interface{}, 2000)
+	var sizes []int
+	err = dbh.inChunks(tbl, values, func(chunk []interface{}) error {
+		sizes = append(sizes, len(chunk))
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if !reflect.DeepEqual(sizes, []int{999, 999, 2}) {
+		t.Errorf("unexpected chunks: %v", sizes)
+		return
+	}
+
+	// too many parameters are reported before query is prepared
+	names := make([]string, 1000)
+	for n := range names {
+		names[n] = fmt.Sprintf(":p%d", n)
+	}
+
+	_, err = dbh.Prepare(fmt.Sprintf("SELECT * FROM test WHERE id IN (%s)", strings.Join(names, ", ")))
+	if err == nil || !strings.Contains(err.Error(), "at most 999") {
+		t.Errorf("error expected: %v", err)
+		return
+	}
+}
+
+// Dialect implemented outside of the package.
+type testCustomDialect struct {
+	Sqlite
+}
+
+func (sqld testCustomDialect) Quote(name string) string {
+	return "[" + name + "]"
+}
+
+func (sqld testCustomDialect) ColumnType(c ColumnInfo) string {
+	if c.Id {
+		return "BIGINT"
+	}
+
+	return sqld.Sqlite.ColumnType(c)
+}
+
+func TestCustomDialect(t *testing.T) {
+	dbh := New(nil, testCustomDialect{})
+	err := dbh.AddTable(testClickHouseStruct{}, "test_custom")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testClickHouseStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	query := tbl.createTableQuery()
+	if !strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS [test_custom] ([id] BIGINT PRIMARY KEY AUTOINCREMENT, [amount] INTEGER NOT NULL") {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+
+	info := tbl.info()
+	if info.Name != "test_custom" || info.Id.Name != "id" || len(info.Columns) != 3 {
+		t.Errorf("unexpected table info: %v", info)
+		return
+	}
+}
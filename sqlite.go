@@ -0,0 +1,100 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql"
+	"sort"
+)
+
+// SqlitePragmas are recommended settings of Sqlite connection applied by
+// NewSqlite: write-ahead log, enforced foreign keys and waiting for locks
+// instead of failing immediately.
+var SqlitePragmas = []string{
+	"PRAGMA journal_mode = WAL",
+	"PRAGMA foreign_keys = ON",
+	"PRAGMA busy_timeout = 5000",
+}
+
+// Executes pragmas on db.
+func applyPragmas(db *sql.DB, pragmas []string) error {
+	for _, pragma := range pragmas {
+		_, err := db.Exec(pragma)
+		if err != nil {
+			return wrapError(err)
+		}
+	}
+
+	return nil
+}
+
+// NewSqlite returns DbHelper using Sqlite dialect and applies SqlitePragmas
+// to db. Except journal_mode, pragmas are settings of a single connection,
+// so number of open connections of db is limited to one.
+func NewSqlite(db *sql.DB) (*DbHelper, error) {
+	db.SetMaxOpenConns(1)
+
+	err := applyPragmas(db, SqlitePragmas)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(db, Sqlite{}), nil
+}
+
+// NewSqliteMemory opens in-memory Sqlite database using driver registered
+// as driverName, registers tables and creates them. Keys of tables are table
+// names, values are structures as for AddTable. Every connection to in-memory
+// database opens a new empty database, so only one connection is used.
+// Database is closed by closing Db of returned DbHelper.
+func NewSqliteMemory(driverName string, tables map[string]interface{}) (*DbHelper, error) {
+	db, err := sql.Open(driverName, ":memory:")
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	// database is lost when connection is closed
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+
+	// in-memory database does not use write-ahead log
+	err = applyPragmas(db, SqlitePragmas[1:])
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	dbh := New(db, Sqlite{})
+
+	// create tables in stable order
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		err = dbh.AddTable(tables[name], name)
+		if err == nil {
+			err = dbh.CreateTable(tables[name])
+		}
+
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return dbh, nil
+}
@@ -0,0 +1,27 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"testing"
+)
+
+func TestNewSqliteMemoryUnknownDriver(t *testing.T) {
+	dbh, err := NewSqliteMemory("dbhelper-unknown", map[string]interface{}{
+		"test": testStruct{},
+	})
+
+	if err == nil || dbh != nil {
+		t.Error("unknown driver must be reported")
+		return
+	}
+}
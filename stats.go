@@ -0,0 +1,166 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+// PoolSample is a snapshot of statistics of connection pool recorded by
+// sampler started by StartSampler. It can be encoded to JSON.
+type PoolSample struct {
+	// Time of the snapshot.
+	Time time.Time `json:"time"`
+
+	// Statistics of connection pool: connections in use and idle, number
+	// and total duration of waits for a connection.
+	Stats sql.DBStats `json:"stats"`
+}
+
+// SamplerOptions are options of sampler started by StartSampler.
+type SamplerOptions struct {
+	// Interval between samples, 10 seconds if zero.
+	Interval time.Duration
+
+	// Number of the latest samples kept, 60 if zero.
+	Size int
+
+	// Optional hook called with each sample, e.g. to export metrics.
+	// It is called by goroutine of the sampler and must not block.
+	OnSample func(s PoolSample)
+}
+
+// Records statistics of connection pool in a ring buffer.
+type sampler struct {
+	db   *sql.DB
+	opts SamplerOptions
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+
+	mutex   sync.Mutex
+	samples []PoolSample
+	next    int
+}
+
+// StartSampler starts background goroutine recording statistics of
+// connection pool every opts.Interval, which are returned by Stats. Samples
+// recorded over time help to diagnose exhaustion of the pool, e.g. caused
+// by rows that are not closed: connections in use grow while idle ones
+// disappear and wait count increases. Sampler must be started before
+// DbHelper is copied, e.g. by Begin or WithContext, and stopped by
+// StopSampler.
+func (dbh *DbHelper) StartSampler(opts SamplerOptions) error {
+	if dbh.sampler != nil {
+		return errors.New("dbhelper: sampler has already been started")
+	}
+
+	if opts.Interval <= 0 {
+		opts.Interval = 10 * time.Second
+	}
+
+	if opts.Size <= 0 {
+		opts.Size = 60
+	}
+
+	s := &sampler{
+		db:      dbh.Db,
+		opts:    opts,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		samples: make([]PoolSample, 0, opts.Size),
+	}
+
+	// first sample is available immediately
+	s.record()
+
+	go s.run()
+
+	dbh.sampler = s
+	return nil
+}
+
+// StopSampler stops sampler started by StartSampler. Recorded samples are
+// still returned by Stats.
+func (dbh *DbHelper) StopSampler() {
+	if dbh.sampler == nil {
+		return
+	}
+
+	dbh.sampler.once.Do(func() {
+		close(dbh.sampler.stop)
+	})
+
+	<-dbh.sampler.done
+}
+
+// Stats returns samples of statistics of connection pool recorded by
+// sampler, oldest first. If sampler was not started, only current
+// statistics are returned.
+func (dbh *DbHelper) Stats() []PoolSample {
+	if dbh.sampler == nil {
+		return []PoolSample{{Time: time.Now(), Stats: dbh.Db.Stats()}}
+	}
+
+	return dbh.sampler.list()
+}
+
+// Records samples until sampler is stopped.
+func (s *sampler) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.record()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Records current statistics, replacing the oldest sample if buffer is full.
+func (s *sampler) record() {
+	sample := PoolSample{
+		Time:  time.Now(),
+		Stats: s.db.Stats(),
+	}
+
+	s.mutex.Lock()
+	if len(s.samples) < s.opts.Size {
+		s.samples = append(s.samples, sample)
+	} else {
+		s.samples[s.next] = sample
+		s.next = (s.next + 1) % s.opts.Size
+	}
+	s.mutex.Unlock()
+
+	if s.opts.OnSample != nil {
+		s.opts.OnSample(sample)
+	}
+}
+
+// Returns copy of recorded samples, oldest first.
+func (s *sampler) list() []PoolSample {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	list := make([]PoolSample, 0, len(s.samples))
+	list = append(list, s.samples[s.next:]...)
+	return append(list, s.samples[:s.next]...)
+}
@@ -0,0 +1,67 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSampler(t *testing.T) {
+	dbh := newTestDriverDb("SELECT 1", &testResult{})
+
+	// current statistics without sampler
+	if samples := dbh.Stats(); len(samples) != 1 {
+		t.Errorf("one sample expected: %v", samples)
+		return
+	}
+
+	var hooked int32
+	err := dbh.StartSampler(SamplerOptions{
+		Interval: time.Millisecond,
+		Size:     3,
+		OnSample: func(s PoolSample) {
+			atomic.AddInt32(&hooked, 1)
+		},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if dbh.StartSampler(SamplerOptions{}) == nil {
+		t.Error("error expected")
+		return
+	}
+
+	// wait until buffer is overwritten
+	for atomic.LoadInt32(&hooked) < 5 {
+		time.Sleep(time.Millisecond)
+	}
+
+	dbh.StopSampler()
+	dbh.StopSampler()
+
+	samples := dbh.Stats()
+	if len(samples) != 3 {
+		t.Errorf("3 samples expected: %d", len(samples))
+		return
+	}
+
+	for n := 1; n < len(samples); n++ {
+		if samples[n].Time.Before(samples[n-1].Time) {
+			t.Errorf("samples are not ordered: %v", samples)
+			return
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Key of query tags stored in context.
+type tagsKey struct{}
+
+// WithTags returns copy of ctx carrying query tags, e.g. service, route or
+// trace id, in addition to tags already stored in ctx. Tags are added to
+// queries as SQL comment if TagQueries is set.
+func WithTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string)
+	for k, v := range tagsOf(ctx) {
+		merged[k] = v
+	}
+
+	for k, v := range tags {
+		merged[k] = v
+	}
+
+	return context.WithValue(ctx, tagsKey{}, merged)
+}
+
+// Returns query tags stored in ctx.
+func tagsOf(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(tagsKey{}).(map[string]string)
+	return tags
+}
+
+// Returns SQL comment listing tags of ctx sorted by name, e.g.
+// "/* route=POST /orders service=checkout */ ", or empty string if queries
+// are not tagged.
+func (dbh *DbHelper) tagComment(ctx context.Context) string {
+	if !dbh.TagQueries {
+		return ""
+	}
+
+	tags := tagsOf(ctx)
+	if len(tags) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(tags))
+	for name := range tags {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	list := make([]string, len(names))
+	for i, name := range names {
+		list[i] = fmt.Sprintf("%s=%s", tagText(name), tagText(tags[name]))
+	}
+
+	return fmt.Sprintf("/* %s */ ", strings.Join(list, " "))
+}
+
+// Returns text which cannot terminate comment.
+func tagText(s string) string {
+	return strings.NewReplacer("*/", "*\\/", "/*", "/\\*", "\n", " ", "\r", " ").Replace(s)
+}
@@ -0,0 +1,65 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+)
+
+func TestTagQueries(t *testing.T) {
+	query := "SELECT id FROM test_tags"
+	dbh := newTestDriverDb(query, &testResult{
+		columns: []string{"id"},
+		rows:    [][]driver.Value{{int64(1)}},
+	})
+
+	// tagged query returns another result
+	newTestDriverDb("/* route=POST /orders service=checkout */ "+query, &testResult{
+		columns: []string{"id"},
+		rows:    [][]driver.Value{{int64(2)}},
+	})
+
+	ctx := WithTags(context.Background(), map[string]string{"service": "checkout"})
+	ctx = WithTags(ctx, map[string]string{"route": "POST /orders"})
+
+	q, err := dbh.Prepare(query)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	// queries are not tagged unless TagQueries is set
+	var id int64
+	_, err = q.QueryContext(ctx, &id, nil)
+	if err != nil || id != 1 {
+		t.Errorf("prepared statement expected: %d, %v", id, err)
+		return
+	}
+
+	dbh.TagQueries = true
+	_, err = q.QueryContext(ctx, &id, nil)
+	if err != nil || id != 2 {
+		t.Errorf("tagged query expected: %d, %v", id, err)
+		return
+	}
+
+	// tags cannot terminate comment
+	comment := dbh.tagComment(WithTags(context.Background(), map[string]string{"route": "*/ DROP TABLE test"}))
+	if comment != "/* route=*\\/ DROP TABLE test */ " {
+		t.Errorf("unexpected comment: %s", comment)
+		return
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Returns true if name is a name of registered table or of a mapped column.
+func (dbh *DbHelper) isIdentifier(name string) bool {
+	for _, tbl := range dbh.tables {
+		if tbl.name == name {
+			return true
+		}
+
+		if _, ok := tbl.fields[name]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Functions of query templates which output is safe to insert into query.
+var safeTemplateFuncs = map[string]bool{
+	"ident":  true,
+	"idents": true,
+	"param":  true,
+}
+
+// Returns error if node of template writes to query anything except its
+// text and results of functions ident, idents and param.
+func checkTemplateNode(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+
+		for _, child := range n.Nodes {
+			err := checkTemplateNode(child)
+			if err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		// declaration of variables writes nothing
+		if len(n.Pipe.Decl) > 0 {
+			return nil
+		}
+
+		// result of the last command is written
+		cmd := n.Pipe.Cmds[len(n.Pipe.Cmds)-1]
+		if id, ok := cmd.Args[0].(*parse.IdentifierNode); !ok || !safeTemplateFuncs[id.Ident] {
+			return errors.New(fmt.Sprintf("dbhelper: template action '%s' must end with ident, idents or param", n))
+		}
+	case *parse.IfNode:
+		return checkTemplateBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		return checkTemplateBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return checkTemplateBranch(&n.BranchNode)
+	}
+
+	return nil
+}
+
+// Checks both lists of branch node.
+func checkTemplateBranch(n *parse.BranchNode) error {
+	err := checkTemplateNode(n.List)
+	if err != nil {
+		return err
+	}
+
+	return checkTemplateNode(n.ElseList)
+}
+
+// Returns prefix of parameter names which is not used by named parameters
+// written directly in template tmpl.
+func templateParamPrefix(tmpl string) string {
+	prefix := "tmpl"
+	for strings.Contains(tmpl, ":"+prefix) {
+		prefix += "_"
+	}
+
+	return prefix
+}
+
+// PrepareTemplate renders query from text/template tmpl using data and
+// prepares it. Values must not be inserted into the query directly, instead
+// following functions must be used:
+//
+//	{{ident .Column}}   - inserts table or column name, which must be registered
+//	{{idents .Columns}} - inserts comma-separated list of registered names
+//	{{param .Value}}    - inserts named parameter and stores its value
+//
+// Templates with actions writing anything else are rejected. Names are
+// quoted if SQL dialect requires it.
+//
+// Returns prepared query and values of parameters, which must be passed to
+// Query or Exec. Parameter values can be extended with values of named
+// parameters written directly in the template.
+func (dbh *DbHelper) PrepareTemplate(tmpl string, data interface{}) (*Pstmt, map[string]interface{}, error) {
+	params := make(map[string]interface{})
+	prefix := templateParamPrefix(tmpl)
+
+	ident := func(name string) (string, error) {
+		if !dbh.isIdentifier(name) {
+			return "", errors.New(fmt.Sprintf("dbhelper: '%s' is not a registered table or column", name))
+		}
+
+		return dbh.quote(name), nil
+	}
+
+	funcs := template.FuncMap{
+		"ident": ident,
+		"idents": func(names []string) (string, error) {
+			quoted := make([]string, len(names))
+			for i, name := range names {
+				q, err := ident(name)
+				if err != nil {
+					return "", err
+				}
+
+				quoted[i] = q
+			}
+
+			return strings.Join(quoted, ", "), nil
+		},
+		"param": func(value interface{}) string {
+			name := fmt.Sprintf("%s%d", prefix, len(params))
+			params[name] = value
+			return getNamedPlaceholder(name)
+		},
+	}
+
+	// parse template
+	t, err := template.New("query").Funcs(funcs).Parse(tmpl)
+	if err != nil {
+		return nil, nil, wrapError(err)
+	}
+
+	// check all defined templates
+	for _, defined := range t.Templates() {
+		if defined.Tree == nil {
+			continue
+		}
+
+		err = checkTemplateNode(defined.Tree.Root)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// render query
+	var buf bytes.Buffer
+	err = t.Execute(&buf, data)
+	if err != nil {
+		return nil, nil, wrapError(err)
+	}
+
+	// prepare query
+	pstmt, err := dbh.Prepare(buf.String())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pstmt, params, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrepareTemplate(t *testing.T) {
+	dbh := newTestDriverDb("SELECT b, c FROM test WHERE text = $1 AND id > $2", &testResult{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Table":   "test",
+		"Columns": []string{"b", "c"},
+		"Column":  "text",
+		"Value":   "a",
+	}
+
+	// generated parameter does not collide with parameter of template
+	q, params, err := dbh.PrepareTemplate("SELECT {{idents .Columns}} FROM {{ident .Table}} "+
+		"WHERE {{ident .Column}} = {{param .Value}} AND id > :tmpl0", data)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	if !reflect.DeepEqual(params, map[string]interface{}{"tmpl_0": "a"}) ||
+		!reflect.DeepEqual(q.Params(), []string{"tmpl_0", "tmpl0"}) {
+		t.Errorf("unexpected parameters: %v, %v", params, q.Params())
+		return
+	}
+}
+
+func TestPrepareTemplateQuoting(t *testing.T) {
+	dbh := newTestDriverDb("SELECT `b` FROM `test` WHERE `id` = ?", &testResult{})
+	dbh.sqlDialect = MySql{}
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	q, _, err := dbh.PrepareTemplate("SELECT {{ident \"b\"}} FROM {{ident \"test\"}} WHERE {{\"id\" | ident}} = {{param 1}}", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	q.Close()
+}
+
+func TestPrepareTemplateInjection(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Value": "1; DROP TABLE test",
+	}
+
+	// values written directly are rejected before rendering
+	for _, tmpl := range []string{
+		"SELECT * FROM test WHERE id = {{.Value}}",
+		"SELECT * FROM test WHERE id = {{printf \"%s\" .Value}}",
+		"SELECT * FROM test WHERE id = {{param .Value | printf \"%s\"}}",
+		"SELECT * FROM test{{if .Value}} WHERE id = {{.Value}}{{end}}",
+		"{{define \"where\"}}WHERE id = {{.Value}}{{end}}SELECT * FROM test {{template \"where\" .}}",
+	} {
+		_, _, err = dbh.PrepareTemplate(tmpl, data)
+		if err == nil || !strings.Contains(err.Error(), "must end with ident, idents or param") {
+			t.Errorf("template is not rejected: %s: %v", tmpl, err)
+			return
+		}
+	}
+
+	// names are checked
+	_, _, err = dbh.PrepareTemplate("SELECT * FROM {{ident .Value}}", data)
+	if err == nil || !strings.Contains(err.Error(), "is not a registered table or column") {
+		t.Errorf("unregistered name is not rejected: %v", err)
+		return
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Maximum depth of trees traversed by SelectAncestors and SubtreeDelete,
+// which stops traversal of trees with cycles.
+const maxTreeDepth = 1000
+
+// Returns table assigned to type of i, which must have parent column.
+func (dbh *DbHelper) getTree(i interface{}) (*dbTable, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return nil, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if tbl.parentField == nil {
+		return nil, errors.New(fmt.Sprintf("dbhelper: table '%s' has no parent column", tbl.name))
+	}
+
+	return tbl, nil
+}
+
+// SelectChildren selects records of tree table assigned to type of i which
+// parent is record with id. I must be a pointer to slice of pointers.
+func (dbh *DbHelper) SelectChildren(i interface{}, id int64) (int64, error) {
+	tbl, err := dbh.getTree(i)
+	if err != nil {
+		return 0, err
+	}
+
+	return dbh.Select(i).Where(Eq(tbl.parentField.column, id)).OrderBy(tbl.idField.column).Query(i)
+}
+
+// SelectAncestors selects parent of record with id, its parent and so on up
+// to the root of the tree, in this order. I must be a pointer to slice of
+// pointers. Ancestors are selected by one recursive query if database
+// supports it, otherwise by one query per ancestor.
+func (dbh *DbHelper) SelectAncestors(i interface{}, id int64) (int64, error) {
+	tbl, err := dbh.getTree(i)
+	if err != nil {
+		return 0, err
+	}
+
+	list, err := listValue(tbl, i)
+	if err != nil {
+		return 0, err
+	}
+
+	if !dbh.Capabilities().RecursiveQueries {
+		return dbh.selectAncestors(tbl, list, id)
+	}
+
+	// parameters of query limited to tenant
+	params := map[string]interface{}{"id": id}
+	err = dbh.scopeParams(tbl, params)
+	if err != nil {
+		return 0, err
+	}
+
+	name := dbh.quote(tbl.name)
+	idColumn := dbh.quote(tbl.idField.column)
+	parentColumn := dbh.quote(tbl.parentField.column)
+
+	// ids of ancestors with their distance from the record
+	where := fmt.Sprintf("%s.%s = dbhelper_tree.dbhelper_id", name, idColumn)
+	for _, cond := range tbl.scopeConds(tenantParam) {
+		where += " AND " + cond
+	}
+
+	query := fmt.Sprintf("WITH RECURSIVE dbhelper_tree (dbhelper_id, dbhelper_depth) AS ("+
+		"SELECT %[3]s, 1 FROM %[1]s WHERE %[2]s = :id UNION ALL "+
+		"SELECT %[1]s.%[3]s, dbhelper_tree.dbhelper_depth + 1 FROM %[1]s, dbhelper_tree "+
+		"WHERE %[1]s.%[2]s = dbhelper_tree.dbhelper_id AND dbhelper_tree.dbhelper_depth < %[4]d) "+
+		"SELECT %[1]s.* FROM %[1]s, dbhelper_tree WHERE %[5]s ORDER BY dbhelper_tree.dbhelper_depth",
+		name, idColumn, parentColumn, maxTreeDepth, where)
+
+	q, err := tbl.getQuery(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.in(dbh).Query(i, params)
+}
+
+// Returns id of parent of structure v, false if v is root.
+func (tbl *dbTable) parentId(v reflect.Value) (interface{}, bool) {
+	parent := tbl.parentField.value(v)
+	if parent.IsZero() {
+		return nil, false
+	}
+
+	return parent.Interface(), true
+}
+
+// Selects ancestors of record with id one by one and stores them in list.
+func (dbh *DbHelper) selectAncestors(tbl *dbTable, list reflect.Value, id int64) (int64, error) {
+	list.Set(reflect.MakeSlice(list.Type(), 0, 10))
+
+	var parent interface{} = id
+	ok := true
+	for depth := 0; depth <= maxTreeDepth; depth++ {
+		ptr := reflect.New(tbl.structType)
+		num, err := dbh.Select(ptr.Interface()).Where(Eq(tbl.idField.column, parent)).Query(ptr.Interface())
+		if err != nil {
+			return 0, err
+		}
+
+		if num == 0 {
+			break
+		}
+
+		// the record itself is not selected
+		if depth > 0 {
+			list.Set(reflect.Append(list, ptr))
+		}
+
+		parent, ok = tbl.parentId(ptr.Elem())
+		if !ok {
+			break
+		}
+	}
+
+	return int64(list.Len()), nil
+}
+
+// Returns ids of record with id and all its descendants.
+func (dbh *DbHelper) subtreeIds(tbl *dbTable, id int64) ([]interface{}, error) {
+	if !dbh.Capabilities().RecursiveQueries {
+		// descendants are selected level by level
+		ids := []interface{}{id}
+		level := ids
+		for depth := 0; depth < maxTreeDepth && len(level) > 0; depth++ {
+			var next []interface{}
+			err := dbh.inChunks(tbl, level, func(chunk []interface{}) error {
+				query, params, err := dbh.Select(reflect.New(tbl.structType).Interface()).
+					Columns(tbl.idField.column).Where(In(tbl.parentField.column, chunk...)).SQL()
+				if err != nil {
+					return err
+				}
+
+				chunkIds, err := dbh.queryIds(tbl, query, params)
+				next = append(next, chunkIds...)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			level = next
+			ids = append(ids, level...)
+		}
+
+		return ids, nil
+	}
+
+	name := dbh.quote(tbl.name)
+	idColumn := dbh.quote(tbl.idField.column)
+	parentColumn := dbh.quote(tbl.parentField.column)
+
+	query := fmt.Sprintf("WITH RECURSIVE dbhelper_tree (dbhelper_id, dbhelper_depth) AS ("+
+		"SELECT %[2]s, 0 FROM %[1]s WHERE %[2]s = :id UNION ALL "+
+		"SELECT %[1]s.%[2]s, dbhelper_tree.dbhelper_depth + 1 FROM %[1]s, dbhelper_tree "+
+		"WHERE %[1]s.%[3]s = dbhelper_tree.dbhelper_id AND dbhelper_tree.dbhelper_depth < %[4]d) "+
+		"SELECT dbhelper_id FROM dbhelper_tree",
+		name, idColumn, parentColumn, maxTreeDepth)
+
+	return dbh.queryIds(tbl, query, map[string]interface{}{"id": id})
+}
+
+// SubtreeDelete deletes record of tree table assigned to type of i with id
+// together with all its descendants within a transaction. Records are
+// deleted like DeleteWhere does, so soft delete is respected. Returns number
+// of deleted records.
+func (dbh *DbHelper) SubtreeDelete(i interface{}, id int64) (int64, error) {
+	tbl, err := dbh.getTree(i)
+	if err != nil {
+		return 0, err
+	}
+
+	if dbh.tx == nil {
+		txh, err := dbh.Begin()
+		if err != nil {
+			return 0, err
+		}
+
+		num, err := txh.SubtreeDelete(i, id)
+		if err != nil {
+			txh.Rollback()
+			return 0, err
+		}
+
+		return num, txh.Commit()
+	}
+
+	ids, err := dbh.subtreeIds(tbl, id)
+	if err != nil {
+		return 0, err
+	}
+
+	var num int64
+	err = dbh.inChunks(tbl, ids, func(chunk []interface{}) error {
+		n, err := dbh.DeleteWhere(i, In(tbl.idField.column, chunk...))
+		num += n
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return num, nil
+}
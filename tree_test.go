@@ -0,0 +1,82 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testNode struct {
+	Id       int64  `db:"id" dbopt:"id,auto"`
+	ParentId int64  `db:"parent_id"`
+	Name     string `db:"name"`
+}
+
+func TestTreeOptions(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+
+	// parent must have type of id
+	err := dbh.AddTableWith(testNode{}, TableOptions{Name: "nodes", Parent: "name"})
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	// table without parent column is not a tree
+	err = dbh.AddTable(testNode{}, "nodes")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_, err = dbh.SelectChildren(&[]*testNode{}, 1)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	dbh.RemoveTable(testNode{})
+	err = dbh.AddTableWith(testNode{}, TableOptions{Name: "nodes", Parent: "parent_id"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// pointer to slice of pointers is required
+	_, err = dbh.SelectAncestors(&testNode{}, 1)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testNode{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if tbl.info().Options.Parent != "parent_id" {
+		t.Errorf("unexpected options: %v", tbl.info().Options)
+		return
+	}
+
+	// root records have no parent
+	nodes := []testNode{{Id: 1}, {Id: 4, ParentId: 3}}
+	for n, node := range nodes {
+		id, ok := tbl.parentId(reflect.ValueOf(node))
+		if ok != (n == 1) || (ok && id != node.ParentId) {
+			t.Errorf("unexpected parent of node %d: %v", node.Id, id)
+			return
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// CreateSequence creates sequence name, if it does not exist.
+// Only Postgresql supports sequences.
+func (dbh *DbHelper) CreateSequence(name string) error {
+	sqld, ok := dbh.sqlDialect.(HasSequences)
+	if !ok {
+		return errors.New("dbhelper: SQL dialect does not support sequences")
+	}
+
+	if !identifierRegexp.MatchString(name) {
+		return errors.New(fmt.Sprintf("dbhelper: wrong sequence name '%s'", name))
+	}
+
+	_, err := dbh.execRaw(sqld.CreateSequence(name))
+	return err
+}
+
+// SetSequenceOwner makes sequence name owned by column of the table assigned
+// to type of i, so sequence is dropped together with the table.
+func (dbh *DbHelper) SetSequenceOwner(name string, i interface{}, column string) error {
+	sqld, ok := dbh.sqlDialect.(HasSequences)
+	if !ok {
+		return errors.New("dbhelper: SQL dialect does not support sequences")
+	}
+
+	if !identifierRegexp.MatchString(name) {
+		return errors.New(fmt.Sprintf("dbhelper: wrong sequence name '%s'", name))
+	}
+
+	tbl, _, err := dbh.getTableField(i, column)
+	if err != nil {
+		return err
+	}
+
+	_, err = dbh.execRaw(sqld.SetSequenceOwner(name, dbh.quote(tbl.name), dbh.quote(column)))
+	return err
+}
+
+// Returns table assigned to type of i and its field assigned to column.
+func (dbh *DbHelper) getTableField(i interface{}, column string) (*dbTable, *dbField, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tbl.view {
+		return nil, nil, errors.New(fmt.Sprintf("dbhelper: cannot modify read-only view '%s'", tbl.name))
+	}
+
+	// get field
+	f, ok := tbl.fields[column]
+	if !ok {
+		return nil, nil, errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field assigned to column '%s' of table '%s'",
+			t, column, tbl.name))
+	}
+
+	return tbl, f, nil
+}
+
+// Returns name of trigger maintaining modification timestamp in column.
+func modifiedTriggerName(tbl *dbTable, column string) string {
+	return fmt.Sprintf("%s_%s_modified", tbl.name, column)
+}
+
+// CreateModifiedTrigger creates trigger setting column of the table assigned
+// to type of i to the current timestamp whenever record is updated, so
+// modification time is maintained by database even for updates that are not
+// performed by dbhelper. Column must be an integer column, usually the one with
+// option 'modified'. Existing trigger is replaced.
+func (dbh *DbHelper) CreateModifiedTrigger(i interface{}, column string) error {
+	tbl, f, err := dbh.getTableField(i, column)
+	if err != nil {
+		return err
+	}
+
+	switch f.typ.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+	default:
+		return errors.New(fmt.Sprintf("dbhelper: column '%s' of table '%s' cannot store timestamp", column, tbl.name))
+	}
+
+	queries := dbh.sqlDialect.ModifiedTrigger(modifiedTriggerName(tbl, column), dbh.quote(tbl.name), dbh.quote(column),
+		dbh.quote(tbl.idField.column))
+	if len(queries) == 0 {
+		return errors.New(fmt.Sprintf("dbhelper: SQL dialect does not support triggers"))
+	}
+
+	for _, query := range queries {
+		_, err = dbh.execRaw(query)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DropModifiedTrigger drops trigger created by CreateModifiedTrigger, if it exists.
+func (dbh *DbHelper) DropModifiedTrigger(i interface{}, column string) error {
+	tbl, _, err := dbh.getTableField(i, column)
+	if err != nil {
+		return err
+	}
+
+	queries := dbh.sqlDialect.DropModifiedTrigger(modifiedTriggerName(tbl, column), dbh.quote(tbl.name))
+	for _, query := range queries {
+		_, err = dbh.execRaw(query)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var transactionIdRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,150}$`)
+
+// CommitError is returned by TwoPhaseCommit if not all transactions were
+// committed. If Committed and InDoubt are empty, all transactions were
+// rolled back.
+type CommitError struct {
+	// Id passed to TwoPhaseCommit.
+	Id string
+
+	// Indexes of committed transactions.
+	Committed []int
+
+	// Indexes of prepared transactions, which were neither committed nor
+	// rolled back. They must be resolved manually using ids returned by
+	// PreparedId.
+	InDoubt []int
+
+	// The first error.
+	Err error
+}
+
+func (e *CommitError) Error() string {
+	return fmt.Sprintf("dbhelper: two-phase commit '%s' failed: %v (committed: %v, in doubt: %v)",
+		e.Id, e.Err, e.Committed, e.InDoubt)
+}
+
+// Unwrap returns the first error.
+func (e *CommitError) Unwrap() error {
+	return e.Err
+}
+
+// PreparedId returns id of prepared transaction number n of TwoPhaseCommit.
+func PreparedId(id string, n int) string {
+	return fmt.Sprintf("%s_%d", id, n)
+}
+
+// State of transaction of TwoPhaseCommit.
+const (
+	txOpen = iota
+	txPrepared
+	txCommitted
+	txRolledBack
+	txInDoubt
+)
+
+// TwoPhaseCommit commits transactions of DbHelpers bound to them (see Begin),
+// usually of different databases. Transactions of dialects implementing
+// HasTwoPhaseCommit are prepared first, then other transactions are committed
+// one by one, then prepared transactions are committed. If any step fails,
+// remaining transactions are rolled back. Only the last transaction without
+// two-phase commit is committed safely, so with several such transactions
+// consistency is not guaranteed. Id must be unique among prepared
+// transactions of databases and consist of letters, digits, '_', '.' and
+// '-'. Returns *CommitError if not all transactions were committed.
+func TwoPhaseCommit(id string, txs ...*DbHelper) error {
+	if !transactionIdRegexp.MatchString(id) {
+		return errors.New(fmt.Sprintf("dbhelper: invalid transaction id '%s'", id))
+	}
+
+	for _, txh := range txs {
+		if txh.tx == nil {
+			return errorNoTx
+		}
+	}
+
+	states := make([]int, len(txs))
+
+	// prepare transactions
+	for n, txh := range txs {
+		sqld, ok := txh.sqlDialect.(HasTwoPhaseCommit)
+		if !ok {
+			continue
+		}
+
+		_, err := txh.execRaw(sqld.PrepareTransaction(PreparedId(id, n)))
+		if err != nil {
+			return abortTwoPhaseCommit(id, txs, states, err)
+		}
+
+		// prepared transaction is no longer bound to connection
+		txh.txLeak.stop()
+		txh.tx.Rollback()
+		states[n] = txPrepared
+	}
+
+	// commit other transactions
+	for n, txh := range txs {
+		if states[n] != txOpen {
+			continue
+		}
+
+		txh.txLeak.stop()
+		err := txh.tx.Commit()
+		if err != nil {
+			states[n] = txRolledBack
+			return abortTwoPhaseCommit(id, txs, states, wrapError(err))
+		}
+
+		txh.committed()
+		states[n] = txCommitted
+	}
+
+	// commit prepared transactions
+	var first error
+	for n, txh := range txs {
+		if states[n] != txPrepared {
+			continue
+		}
+
+		sqld := txh.sqlDialect.(HasTwoPhaseCommit)
+		err := txh.execDb(sqld.CommitPrepared(PreparedId(id, n)))
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+
+			states[n] = txInDoubt
+			continue
+		}
+
+		txh.committed()
+		states[n] = txCommitted
+	}
+
+	if first != nil {
+		return commitError(id, states, first)
+	}
+
+	return nil
+}
+
+// Rolls back open and prepared transactions after error err.
+func abortTwoPhaseCommit(id string, txs []*DbHelper, states []int, err error) error {
+	for n, txh := range txs {
+		switch states[n] {
+		case txOpen:
+			txh.txLeak.stop()
+			txh.tx.Rollback()
+			states[n] = txRolledBack
+		case txPrepared:
+			sqld := txh.sqlDialect.(HasTwoPhaseCommit)
+			if txh.execDb(sqld.RollbackPrepared(PreparedId(id, n))) != nil {
+				states[n] = txInDoubt
+			} else {
+				states[n] = txRolledBack
+			}
+		}
+	}
+
+	return commitError(id, states, err)
+}
+
+// Returns CommitError for states of transactions.
+func commitError(id string, states []int, err error) error {
+	e := &CommitError{
+		Id:  id,
+		Err: err,
+	}
+
+	for n, state := range states {
+		switch state {
+		case txCommitted:
+			e.Committed = append(e.Committed, n)
+		case txInDoubt:
+			e.InDoubt = append(e.InDoubt, n)
+		}
+	}
+
+	return e
+}
+
+// Executes query that is not prepared outside of transaction.
+func (dbh *DbHelper) execDb(query string) error {
+	// apply default timeout
+	ctx, cancel := dbh.withTimeout(dbh.context())
+	defer cancel()
+
+	_, err := dbh.Db.ExecContext(ctx, query)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+func TestTwoPhaseCommit(t *testing.T) {
+	for _, q := range []string{
+		"PREPARE TRANSACTION 'order_1_0'",
+		"COMMIT PREPARED 'order_1_0'",
+		"ROLLBACK PREPARED 'order_1_0'",
+		"PREPARE TRANSACTION 'order_2_0'",
+		"PREPARE TRANSACTION 'order_3_0'",
+		"ROLLBACK PREPARED 'order_3_0'",
+	} {
+		newTestDriverDb(q, &testResult{})
+	}
+
+	db, _ := sql.Open("dbhelper_test", "")
+	pg := New(db, Postgresql{})
+	my := New(db, MySql{})
+
+	begin := func() (*DbHelper, *DbHelper) {
+		tx1, err := pg.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tx2, err := my.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return tx1, tx2
+	}
+
+	err := TwoPhaseCommit("order 1", pg)
+	if err == nil {
+		t.Error("error expected for invalid id")
+		return
+	}
+
+	err = TwoPhaseCommit("order_1", pg)
+	if err != errorNoTx {
+		t.Errorf("error expected for DbHelper not bound to transaction: %v", err)
+		return
+	}
+
+	// Postgresql transaction is prepared, MySql transaction is committed
+	tx1, tx2 := begin()
+	err = TwoPhaseCommit("order_1", tx1, tx2)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// prepared transaction which cannot be committed is in doubt
+	tx1, tx2 = begin()
+	err = TwoPhaseCommit("order_2", tx1, tx2)
+
+	var ce *CommitError
+	if !errors.As(err, &ce) {
+		t.Errorf("CommitError expected: %v", err)
+		return
+	}
+
+	if len(ce.Committed) != 1 || ce.Committed[0] != 1 || len(ce.InDoubt) != 1 || ce.InDoubt[0] != 0 {
+		t.Errorf("unexpected states: %v", ce)
+		return
+	}
+
+	// prepared transaction is rolled back if the second one cannot be prepared
+	tx1, tx2 = begin()
+	tx3, err := pg.Begin()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = TwoPhaseCommit("order_3", tx1, tx3, tx2)
+	if !errors.As(err, &ce) {
+		t.Errorf("CommitError expected: %v", err)
+		return
+	}
+
+	if len(ce.Committed) != 0 || len(ce.InDoubt) != 0 {
+		t.Errorf("all transactions must be rolled back: %v", ce)
+		return
+	}
+}
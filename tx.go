@@ -0,0 +1,161 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"time"
+)
+
+var errorNoTx = errors.New("dbhelper: DbHelper is not bound to a transaction")
+
+// TxOptions are options of transaction started by BeginWith.
+type TxOptions struct {
+	// Maximum execution time of statements, not limited if zero.
+	StatementTimeout time.Duration
+
+	// Maximum time of waiting for locks, not limited if zero.
+	LockTimeout time.Duration
+}
+
+// Begin starts a transaction and returns DbHelper bound to it.
+// All queries executed using returned DbHelper or statements prepared by it
+// are performed within the transaction. Registered tables are shared with dbh.
+func (dbh *DbHelper) Begin() (*DbHelper, error) {
+	return dbh.BeginWith(TxOptions{})
+}
+
+// BeginWith starts a transaction as Begin does, limiting execution time of
+// its statements and waiting for locks on the server, so that a single heavy
+// query can be bounded without changing settings of the connection pool.
+// Timeouts are set using dialects implementing HasStatementTimeout and
+// HasLockTimeout (Postgresql) and are reset when transaction is finished.
+func (dbh *DbHelper) BeginWith(opts TxOptions) (*DbHelper, error) {
+	if dbh.tx != nil {
+		return nil, errors.New("dbhelper: transaction has already been started")
+	}
+
+	// check that timeouts are supported
+	sqldStatement, hasStatement := dbh.sqlDialect.(HasStatementTimeout)
+	if opts.StatementTimeout > 0 && !hasStatement {
+		return nil, errors.New("dbhelper: SQL dialect does not support statement timeout")
+	}
+
+	sqldLock, hasLock := dbh.sqlDialect.(HasLockTimeout)
+	if opts.LockTimeout > 0 && !hasLock {
+		return nil, errors.New("dbhelper: SQL dialect does not support lock timeout")
+	}
+
+	// start transaction
+	tx, err := dbh.Db.Begin()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	txh := &DbHelper{
+		Db:                 dbh.Db,
+		Cache:              dbh.Cache,
+		DefaultTimeout:     dbh.DefaultTimeout,
+		AllowUnknownParams: dbh.AllowUnknownParams,
+		IgnoreColumnCase:   dbh.IgnoreColumnCase,
+		NullPolicy:         dbh.NullPolicy,
+		SecretHasher:       dbh.SecretHasher,
+		MaxRows:            dbh.MaxRows,
+		StrictSingleRow:    dbh.StrictSingleRow,
+		ErrorClassifier:    dbh.ErrorClassifier,
+		TagQueries:         dbh.TagQueries,
+		MaxPreparedQueries: dbh.MaxPreparedQueries,
+		MinIdleConns:       dbh.MinIdleConns,
+		LeakTimeout:        dbh.LeakTimeout,
+		OnLeak:             dbh.OnLeak,
+		sqlDialect:         dbh.sqlDialect,
+		tables:             dbh.tables,
+		mappings:           dbh.mappings,
+		tenant:             dbh.tenant,
+		ctx:                dbh.ctx,
+		sampler:            dbh.sampler,
+		tx:                 tx,
+		txEvents:           &commitEvents{},
+		txLeak:             dbh.watchLeak(),
+	}
+
+	// limit execution time of statements on the server
+	if opts.StatementTimeout > 0 {
+		_, err = txh.execRaw(sqldStatement.StatementTimeout(opts.StatementTimeout))
+		if err != nil {
+			txh.txLeak.stop()
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	// limit waiting for locks
+	if opts.LockTimeout > 0 {
+		_, err = txh.execRaw(sqldLock.LockTimeout(opts.LockTimeout))
+		if err != nil {
+			txh.txLeak.stop()
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	// timeouts are set, statements modifying data can be refused
+	txh.readOnly = dbh.readOnly
+
+	return txh, nil
+}
+
+// Commit commits the transaction DbHelper is bound to.
+func (dbh *DbHelper) Commit() error {
+	if dbh.tx == nil {
+		return errorNoTx
+	}
+
+	dbh.txLeak.stop()
+	err := dbh.tx.Commit()
+	if err != nil {
+		return wrapError(err)
+	}
+
+	dbh.committed()
+	return nil
+}
+
+// Invalidates cached results of tables modified within committed transaction
+// and calls AfterCommit callbacks.
+func (dbh *DbHelper) committed() {
+	for name := range dbh.txTables {
+		dbh.invalidateTable(name)
+	}
+
+	for _, e := range dbh.takeEvents() {
+		e.fire()
+	}
+}
+
+// Rollback aborts the transaction DbHelper is bound to.
+func (dbh *DbHelper) Rollback() error {
+	if dbh.tx == nil {
+		return errorNoTx
+	}
+
+	// modifications are discarded
+	dbh.takeEvents()
+	dbh.txLeak.stop()
+
+	err := dbh.tx.Rollback()
+	if err != nil {
+		return wrapError(err)
+	}
+
+	return nil
+}
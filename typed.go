@@ -0,0 +1,131 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// PstmtOf is a prepared statement mapping query results to values of type
+// T, which is a structure, a pointer to structure or a value of another
+// supported type. Type T is checked once, so queries are performed without
+// checks of destination done by Query.
+type PstmtOf[T any] struct {
+	pstmt *Pstmt
+
+	// Shapes of results are resolved on first use, when tables are
+	// registered.
+	once  sync.Once
+	one   *resultShape
+	all   *resultShape
+	isPtr bool
+	err   error
+}
+
+// Typed returns prepared statement pstmt mapping query results to values of
+// type T. Statement is shared, so it must be closed once.
+func Typed[T any](pstmt *Pstmt) *PstmtOf[T] {
+	return &PstmtOf[T]{pstmt: pstmt}
+}
+
+// Resolves shapes of results for T.
+func (p *PstmtOf[T]) resolve() error {
+	p.once.Do(func() {
+		rowType := reflect.TypeOf((*T)(nil)).Elem()
+		if rowType.Kind() == reflect.Ptr {
+			p.isPtr = true
+			rowType = rowType.Elem()
+		}
+
+		dbh := p.pstmt.dbHelper
+		p.one, p.err = dbh.resultShapeOf(reflect.PtrTo(rowType))
+		if p.err != nil {
+			return
+		}
+
+		p.all, p.err = dbh.resultShapeOf(reflect.PtrTo(reflect.SliceOf(reflect.PtrTo(rowType))))
+	})
+
+	return p.err
+}
+
+// Pstmt returns underlying prepared statement.
+func (p *PstmtOf[T]) Pstmt() *Pstmt {
+	return p.pstmt
+}
+
+// QueryAll executes query and returns values of all rows. Parameters are
+// handled in the same way as by Query.
+func (p *PstmtOf[T]) QueryAll(params interface{}) ([]T, error) {
+	return p.QueryAllContext(p.pstmt.dbHelper.context(), params)
+}
+
+// QueryAllContext is like QueryAll, but uses context ctx for execution.
+func (p *PstmtOf[T]) QueryAllContext(ctx context.Context, params interface{}) ([]T, error) {
+	err := p.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	ptr := reflect.New(p.all.sliceType)
+	_, err = p.pstmt.queryShape(ctx, p.all, ptr, params, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// slice of pointers is returned as is
+	if p.isPtr {
+		return ptr.Elem().Interface().([]T), nil
+	}
+
+	list := ptr.Elem()
+	result := make([]T, list.Len())
+	for n := range result {
+		result[n] = list.Index(n).Elem().Interface().(T)
+	}
+
+	return result, nil
+}
+
+// QueryOne executes query and returns value of the first row, or
+// ErrNotFound if query returned no rows. Other rows are handled as by Query,
+// see StrictSingleRow. Parameters are handled in the same way as by Query.
+func (p *PstmtOf[T]) QueryOne(params interface{}) (T, error) {
+	return p.QueryOneContext(p.pstmt.dbHelper.context(), params)
+}
+
+// QueryOneContext is like QueryOne, but uses context ctx for execution.
+func (p *PstmtOf[T]) QueryOneContext(ctx context.Context, params interface{}) (T, error) {
+	var zero T
+	err := p.resolve()
+	if err != nil {
+		return zero, err
+	}
+
+	ptr := reflect.New(p.one.rowType)
+	num, err := p.pstmt.queryShape(ctx, p.one, ptr, params, nil)
+	if err != nil && err != ErrMultipleRows {
+		return zero, err
+	}
+
+	if num == 0 {
+		return zero, ErrNotFound
+	}
+
+	if p.isPtr {
+		return ptr.Interface().(T), err
+	}
+
+	return ptr.Elem().Interface().(T), err
+}
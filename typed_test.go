@@ -0,0 +1,109 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestTyped(t *testing.T) {
+	query := "SELECT id, text FROM test_typed"
+	dbh := newTestDriverDb(query, &testResult{
+		columns: []string{"id", "text"},
+		rows:    [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}},
+	})
+
+	q, err := dbh.Prepare(query)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	// pointers to structures
+	list, err := Typed[*testStruct](q).QueryAll(nil)
+	if err != nil || len(list) != 2 || list[0].Id != 1 || list[1].Text != "b" {
+		t.Errorf("all rows expected: %v, %v", list, err)
+		return
+	}
+
+	// structures
+	records, err := Typed[testStruct](q).QueryAll(nil)
+	if err != nil || len(records) != 2 || records[1].Id != 2 {
+		t.Errorf("all rows expected: %v, %v", records, err)
+		return
+	}
+
+	record, err := Typed[testStruct](q).QueryOne(nil)
+	if err != nil || record.Id != 1 || record.Text != "a" {
+		t.Errorf("the first row expected: %v, %v", record, err)
+		return
+	}
+
+	// slices are not supported as rows
+	_, err = Typed[[]int64](q).QueryOne(nil)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestTypedScalar(t *testing.T) {
+	query := "SELECT count(*) FROM test_typed_scalar"
+	dbh := newTestDriverDb(query, &testResult{
+		columns: []string{"count"},
+		rows:    [][]driver.Value{{int64(5)}},
+	})
+
+	q, err := dbh.Prepare(query)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	num, err := Typed[int64](q).QueryOne(nil)
+	if err != nil || num != 5 {
+		t.Errorf("5 expected: %d, %v", num, err)
+		return
+	}
+}
+
+func TestTypedNotFound(t *testing.T) {
+	query := "SELECT id, text FROM test_typed_empty"
+	dbh := newTestDriverDb(query, &testResult{
+		columns: []string{"id", "text"},
+	})
+
+	q, err := dbh.Prepare(query)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	defer q.Close()
+
+	_, err = Typed[*testStruct](q).QueryOne(nil)
+	if err != ErrNotFound {
+		t.Errorf("ErrNotFound expected: %v", err)
+		return
+	}
+
+	list, err := Typed[testStruct](q).QueryAll(nil)
+	if err != nil || len(list) != 0 {
+		t.Errorf("no rows expected: %v, %v", list, err)
+		return
+	}
+}
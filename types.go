@@ -0,0 +1,271 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// Returns true if values of type t can be scanned and passed to database
+// by database/sql, like decimal types of third party packages.
+func isScannerValuer(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(scannerType) && t.Implements(valuerType)
+}
+
+// Kinds of columns which types are not defined by field type.
+const (
+	kindDecimal = "decimal"
+	kindInet    = "inet"
+	kindCidr    = "cidr"
+)
+
+// Converts values of fields which types are not supported by database/sql.
+type fieldCodec interface {
+	// Returns kind of the column.
+	kind() string
+
+	// Returns destination for rows.Scan assigning value to field v.
+	dest(v reflect.Value) sql.Scanner
+
+	// Returns value of field v passed to database.
+	arg(v reflect.Value) interface{}
+}
+
+// Codecs of supported field types.
+var codecs = map[reflect.Type]fieldCodec{
+	reflect.TypeOf(big.Int{}):      bigCodec{},
+	reflect.TypeOf(&big.Int{}):     bigCodec{},
+	reflect.TypeOf(big.Rat{}):      bigCodec{},
+	reflect.TypeOf(&big.Rat{}):     bigCodec{},
+	reflect.TypeOf(net.IP{}):       textCodec{kindInet},
+	reflect.TypeOf(netip.Addr{}):   textCodec{kindInet},
+	reflect.TypeOf(netip.Prefix{}): textCodec{kindCidr},
+	reflect.TypeOf(Point{}):        geometryCodec{},
+}
+
+// Returns codec for field type t or nil if values of type t do not need conversion.
+func getCodec(t reflect.Type) fieldCodec {
+	return codecs[t]
+}
+
+// Returns value passed to database for parameter value.
+func paramValue(value interface{}) interface{} {
+	switch x := value.(type) {
+	case *big.Int:
+		if x != nil {
+			return x.String()
+		}
+	case big.Int:
+		return x.String()
+	case *big.Rat:
+		if x != nil {
+			return ratString(x)
+		}
+	case big.Rat:
+		return ratString(&x)
+	case net.IP:
+		if x != nil {
+			return x.String()
+		}
+
+		return nil
+	case netip.Addr:
+		if x.IsValid() {
+			return x.String()
+		}
+
+		return nil
+	case Point:
+		return x.String()
+	case netip.Prefix:
+		if x.IsValid() {
+			return x.String()
+		}
+
+		return nil
+	}
+
+	return value
+}
+
+// Maximum number of decimal digits of big.Rat passed to database.
+const ratPrecision = 30
+
+// Returns decimal representation of r. Fraction is rounded if it cannot be
+// exactly represented by ratPrecision digits.
+func ratString(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+
+	// find the number of digits needed for exact representation
+	x := new(big.Rat).Set(r)
+	ten := big.NewRat(10, 1)
+	for prec := 1; prec < ratPrecision; prec++ {
+		x.Mul(x, ten)
+		if x.IsInt() {
+			return r.FloatString(prec)
+		}
+	}
+
+	return r.FloatString(ratPrecision)
+}
+
+// Converts numeric fields of types big.Int and big.Rat to and from decimal strings.
+type bigCodec struct {
+}
+
+// Returns kind of numeric column.
+func (c bigCodec) kind() string {
+	return kindDecimal
+}
+
+// Returns scanner assigning value to v.
+func (c bigCodec) dest(v reflect.Value) sql.Scanner {
+	return &bigScanner{v: v}
+}
+
+// Returns decimal string or nil if v is a nil pointer.
+func (c bigCodec) arg(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+
+		return paramValue(v.Interface())
+	}
+
+	return paramValue(v.Addr().Interface())
+}
+
+// Scans numeric column to field of type big.Int or big.Rat.
+type bigScanner struct {
+	v reflect.Value
+}
+
+// Scan implements sql.Scanner.
+func (s *bigScanner) Scan(src interface{}) error {
+	var str string
+	switch x := src.(type) {
+	case nil:
+		s.v.Set(reflect.Zero(s.v.Type()))
+		return nil
+	case []byte:
+		str = string(x)
+	case string:
+		str = x
+	case int64:
+		str = strconv.FormatInt(x, 10)
+	case float64:
+		str = strconv.FormatFloat(x, 'f', -1, 64)
+	default:
+		return errors.New(fmt.Sprintf("dbhelper: cannot assign value of type '%T' to field of type '%v'", src, s.v.Type()))
+	}
+
+	// pointer to value
+	p := s.v
+	if s.v.Kind() == reflect.Ptr {
+		if s.v.IsNil() {
+			s.v.Set(reflect.New(s.v.Type().Elem()))
+		}
+	} else {
+		p = s.v.Addr()
+	}
+
+	ok := false
+	switch x := p.Interface().(type) {
+	case *big.Int:
+		_, ok = x.SetString(str, 10)
+	case *big.Rat:
+		_, ok = x.SetString(str)
+	}
+
+	if !ok {
+		return errors.New(fmt.Sprintf("dbhelper: cannot assign '%s' to field of type '%v'", str, s.v.Type()))
+	}
+
+	return nil
+}
+
+// Converts fields of types net.IP, netip.Addr and netip.Prefix to and from
+// their text representation.
+type textCodec struct {
+	k string
+}
+
+// Returns kind of the column.
+func (c textCodec) kind() string {
+	return c.k
+}
+
+// Returns scanner assigning value to v.
+func (c textCodec) dest(v reflect.Value) sql.Scanner {
+	return &textScanner{v: v, address: c.k == kindInet}
+}
+
+// Returns text representation or nil if v has zero value.
+func (c textCodec) arg(v reflect.Value) interface{} {
+	return paramValue(v.Interface())
+}
+
+// Scans column to field which type implements encoding.TextUnmarshaler.
+type textScanner struct {
+	v reflect.Value
+
+	// Field stores an address without network mask.
+	address bool
+}
+
+// Scan implements sql.Scanner.
+func (s *textScanner) Scan(src interface{}) error {
+	var str string
+	switch x := src.(type) {
+	case nil:
+		s.v.Set(reflect.Zero(s.v.Type()))
+		return nil
+	case []byte:
+		str = string(x)
+	case string:
+		str = x
+	default:
+		return errors.New(fmt.Sprintf("dbhelper: cannot assign value of type '%T' to field of type '%v'", src, s.v.Type()))
+	}
+
+	// inet values can contain network mask
+	if s.address {
+		if n := strings.IndexByte(str, '/'); n >= 0 {
+			str = str[:n]
+		}
+	}
+
+	err := s.v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(str))
+	if err != nil {
+		return wrapError(err)
+	}
+
+	return nil
+}
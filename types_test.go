@@ -0,0 +1,140 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql"
+	"math/big"
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+type testDecimalStruct struct {
+	Id     int64    `db:"id" dbopt:"id,auto"`
+	Amount big.Rat  `db:"amount"`
+	Total  *big.Int `db:"total"`
+}
+
+func TestDecimalFields(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testDecimalStruct{}, "test_decimal")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testDecimalStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if dbh.sqlDialect.ColumnType(tbl.fields["amount"].info()) != "numeric" {
+		t.Errorf("unexpected column type: %s", dbh.sqlDialect.ColumnType(tbl.fields["amount"].info()))
+		return
+	}
+
+	s := &testDecimalStruct{}
+	v := reflect.ValueOf(s).Elem()
+
+	// scan values
+	err = tbl.fields["amount"].dest(v).(sql.Scanner).Scan([]byte("12.345"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = tbl.fields["total"].dest(v).(sql.Scanner).Scan(int64(1000))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if s.Amount.Cmp(big.NewRat(12345, 1000)) != 0 || s.Total == nil || s.Total.Int64() != 1000 {
+		t.Errorf("unexpected values: %v, %v", s.Amount.String(), s.Total)
+		return
+	}
+
+	// values passed to database
+	if tbl.fields["amount"].arg(v) != "12.345" || tbl.fields["total"].arg(v) != "1000" {
+		t.Errorf("unexpected values: %v, %v", tbl.fields["amount"].arg(v), tbl.fields["total"].arg(v))
+		return
+	}
+
+	if ratString(big.NewRat(1, 3)) != "0.333333333333333333333333333333" {
+		t.Errorf("unexpected value: %s", ratString(big.NewRat(1, 3)))
+		return
+	}
+}
+
+type testNetStruct struct {
+	Id      int64        `db:"id" dbopt:"id,auto"`
+	IP      net.IP       `db:"ip"`
+	Addr    netip.Addr   `db:"addr"`
+	Network netip.Prefix `db:"network"`
+}
+
+func TestNetFields(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testNetStruct{}, "test_net")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testNetStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if dbh.sqlDialect.ColumnType(tbl.fields["ip"].info()) != "inet" || dbh.sqlDialect.ColumnType(tbl.fields["network"].info()) != "cidr" {
+		t.Error("unexpected column types")
+		return
+	}
+
+	s := &testNetStruct{}
+	v := reflect.ValueOf(s).Elem()
+
+	// scan values
+	values := map[string]interface{}{
+		"ip":      []byte("192.168.0.1/24"),
+		"addr":    "::1",
+		"network": "10.0.0.0/8",
+	}
+
+	for column, value := range values {
+		err = tbl.fields[column].dest(v).(sql.Scanner).Scan(value)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if !s.IP.Equal(net.ParseIP("192.168.0.1")) || s.Addr.String() != "::1" || s.Network.String() != "10.0.0.0/8" {
+		t.Errorf("unexpected values: %v, %v, %v", s.IP, s.Addr, s.Network)
+		return
+	}
+
+	// values passed to database
+	if tbl.fields["ip"].arg(v) != "192.168.0.1" || tbl.fields["network"].arg(v) != "10.0.0.0/8" {
+		t.Errorf("unexpected values: %v, %v", tbl.fields["ip"].arg(v), tbl.fields["network"].arg(v))
+		return
+	}
+
+	if tbl.fields["addr"].arg(reflect.ValueOf(testNetStruct{})) != nil {
+		t.Error("nil expected for zero address")
+		return
+	}
+}
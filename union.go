@@ -0,0 +1,43 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"strings"
+)
+
+// Union prepares query combining results of prepared queries using UNION
+// (or UNION ALL if all is true). Queries must use named parameters.
+// Parameters with the same name in different queries get the same value.
+// Placeholders are renumbered according to SQL dialect.
+func (dbh *DbHelper) Union(all bool, queries ...*Pstmt) (*Pstmt, error) {
+	if len(queries) == 0 {
+		return nil, errors.New("dbhelper: no queries to combine")
+	}
+
+	sqls := make([]string, len(queries))
+	for i, q := range queries {
+		if q.positional {
+			return nil, errors.New("dbhelper: only queries with named parameters can be combined")
+		}
+
+		sqls[i] = q.query
+	}
+
+	op := " UNION "
+	if all {
+		op = " UNION ALL "
+	}
+
+	return dbh.Prepare(strings.Join(sqls, op))
+}
@@ -0,0 +1,278 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UpdateExpr updates columns of the record defined by the field with option
+// 'id' of structure i to values of SQL expressions, e.g.
+//
+//	dbh.UpdateExpr(&user, map[string]string{"visits": "visits + :n"}, map[string]interface{}{"n": 1})
+//
+// Expressions can contain named parameters, which values are taken from params.
+// Expressions are inserted to the query as is and must not contain user input.
+// Field with option 'modified' and version column are updated as by Update.
+// Other fields of i are not changed. Returns number of affected rows.
+func (dbh *DbHelper) UpdateExpr(i interface{}, exprs map[string]string, params map[string]interface{}) (int64, error) {
+	// get current timestamp
+	time := time.Now().UTC().Unix()
+
+	// prepare parameters
+	tbl, v, err := dbh.prepareParams(i)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(exprs) == 0 {
+		return 0, errors.New("dbhelper: no expressions to update")
+	}
+
+	err = tbl.checkSettable(v, tbl.modifiedField, tbl.versionField)
+	if err != nil {
+		return 0, err
+	}
+
+	// columns in stable order, so the same query is prepared once
+	columns := make([]string, 0, len(exprs))
+	for col := range exprs {
+		f, ok := tbl.fields[col]
+		if !ok {
+			return 0, errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field assigned to column '%s' of table '%s'",
+				tbl.structType, col, tbl.name))
+		}
+
+		if f.id || f.readonly || f.tsvector || f == tbl.tenantField {
+			return 0, errors.New(fmt.Sprintf("dbhelper: column '%s' of table '%s' cannot be updated", col, tbl.name))
+		}
+
+		columns = append(columns, col)
+	}
+
+	sort.Strings(columns)
+
+	// parameter values
+	values := make(map[string]interface{}, len(params)+2)
+	for name, value := range params {
+		values[name] = value
+	}
+
+	values["dbhelper_id"] = tbl.idField.arg(v)
+	err = dbh.scopeParams(tbl, values)
+	if err != nil {
+		return 0, err
+	}
+
+	if tbl.modifiedField != nil && exprs[tbl.modifiedField.column] == "" {
+		values["dbhelper_modified"] = time
+	}
+
+	// assignments define the query
+	set := make([]string, 0, len(columns)+2)
+	for _, col := range columns {
+		set = append(set, fmt.Sprintf("%s = %s", dbh.quote(col), exprs[col]))
+	}
+
+	q, err := tbl.getPlan("updateexpr", strings.Join(set, ", "), func() (string, error) {
+		if tbl.modifiedField != nil && exprs[tbl.modifiedField.column] == "" {
+			set = append(set, fmt.Sprintf("%s = :dbhelper_modified", dbh.quote(tbl.modifiedField.column)))
+		}
+
+		if tbl.versionField != nil && exprs[tbl.versionField.column] == "" {
+			version := dbh.quote(tbl.versionField.column)
+			set = append(set, fmt.Sprintf("%s = %s + 1", version, version))
+		}
+
+		return fmt.Sprintf("UPDATE %s SET %s WHERE %s = :dbhelper_id%s", dbh.quote(tbl.name), strings.Join(set, ", "),
+			dbh.quote(tbl.idField.column), tbl.scopeSQL(tenantParam)), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	num, err := dbh.withHistory(tbl, v, time, func(dbh *DbHelper) (int64, error) {
+		return q.in(dbh).Exec(values)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// invalidate cached results
+	dbh.invalidate(tbl)
+
+	// update modified field in structure
+	if tbl.modifiedField != nil && exprs[tbl.modifiedField.column] == "" {
+		tbl.modifiedField.value(v).SetInt(time)
+	}
+
+	// record has new version
+	if tbl.versionField != nil && exprs[tbl.versionField.column] == "" && num > 0 {
+		f := tbl.versionField.value(v)
+		f.SetInt(f.Int() + 1)
+	}
+
+	return num, nil
+}
+
+// Touch sets the field with option 'modified' of structure i and of the record
+// defined by the field with option 'id' to current timestamp. Other columns
+// are not updated. Returns number of affected rows.
+func (dbh *DbHelper) Touch(i interface{}) (int64, error) {
+	// get current timestamp
+	time := time.Now().UTC().Unix()
+
+	// prepare parameters
+	tbl, v, err := dbh.prepareParams(i)
+	if err != nil {
+		return 0, err
+	}
+
+	if tbl.modifiedField == nil {
+		return 0, errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field with option 'modified'", tbl.structType))
+	}
+
+	err = tbl.checkSettable(v, tbl.modifiedField)
+	if err != nil {
+		return 0, err
+	}
+
+	// values in the order of parameters
+	params := orderedParams{time, tbl.idField.arg(v)}
+	if tbl.tenantField != nil {
+		params = append(params, dbh.tenant)
+	}
+
+	q, err := tbl.getPlan("touch", "", func() (string, error) {
+		return fmt.Sprintf("UPDATE %s SET %s = :dbhelper_modified WHERE %s = :dbhelper_id%s",
+			dbh.quote(tbl.name), dbh.quote(tbl.modifiedField.column), dbh.quote(tbl.idField.column),
+			tbl.scopeSQL(tenantParam)), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	num, err := dbh.withHistory(tbl, v, time, func(dbh *DbHelper) (int64, error) {
+		return q.in(dbh).Exec(params)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// invalidate cached results
+	dbh.invalidate(tbl)
+
+	// update modified field in structure
+	tbl.modifiedField.value(v).SetInt(time)
+
+	return num, nil
+}
+
+// Upsert inserts new record to database or, if record with the same id
+// exists, updates it. Unlike Insert, value of the field with option 'id' is
+// inserted, unless it is auto-incremented and has zero value, in which case
+// Insert is performed. Field with option 'created' is not updated.
+func (dbh *DbHelper) Upsert(i interface{}) error {
+	// get current timestamp
+	time := time.Now().UTC().Unix()
+
+	// prepare parameters
+	tbl, v, err := dbh.prepareParams(i)
+	if err != nil {
+		return err
+	}
+
+	sqld, ok := dbh.sqlDialect.(HasUpsert)
+	if !ok {
+		return errors.New("dbhelper: SQL dialect does not support upsert")
+	}
+
+	// new record
+	if tbl.idField.auto && tbl.idField.value(v).IsZero() {
+		return dbh.Insert(i)
+	}
+
+	setFields := append([]*dbField{tbl.modifiedField}, tbl.defaultFields...)
+	err = tbl.checkSettable(v, append(setFields, tbl.secretFields...)...)
+	if err != nil {
+		return err
+	}
+
+	// assign default values to fields with zero values
+	err = tbl.applyDefaults(v, time)
+	if err != nil {
+		return err
+	}
+
+	// check values of enum fields
+	err = tbl.checkEnums(v)
+	if err != nil {
+		return err
+	}
+
+	// store hashes of secrets
+	err = dbh.hashSecrets(tbl, v)
+	if err != nil {
+		return err
+	}
+
+	q, err := tbl.getPlan("upsert", "", func() (string, error) {
+		// inserted columns
+		fields, ph := tbl.getInsertFields()
+		if tbl.idField.auto {
+			fields = append(fields, tbl.idField.column)
+			ph = append(ph, tbl.fieldPlaceholder(tbl.idField))
+		}
+
+		for n, col := range fields {
+			fields[n] = dbh.quote(col)
+		}
+
+		// updated columns
+		update, _ := tbl.getUpdateFields()
+		for n, col := range update {
+			update[n] = dbh.quote(col)
+		}
+
+		return fmt.Sprintf("INSERT INTO %s(%s) VALUES(%s) %s", dbh.quote(tbl.name), strings.Join(fields, ", "),
+			strings.Join(ph, ", "), sqld.Upsert(dbh.quote(tbl.idField.column), update)), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// get parameter values, created and modified time is set
+	params := tbl.structValues(q, v, time, true)
+	defer putValueBuffer(params)
+
+	_, err = dbh.withHistory(tbl, v, time, func(dbh *DbHelper) (int64, error) {
+		return q.in(dbh).Exec(orderedParams(*params))
+	})
+	if err != nil {
+		return err
+	}
+
+	// invalidate cached results
+	dbh.invalidate(tbl)
+
+	// update modified field in structure
+	if tbl.modifiedField != nil {
+		tbl.modifiedField.value(v).SetInt(time)
+	}
+
+	dbh.changed(tbl, v, OpUpsert)
+	return nil
+}
@@ -0,0 +1,263 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Problem describes a problem of structure mapping.
+type Problem struct {
+	// Structure type.
+	Type reflect.Type
+
+	// Table name, empty if structure is not registered.
+	Table string
+
+	// Column name, empty if problem concerns the whole structure.
+	Column string
+
+	// Description of the problem.
+	Message string
+}
+
+// String returns description of the problem with its location.
+func (p Problem) String() string {
+	location := fmt.Sprintf("structure type '%v'", p.Type)
+	if p.Table != "" {
+		location += fmt.Sprintf(", table '%s'", p.Table)
+	}
+
+	if p.Column != "" {
+		location += fmt.Sprintf(", column '%s'", p.Column)
+	}
+
+	return fmt.Sprintf("%s: %s", location, p.Message)
+}
+
+// ValidationReport lists problems of registered tables found by Validate.
+type ValidationReport struct {
+	Problems []Problem
+}
+
+// Err returns error listing all problems or nil if there are no problems.
+func (r *ValidationReport) Err() error {
+	if len(r.Problems) == 0 {
+		return nil
+	}
+
+	problems := make([]string, len(r.Problems))
+	for i, p := range r.Problems {
+		problems[i] = p.String()
+	}
+
+	return errors.New(fmt.Sprintf("dbhelper: %s", strings.Join(problems, "; ")))
+}
+
+// Adds problem of table.
+func (r *ValidationReport) add(tbl *dbTable, column string, format string, args ...interface{}) {
+	r.Problems = append(r.Problems, Problem{
+		Type:    tbl.structType,
+		Table:   tbl.name,
+		Column:  column,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Returns registered tables sorted by name and structure type.
+func (dbh *DbHelper) sortedTables() []*dbTable {
+	tables := make([]*dbTable, 0, len(dbh.tables))
+	for _, tbl := range dbh.tables {
+		tables = append(tables, tbl)
+	}
+
+	sort.Slice(tables, func(i, j int) bool {
+		if tables[i].name != tables[j].name {
+			return tables[i].name < tables[j].name
+		}
+
+		return tables[i].structType.String() < tables[j].structType.String()
+	})
+
+	return tables
+}
+
+// Validate cross-checks all registered tables and returns report of found
+// problems: names which are not valid identifiers, structure types mapped
+// to the same table which define the same columns differently or have
+// different ids, and foreign keys of relations which types are not
+// compatible with referenced ids.
+func (dbh *DbHelper) Validate() *ValidationReport {
+	report := &ValidationReport{}
+
+	// tables by name
+	tables := dbh.sortedTables()
+	byName := make(map[string][]*dbTable)
+	for _, tbl := range tables {
+		if !identifierRegexp.MatchString(tbl.name) {
+			report.add(tbl, "", "table name is not a valid identifier")
+		}
+
+		for _, f := range tbl.orderedFields {
+			if !identifierRegexp.MatchString(f.column) || strings.Contains(f.column, ".") {
+				report.add(tbl, f.column, "column name is not a valid identifier")
+			}
+		}
+
+		byName[tbl.name] = append(byName[tbl.name], tbl)
+	}
+
+	// compare types mapped to the same table with the first of them
+	for _, tbl := range tables {
+		first := byName[tbl.name][0]
+		if tbl == first {
+			continue
+		}
+
+		dbh.compareTables(report, first, tbl)
+	}
+
+	// foreign keys of relations
+	for _, tbl := range tables {
+		for _, name := range tbl.relationNames {
+			r := tbl.relations[name]
+
+			var fk, id *dbField
+			switch r.kind {
+			case HasMany, HasOne:
+				fk, id = r.target.fields[r.foreignKey], tbl.idField
+			case BelongsTo:
+				fk, id = tbl.fields[r.foreignKey], r.target.idField
+			default:
+				continue
+			}
+
+			if !compatibleIdTypes(fk.typ, id.typ) {
+				report.add(tbl, r.foreignKey, "foreign key type '%v' of relation '%s' is not compatible with id type '%v'",
+					fk.typ, r.name, id.typ)
+			}
+		}
+	}
+
+	return report
+}
+
+// Returns true if values of type a can reference ids of type b.
+func compatibleIdTypes(a reflect.Type, b reflect.Type) bool {
+	integer := func(t reflect.Type) bool {
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return true
+		}
+
+		return false
+	}
+
+	if integer(a) || integer(b) {
+		return integer(a) && integer(b)
+	}
+
+	return a.Kind() == b.Kind()
+}
+
+// Reports differences of structure types mapped to the same table.
+func (dbh *DbHelper) compareTables(report *ValidationReport, first *dbTable, tbl *dbTable) {
+	// ids are checked for tables only
+	if first.idField != nil && tbl.idField != nil {
+		if first.idField.column != tbl.idField.column {
+			report.add(tbl, tbl.idField.column, "id column differs from id column '%s' of structure type '%v'",
+				first.idField.column, first.structType)
+		} else if first.idField.typ != tbl.idField.typ || first.idField.auto != tbl.idField.auto {
+			report.add(tbl, tbl.idField.column, "id type '%v' differs from id type '%v' of structure type '%v'",
+				tbl.idField.typ, first.idField.typ, first.structType)
+		}
+	}
+
+	for _, f := range tbl.orderedFields {
+		other, ok := first.fields[f.column]
+		if !ok || f.id {
+			continue
+		}
+
+		typ := dbh.sqlDialect.ColumnType(f.info())
+		otherTyp := dbh.sqlDialect.ColumnType(other.info())
+		if typ != otherTyp {
+			report.add(tbl, f.column, "column type '%s' differs from type '%s' of structure type '%v'",
+				typ, otherTyp, first.structType)
+		}
+	}
+}
+
+// CheckTags parses structure tags of types as AddTable does, without
+// connection to database, and returns found problems. Unlike AddTable,
+// parsing is not stopped on the first problem, so that all problems of
+// each structure are listed. Intended to be used in unit tests.
+func CheckTags(types ...interface{}) []Problem {
+	var problems []Problem
+	dbh := New(nil, Postgresql{})
+	for _, i := range types {
+		t, err := typeOf(i)
+		if err != nil {
+			problems = append(problems, Problem{Message: problemMessage(err)})
+			continue
+		}
+
+		if t.Kind() != reflect.Struct {
+			problems = append(problems, Problem{Type: t, Message: "type is not a structure"})
+			continue
+		}
+
+		// fields are parsed one by one
+		tbl := &dbTable{dbHelper: dbh, structType: t}
+		failed := false
+		for n := 0; n < t.NumField(); n++ {
+			field := t.Field(n)
+			fields, err := tbl.parseField(field)
+			if err != nil {
+				column := field.Tag.Get("db")
+				if column == "" {
+					column = field.Name
+				}
+
+				problems = append(problems, Problem{Type: t, Column: column, Message: problemMessage(err)})
+				failed = true
+				continue
+			}
+
+			for _, f := range fields {
+				if !identifierRegexp.MatchString(f.column) || strings.Contains(f.column, ".") {
+					problems = append(problems, Problem{Type: t, Column: f.column, Message: "column name is not a valid identifier"})
+				}
+			}
+		}
+
+		// problems of the whole structure
+		if !failed {
+			_, err = dbh.newDbTable(t, "")
+			if err != nil {
+				problems = append(problems, Problem{Type: t, Message: problemMessage(err)})
+			}
+		}
+	}
+
+	return problems
+}
+
+// Returns message of error without package prefix.
+func problemMessage(err error) string {
+	return strings.TrimPrefix(err.Error(), "dbhelper: ")
+}
@@ -0,0 +1,92 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	type testUser struct {
+		Id   int64  `db:"id" dbopt:"id,auto"`
+		Name string `db:"name"`
+		Age  int64  `db:"age"`
+	}
+
+	type testUserName struct {
+		Id   string `db:"id" dbopt:"id"`
+		Name string `db:"name"`
+		Age  string `db:"age"`
+	}
+
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testUser{}, "users")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if dbh.Validate().Err() != nil {
+		t.Error(dbh.Validate().Err())
+		return
+	}
+
+	err = dbh.AddTable(testUserName{}, "users")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = dbh.AddTable(testStruct{}, "wrong-name")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	report := dbh.Validate()
+	if len(report.Problems) != 3 || report.Err() == nil {
+		t.Errorf("unexpected problems: %v", report.Problems)
+		return
+	}
+
+	if report.Problems[0].Table != "wrong-name" || report.Problems[1].Column != "id" ||
+		report.Problems[2].Column != "age" {
+		t.Errorf("unexpected problems: %v", report.Problems)
+		return
+	}
+}
+
+func TestCheckTags(t *testing.T) {
+	type testWrongTags struct {
+		Id    int64  `db:"id" dbopt:"id,auto"`
+		Name  string `db:"name" dbopt:"unknown"`
+		Count int    `db:"count" dbopt:"default=many"`
+	}
+
+	type testNoId struct {
+		Name string `db:"name"`
+	}
+
+	problems := CheckTags(testStruct{}, &testWrongTags{}, []testNoId{}, 1)
+	if len(problems) != 4 {
+		t.Errorf("unexpected problems: %v", problems)
+		return
+	}
+
+	if problems[0].Column != "name" || problems[1].Column != "count" ||
+		problems[2].Column != "" || problems[2].Type != reflect.TypeOf(testNoId{}) ||
+		problems[3].Message != "type is not a structure" {
+		t.Errorf("unexpected problems: %v", problems)
+		return
+	}
+}
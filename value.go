@@ -0,0 +1,122 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	// ErrNoRows is returned by QueryValue when query returned no rows.
+	// It is the same error as ErrNotFound.
+	ErrNoRows = ErrNotFound
+
+	// ErrTooManyRows is returned by QueryValue when query returned more than one row.
+	ErrTooManyRows = errors.New("dbhelper: query returned more than one row")
+)
+
+// QueryValue executes prepared query, which must return exactly one row with
+// one column, and assigns the value to i, which must be a pointer.
+// Returns ErrNoRows or ErrTooManyRows if number of rows is different.
+// Parameters are handled in the same way as by Query.
+func (pstmt *Pstmt) QueryValue(i interface{}, params interface{}) error {
+	return pstmt.QueryValueContext(pstmt.dbHelper.context(), i, params)
+}
+
+// QueryValueContext is like QueryValue, but uses context ctx for execution.
+func (pstmt *Pstmt) QueryValueContext(ctx context.Context, i interface{}, params interface{}) error {
+	if i == nil {
+		return errorNil
+	}
+
+	if reflect.TypeOf(i).Kind() != reflect.Ptr {
+		return errors.New("dbhelper: pointer expected")
+	}
+
+	// refuse to modify data
+	err := pstmt.checkReadOnly(false)
+	if err != nil {
+		return err
+	}
+
+	// get parameter values for query
+	values, err := pstmt.getValues(params)
+	if err != nil {
+		return err
+	}
+
+	// apply default timeout
+	ctx, cancel := pstmt.dbHelper.withTimeout(ctx)
+	defer cancel()
+
+	// perform query
+	rows, err := pstmt.queryValues(ctx, values)
+	if err != nil {
+		return pstmt.queryError(err)
+	}
+
+	// close rows on exit
+	defer rows.Close()
+
+	// check number of columns
+	columns, err := rows.Columns()
+	if err != nil {
+		return pstmt.queryError(err)
+	}
+
+	if len(columns) != 1 {
+		return errors.New(fmt.Sprintf("dbhelper: query returned %d columns, one column expected", len(columns)))
+	}
+
+	// read the only row
+	if !rows.Next() {
+		err = rows.Err()
+		if err != nil {
+			return pstmt.queryError(err)
+		}
+
+		return ErrNoRows
+	}
+
+	err = rows.Scan(i)
+	if err != nil {
+		return pstmt.queryError(err)
+	}
+
+	// check that there are no more rows
+	if rows.Next() {
+		return ErrTooManyRows
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return pstmt.queryError(err)
+	}
+
+	return nil
+}
+
+// SelectValue prepares query, executes it using QueryValue and closes it.
+func (dbh *DbHelper) SelectValue(i interface{}, query string, params interface{}) error {
+	// prepare query
+	q, err := dbh.Prepare(query)
+	if err != nil {
+		return err
+	}
+
+	defer q.Close()
+
+	return q.QueryValue(i, params)
+}
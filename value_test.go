@@ -0,0 +1,66 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestQueryValue(t *testing.T) {
+	dbh := newTestDriverDb("SELECT count(*) FROM test_value WHERE a = $1", &testResult{
+		columns: []string{"count"},
+		rows:    [][]driver.Value{{int64(3)}},
+	})
+	newTestDriverDb("SELECT a FROM test_value_none", &testResult{columns: []string{"a"}})
+	newTestDriverDb("SELECT a FROM test_value_many", &testResult{
+		columns: []string{"a"},
+		rows:    [][]driver.Value{{"a"}, {"b"}},
+	})
+	newTestDriverDb("SELECT a, b FROM test_value_columns", &testResult{
+		columns: []string{"a", "b"},
+		rows:    [][]driver.Value{{"a", "b"}},
+	})
+
+	var count int64
+	err := dbh.SelectValue(&count, "SELECT count(*) FROM test_value WHERE a = :a", map[string]interface{}{"a": 1})
+	if err != nil || count != 3 {
+		t.Errorf("unexpected value: %d, %v", count, err)
+		return
+	}
+
+	var s string
+	err = dbh.SelectValue(&s, "SELECT a FROM test_value_none", nil)
+	if err != ErrNoRows {
+		t.Errorf("ErrNoRows expected: %v", err)
+		return
+	}
+
+	err = dbh.SelectValue(&s, "SELECT a FROM test_value_many", nil)
+	if err != ErrTooManyRows {
+		t.Errorf("ErrTooManyRows expected: %v", err)
+		return
+	}
+
+	err = dbh.SelectValue(&s, "SELECT a, b FROM test_value_columns", nil)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	// destination must be a pointer
+	err = dbh.SelectValue(s, "SELECT a FROM test_value_many", nil)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Warmup prepares standard queries of all registered tables and opens
+// MinIdleConns connections, which are returned to the pool as idle, so
+// that the first requests do not pay latency of connecting and preparing.
+// Used at startup before serving requests. Queries are prepared on one of
+// the connections, database/sql prepares them on other connections when
+// they are used. Warmup is limited by ctx.
+func (dbh *DbHelper) Warmup(ctx context.Context) error {
+	if dbh.tx != nil {
+		return errors.New("dbhelper: cannot warm up DbHelper bound to a transaction")
+	}
+
+	// open connections at the same time, so that they are not reused
+	conns := make([]*sql.Conn, 0, dbh.MinIdleConns)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	for len(conns) < dbh.MinIdleConns {
+		conn, err := dbh.Db.Conn(ctx)
+		if err != nil {
+			return wrapError(err)
+		}
+
+		conns = append(conns, conn)
+
+		err = conn.PingContext(ctx)
+		if err != nil {
+			return wrapError(err)
+		}
+	}
+
+	// prepare tables in stable order
+	tables := make([]*dbTable, 0, len(dbh.tables))
+	for _, tbl := range dbh.tables {
+		tables = append(tables, tbl)
+	}
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].name < tables[j].name
+	})
+
+	for _, tbl := range tables {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		err = tbl.prepare()
+		if err != nil {
+			return errors.New(fmt.Sprintf("dbhelper: cannot prepare queries of table '%s': %v", tbl.name, err))
+		}
+	}
+
+	return nil
+}
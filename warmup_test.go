@@ -0,0 +1,46 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWarmup(t *testing.T) {
+	db := newTestDriverDb("SELECT 1", &testResult{}).Db
+	dbh := NewWith(db, Postgresql{}, PoolOptions{MinIdleConns: 3})
+
+	err := dbh.Warmup(context.Background())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if idle := dbh.Db.Stats().Idle; idle != 3 {
+		t.Errorf("3 idle connections expected: %d", idle)
+		return
+	}
+
+	// queries of tables are prepared
+	err = dbh.AddTable(testStruct{}, "test_warmup")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = dbh.Warmup(context.Background())
+	if err == nil {
+		t.Error("error expected for queries unknown to test driver")
+		return
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Returns table assigned to type of i that can be modified by conditions.
+func (dbh *DbHelper) getWhereTable(i interface{}) (*dbTable, error) {
+	// get type
+	t, err := typeOf(i)
+	if err != nil {
+		return nil, err
+	}
+
+	// get table
+	tbl, err := dbh.getTable(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if tbl.view {
+		return nil, errors.New(fmt.Sprintf("dbhelper: cannot modify read-only view '%s'", tbl.name))
+	}
+
+	if tbl.history {
+		return nil, errors.New(fmt.Sprintf("dbhelper: records of table '%s' with history can only be modified one by one", tbl.name))
+	}
+
+	return tbl, nil
+}
+
+// Compiles condition checking that columns belong to the table.
+func (tbl *dbTable) buildWhere(c Cond) (string, map[string]interface{}, error) {
+	if c == nil {
+		return "", nil, errors.New("dbhelper: condition is missing")
+	}
+
+	b := &condBuilder{
+		params: make(map[string]interface{}),
+		checkColumn: func(column string) error {
+			if _, ok := tbl.fields[column]; !ok {
+				return errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field assigned to column '%s' of table '%s'",
+					tbl.structType, column, tbl.name))
+			}
+
+			return nil
+		},
+		quote: tbl.dbHelper.quote,
+	}
+
+	sql, err := c.build(b)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return sql, b.params, nil
+}
+
+// Returns assignments of update query setting columns to values, storing
+// parameter values in params. Field with option 'modified' is set to timestamp.
+func (dbh *DbHelper) buildSet(tbl *dbTable, values map[string]interface{}, params map[string]interface{}, timestamp int64) (string, error) {
+	if len(values) == 0 {
+		return "", errors.New("dbhelper: no values to update")
+	}
+
+	// columns in stable order, so the same query is prepared once
+	columns := make([]string, 0, len(values)+1)
+	for col := range values {
+		f, ok := tbl.fields[col]
+		if !ok {
+			return "", errors.New(fmt.Sprintf("dbhelper: structure type '%v' has no field assigned to column '%s' of table '%s'",
+				tbl.structType, col, tbl.name))
+		}
+
+		if f.id || f.auto || f.created || f.readonly || f.insertonly || f.tsvector || f == tbl.tenantField {
+			return "", errors.New(fmt.Sprintf("dbhelper: column '%s' of table '%s' cannot be updated", col, tbl.name))
+		}
+
+		value := values[col]
+		if f.secret {
+			s, ok := value.(string)
+			if !ok {
+				return "", errors.New(fmt.Sprintf("dbhelper: value of column '%s' of table '%s' must be a string", col, tbl.name))
+			}
+
+			hash, err := dbh.hashSecret(s)
+			if err != nil {
+				return "", err
+			}
+
+			value = hash
+		}
+
+		columns = append(columns, col)
+		params["u_"+col] = value
+	}
+
+	if tbl.modifiedField != nil {
+		if _, ok := values[tbl.modifiedField.column]; !ok {
+			columns = append(columns, tbl.modifiedField.column)
+			params["u_"+tbl.modifiedField.column] = timestamp
+		}
+	}
+
+	sort.Strings(columns)
+
+	set := make([]string, len(columns))
+	for i, col := range columns {
+		set[i] = fmt.Sprintf("%s = %s", dbh.quote(col), getNamedPlaceholder("u_"+col))
+	}
+
+	// records get new version
+	if tbl.versionField != nil {
+		if _, ok := values[tbl.versionField.column]; !ok {
+			version := tbl.dbHelper.quote(tbl.versionField.column)
+			set = append(set, fmt.Sprintf("%s = %s + 1", version, version))
+		}
+	}
+
+	return strings.Join(set, ", "), nil
+}
+
+// DeleteWhere deletes records of the table assigned to type of i matching
+// condition c and returns number of affected rows.
+func (dbh *DbHelper) DeleteWhere(i interface{}, c Cond) (int64, error) {
+	num, _, err := dbh.modifyWhere(i, nil, c, false)
+	return num, err
+}
+
+// UpdateWhere sets columns of records of the table assigned to type of i
+// matching condition c to values and returns number of affected rows.
+// Field with option 'modified' is updated as by Update.
+func (dbh *DbHelper) UpdateWhere(i interface{}, values map[string]interface{}, c Cond) (int64, error) {
+	num, _, err := dbh.modifyWhere(i, values, c, false)
+	return num, err
+}
+
+// DeleteWhereReturning is like DeleteWhere, but returns ids of deleted records.
+// If SQL dialect supports RETURNING clause, ids are returned by delete query.
+// Otherwise they are selected before deletion within a transaction.
+func (dbh *DbHelper) DeleteWhereReturning(i interface{}, c Cond) ([]interface{}, error) {
+	_, ids, err := dbh.modifyWhere(i, nil, c, true)
+	return ids, err
+}
+
+// UpdateWhereReturning is like UpdateWhere, but returns ids of updated records
+// in the same way as DeleteWhereReturning.
+func (dbh *DbHelper) UpdateWhereReturning(i interface{}, values map[string]interface{}, c Cond) ([]interface{}, error) {
+	_, ids, err := dbh.modifyWhere(i, values, c, true)
+	return ids, err
+}
+
+// Deletes records matching condition c or, if values is not nil, updates them.
+// If returning is true, ids of affected records are returned.
+func (dbh *DbHelper) modifyWhere(i interface{}, values map[string]interface{}, c Cond, returning bool) (int64, []interface{}, error) {
+	// get current timestamp
+	time := time.Now().UTC().Unix()
+
+	tbl, err := dbh.getWhereTable(i)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// limit to records which are not deleted and belong to tenant
+	scope, err := dbh.scopeCond(tbl)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if c != nil && len(scope) > 0 {
+		c = And(append([]Cond{c}, scope...)...)
+	}
+
+	// records are marked as deleted
+	if values == nil && tbl.softDeleteField != nil {
+		values = map[string]interface{}{tbl.softDeleteField.column: time}
+	}
+
+	where, whereParams, err := tbl.buildWhere(c)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var query string
+	params := whereParams
+	if values == nil {
+		query = fmt.Sprintf("DELETE FROM %s WHERE %s", dbh.quote(tbl.name), where)
+	} else {
+		// parameters of condition and new values
+		params = make(map[string]interface{}, len(whereParams)+len(values)+1)
+		for name, value := range whereParams {
+			params[name] = value
+		}
+
+		set, err := dbh.buildSet(tbl, values, params, time)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		query = fmt.Sprintf("UPDATE %s SET %s WHERE %s", dbh.quote(tbl.name), set, where)
+	}
+
+	var num int64
+	var ids []interface{}
+	sqld, HasReturning := dbh.sqlDialect.(HasReturning)
+	switch {
+	case !returning:
+		num, err = dbh.execQuery(tbl, query, params)
+	case HasReturning:
+		// ids are returned by the query
+		ids, err = dbh.queryIds(tbl, query+" "+sqld.Returning(dbh.quote(tbl.idField.column)), params)
+		num = int64(len(ids))
+	default:
+		// ids are selected before modification
+		ids, err = dbh.selectIdsAndExec(tbl, fmt.Sprintf("SELECT %s FROM %s WHERE %s", dbh.quote(tbl.idField.column), dbh.quote(tbl.name), where),
+			whereParams, query, params)
+		num = int64(len(ids))
+	}
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// invalidate cached results
+	dbh.invalidate(tbl)
+
+	return num, ids, nil
+}
+
+// Executes query generated for the table.
+func (dbh *DbHelper) execQuery(tbl *dbTable, query string, params map[string]interface{}) (int64, error) {
+	q, err := tbl.getQuery(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.in(dbh).Exec(params)
+}
+
+// Executes query generated for the table returning ids of records.
+func (dbh *DbHelper) queryIds(tbl *dbTable, query string, params map[string]interface{}) ([]interface{}, error) {
+	q, err := tbl.getQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// pointer to slice of pointers to ids
+	ptr := reflect.New(reflect.SliceOf(reflect.PtrTo(tbl.idField.typ)))
+	_, err = q.in(dbh).Query(ptr.Interface(), params)
+	if err != nil {
+		return nil, err
+	}
+
+	list := ptr.Elem()
+	ids := make([]interface{}, list.Len())
+	for n := range ids {
+		ids[n] = list.Index(n).Elem().Interface()
+	}
+
+	return ids, nil
+}
+
+// Selects ids of records and executes query modifying them within one transaction.
+func (dbh *DbHelper) selectIdsAndExec(tbl *dbTable, selectQuery string, selectParams map[string]interface{},
+	query string, params map[string]interface{}) ([]interface{}, error) {
+	if dbh.tx == nil {
+		txh, err := dbh.Begin()
+		if err != nil {
+			return nil, err
+		}
+
+		ids, err := txh.selectIdsAndExec(tbl, selectQuery, selectParams, query, params)
+		if err != nil {
+			txh.Rollback()
+			return nil, err
+		}
+
+		return ids, txh.Commit()
+	}
+
+	// lock selected records until modification
+	if sqld, ok := dbh.sqlDialect.(HasLockingSelect); ok {
+		selectQuery += " " + sqld.ForUpdate()
+	}
+
+	ids, err := dbh.queryIds(tbl, selectQuery, selectParams)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = dbh.execQuery(tbl, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
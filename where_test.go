@@ -0,0 +1,103 @@
+// Copyright 2015 Sergii Bogomolov. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package dbhelper helps to interact with sql.DB by generating, preparing and
+// executing queries. It marshals Go structs to and from databases and uses
+// database/sql.
+//
+// Source code and project home:
+// https://github.com/bogomolovs/dbhelper
+//
+package dbhelper
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildSet(t *testing.T) {
+	dbh := New(nil, Postgresql{})
+	err := dbh.AddTable(testStruct{}, "test")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	where, params, err := tbl.buildWhere(Lt("c", 100))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	set, err := dbh.buildSet(tbl, map[string]interface{}{"b": true}, params, 1000)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if where != "c < :c0" || set != "b = :u_b, m = :u_m" {
+		t.Errorf("unexpected query: SET %s WHERE %s", set, where)
+		return
+	}
+
+	if len(params) != 3 || params["u_m"] != int64(1000) {
+		t.Errorf("unexpected parameters: %v", params)
+		return
+	}
+
+	// id cannot be updated
+	_, err = dbh.buildSet(tbl, map[string]interface{}{"id": 1}, params, 1000)
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+
+	// unknown column
+	_, _, err = tbl.buildWhere(Eq("password", ""))
+	if err == nil {
+		t.Error("error expected")
+		return
+	}
+}
+
+func TestWhereQuoting(t *testing.T) {
+	dbh := New(nil, MySql{})
+	err := dbh.AddTable(testStruct{}, "order")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tbl, err := dbh.getTable(reflect.TypeOf(testStruct{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	where, _, err := tbl.buildWhere(In("text", "a", "b"))
+	if err != nil || where != "`text` IN (:c0, :c1)" {
+		t.Errorf("unexpected condition: %s, %v", where, err)
+		return
+	}
+
+	filter, err := dbh.ParseFilter(&testStruct{}, url.Values{"c_gt": {"1"}, "sort": {"-id"}})
+	if err != nil || filter.Where != "`c` > :f0" || filter.OrderBy != "`id` DESC" {
+		t.Errorf("unexpected filter: %+v, %v", filter, err)
+		return
+	}
+
+	query := tbl.createHistoryTableQuery()
+	if !strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS `order_history` (`id` ") {
+		t.Errorf("unexpected query: %s", query)
+		return
+	}
+}